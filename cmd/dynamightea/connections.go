@@ -0,0 +1,41 @@
+package dynamightea
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var connectionsCmd = &cobra.Command{
+	Use:   "connections",
+	Short: "List the named connections of the config file",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\tNAME\tPROFILE\tREGION\tENDPOINT\tTAGS")
+		for _, name := range cfg.ConnectionNames() {
+			conn := cfg.Connections[name]
+			current := ""
+			if name == cfg.Connection {
+				current = "*"
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", current, name, orDash(conn.Profile), orDash(conn.Region), orDash(conn.Endpoint), orDash(strings.Join(conn.Tags(), ",")))
+		}
+		return w.Flush()
+	},
+}
+
+// orDash returns value, or "-" if it is empty
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
@@ -0,0 +1,67 @@
+package dynamightea
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+var describeCmd = &cobra.Command{
+	Use:   "describe <table>",
+	Short: "Show the key schema and indexes of a table",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		info, err := client.DescribeTable(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		printTableInfo(cmd.OutOrStdout(), info)
+		return nil
+	},
+}
+
+func printTableInfo(w io.Writer, info *db.TableInfo) {
+	fmt.Fprintf(w, "Table: %s\n\n", info.TableName)
+
+	fmt.Fprintln(w, "Primary Key:")
+	for _, key := range info.KeySchema {
+		fmt.Fprintf(w, "  %s (%s)\n", key.AttributeName, key.KeyType)
+	}
+
+	fmt.Fprintln(w, "\nAttributes:")
+	names := make([]string, 0, len(info.AttributeDefinitions))
+	for name := range info.AttributeDefinitions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %s: %s\n", name, info.AttributeDefinitions[name])
+	}
+
+	printIndexes(w, "Global Secondary Indexes:", info.GSIs)
+	printIndexes(w, "Local Secondary Indexes:", info.LSIs)
+}
+
+func printIndexes(w io.Writer, title string, indexes []db.IndexInfo) {
+	fmt.Fprintf(w, "\n%s\n", title)
+	if len(indexes) == 0 {
+		fmt.Fprintln(w, "  None")
+		return
+	}
+	for _, idx := range indexes {
+		fmt.Fprintf(w, "  %s:\n", idx.IndexName)
+		for _, key := range idx.KeySchema {
+			fmt.Fprintf(w, "    %s (%s)\n", key.AttributeName, key.KeyType)
+		}
+	}
+}
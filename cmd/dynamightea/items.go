@@ -0,0 +1,182 @@
+package dynamightea
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// Flags for the item commands
+var (
+	flagLimit      int
+	flagPageSize   int32
+	flagIndex      string
+	flagSortOp     string
+	flagSortValues []string
+	flagDescending bool
+)
+
+var scanCmd = &cobra.Command{
+	Use:   "scan <table>",
+	Short: "Scan a table and print its items as JSON lines",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		size := pageSize(cmd, client)
+		return printPages(cmd.OutOrStdout(), func(startKey db.Item) (*db.ItemPage, error) {
+			return client.Scan(cmd.Context(), args[0], size, startKey)
+		})
+	},
+}
+
+var queryCmd = &cobra.Command{
+	Use:   "query <table> <partition-key-value>",
+	Short: "Query a table or index by partition key and print the items as JSON lines",
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		info, err := client.DescribeTable(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		schema, err := info.KeySchemaFor(flagIndex)
+		if err != nil {
+			return err
+		}
+		pk := db.PartitionKey(schema)
+		pkValue, err := db.KeyValue(info.AttributeDefinitions[pk], args[1])
+		if err != nil {
+			return fmt.Errorf("partition key %s: %w", pk, err)
+		}
+
+		sk := db.SortKey(schema)
+		var sortCondition *db.SortKeyCondition
+		if flagSortOp != "" {
+			if sk == "" {
+				return fmt.Errorf("--sort-op given but %s has no sort key", args[0])
+			}
+			sortCondition = &db.SortKeyCondition{Operator: db.SortOperator(flagSortOp)}
+			for _, raw := range flagSortValues {
+				value, err := db.KeyValue(info.AttributeDefinitions[sk], raw)
+				if err != nil {
+					return fmt.Errorf("sort key %s: %w", sk, err)
+				}
+				sortCondition.Values = append(sortCondition.Values, value)
+			}
+		}
+
+		size := pageSize(cmd, client)
+		return printPages(cmd.OutOrStdout(), func(startKey db.Item) (*db.ItemPage, error) {
+			return client.Query(cmd.Context(), db.QueryInput{
+				TableName:         args[0],
+				IndexName:         flagIndex,
+				PartitionKey:      pk,
+				PartitionValue:    pkValue,
+				SortKey:           sk,
+				SortCondition:     sortCondition,
+				Descending:        flagDescending,
+				Limit:             size,
+				ExclusiveStartKey: startKey,
+			})
+		})
+	},
+}
+
+var getCmd = &cobra.Command{
+	Use:   "get <table> <partition-key-value> [sort-key-value]",
+	Short: "Fetch a single item by primary key and print it as JSON",
+	Args:  cobra.RangeArgs(2, 3),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		info, err := client.DescribeTable(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+
+		var sortValue string
+		if len(args) == 3 {
+			sortValue = args[2]
+		}
+		key, err := info.BuildKey(args[1], sortValue)
+		if err != nil {
+			return err
+		}
+
+		item, err := client.GetItem(cmd.Context(), args[0], key)
+		if err != nil {
+			return err
+		}
+		if item == nil {
+			return fmt.Errorf("item not found in %s", args[0])
+		}
+
+		return json.NewEncoder(cmd.OutOrStdout()).Encode(db.ItemToPlain(item))
+	},
+}
+
+// pageSize returns --page-size, or the configured page size if the flag
+// was not given
+func pageSize(cmd *cobra.Command, client *db.DynamoClient) int32 {
+	if !cmd.Flags().Changed("page-size") && client.Config().PageSize > 0 {
+		return client.Config().PageSize
+	}
+	return flagPageSize
+}
+
+func init() {
+	for _, cmd := range []*cobra.Command{scanCmd, queryCmd} {
+		cmd.Flags().IntVar(&flagLimit, "limit", 0, "maximum number of items to print (0 for all)")
+		cmd.Flags().Int32Var(&flagPageSize, "page-size", 100, "number of items to request per page (overrides DYNAMIGHTEA_PAGE_SIZE)")
+	}
+	queryCmd.Flags().StringVar(&flagIndex, "index", "", "query a global or local secondary index")
+	queryCmd.Flags().StringVar(&flagSortOp, "sort-op", "", "sort key operator: =, <, <=, >, >=, BETWEEN or begins_with")
+	queryCmd.Flags().StringArrayVar(&flagSortValues, "sort-value", nil, "sort key value (repeat twice for BETWEEN)")
+	queryCmd.Flags().BoolVar(&flagDescending, "desc", false, "return items in descending sort key order")
+}
+
+// printPages fetches pages until the results are exhausted or --limit is
+// reached, writing each item as a line of JSON
+func printPages(w io.Writer, fetch func(startKey db.Item) (*db.ItemPage, error)) error {
+	enc := json.NewEncoder(w)
+	printed := 0
+
+	var startKey db.Item
+	for {
+		page, err := fetch(startKey)
+		if err != nil {
+			return err
+		}
+
+		for _, item := range page.Items {
+			if err := enc.Encode(db.ItemToPlain(item)); err != nil {
+				return err
+			}
+			printed++
+			if flagLimit > 0 && printed >= flagLimit {
+				return nil
+			}
+		}
+
+		if !page.HasMore() {
+			return nil
+		}
+		startKey = page.LastEvaluatedKey
+	}
+}
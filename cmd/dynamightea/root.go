@@ -0,0 +1,170 @@
+package dynamightea
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/spf13/cobra"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+	"github.com/jlgore/dynamighTea/pkg/db"
+	"github.com/jlgore/dynamighTea/pkg/ui"
+)
+
+// Global flags shared by every command
+var (
+	flagRegion     string
+	flagProfile    string
+	flagEndpoint   string
+	flagConnection string
+	flagRegions    string
+	flagDemo       bool
+	flagReadOnly   bool
+
+	flagTimeout     time.Duration
+	flagScanTimeout time.Duration
+)
+
+var rootCmd = &cobra.Command{
+	Use:   "dynamightea",
+	Short: "A terminal UI for Amazon DynamoDB",
+	Long: `DynamighTea is a terminal UI for browsing Amazon DynamoDB tables.
+
+Run it without arguments to start the interactive UI, or use one of the
+subcommands for scripted, non-interactive access.`,
+	Args:          cobra.NoArgs,
+	SilenceUsage:  true,
+	SilenceErrors: true,
+	RunE:          runTUI,
+}
+
+func init() {
+	flags := rootCmd.PersistentFlags()
+	flags.StringVar(&flagRegion, "region", "", "AWS region (overrides AWS_REGION)")
+	flags.StringVar(&flagProfile, "profile", "", "AWS profile (overrides AWS_PROFILE)")
+	flags.StringVar(&flagEndpoint, "endpoint", "", "DynamoDB endpoint URL (overrides AWS_DYNAMODB_ENDPOINT)")
+	flags.StringVar(&flagRegions, "regions", "", `comma-separated regions to list tables in, or "all" for every enabled region (overrides DYNAMIGHTEA_REGIONS)`)
+	flags.StringVarP(&flagConnection, "connection", "c", "", "named connection from the config file (overrides DYNAMIGHTEA_CONNECTION)")
+	flags.BoolVar(&flagDemo, "demo", false, "use built-in sample tables instead of AWS")
+	flags.BoolVar(&flagReadOnly, "read-only", false, "refuse to change items (overrides DYNAMIGHTEA_READ_ONLY)")
+	flags.DurationVar(&flagTimeout, "timeout", 0, "timeout for each metadata, read and write request (overrides DYNAMIGHTEA_TIMEOUT)")
+	flags.DurationVar(&flagScanTimeout, "scan-timeout", 0, "timeout for each page of a scan or query (overrides DYNAMIGHTEA_SCAN_TIMEOUT)")
+
+	rootCmd.AddCommand(
+		tablesCmd,
+		describeCmd,
+		scanCmd,
+		queryCmd,
+		getCmd,
+		whoamiCmd,
+		connectionsCmd,
+	)
+}
+
+// Execute runs the root command. An interrupt cancels outstanding requests.
+func Execute() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return rootCmd.ExecuteContext(ctx)
+}
+
+// loadConfig loads the configuration from the environment and the config
+// file and applies any global flags on top of it
+func loadConfig() (*appconfig.Config, error) {
+	cfg, err := appconfig.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	// Flags for single settings override those of the connection
+	if flagConnection != "" {
+		if err := cfg.UseConnection(flagConnection); err != nil {
+			return nil, err
+		}
+	}
+	if flagProfile != "" {
+		cfg.UseProfile(flagProfile)
+	}
+	if flagRegion != "" {
+		cfg.Region = flagRegion
+	}
+	if flagEndpoint != "" {
+		cfg.Endpoint = flagEndpoint
+	}
+	if flagRegions != "" {
+		if cfg.Regions, err = appconfig.ParseRegions(flagRegions); err != nil {
+			return nil, fmt.Errorf("invalid --regions: %w", err)
+		}
+	}
+	if flagDemo {
+		cfg.Demo = true
+	}
+	if flagReadOnly {
+		cfg.ForceReadOnly()
+	}
+	if flagTimeout > 0 {
+		cfg.Timeouts.Metadata = flagTimeout
+		cfg.Timeouts.Read = flagTimeout
+		cfg.Timeouts.Write = flagTimeout
+	}
+	if flagScanTimeout > 0 {
+		cfg.Timeouts.Scan = flagScanTimeout
+	}
+
+	return cfg, nil
+}
+
+// newClient creates a DynamoDB client for the current flags and environment
+func newClient() (*db.DynamoClient, error) {
+	cfg, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := db.NewDynamoClientWithConfig(cfg)
+	var mfa *appconfig.MFARequiredError
+	if errors.As(err, &mfa) {
+		return nil, fmt.Errorf("%w; run dynamightea without a subcommand to enter the code, after which the session is cached", err)
+	}
+	var sso *appconfig.SSOLoginRequiredError
+	if errors.As(err, &sso) {
+		return nil, fmt.Errorf("%w; run dynamightea without a subcommand or aws sso login to sign in", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create DynamoDB client: %w", err)
+	}
+	return client, nil
+}
+
+// runTUI starts the interactive UI. If the profile needs an MFA code or an
+// SSO login the UI asks for it before connecting instead of failing.
+func runTUI(cmd *cobra.Command, args []string) error {
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+
+	var model ui.Model
+	client, err := db.NewDynamoClientWithConfig(cfg)
+	var mfa *appconfig.MFARequiredError
+	var sso *appconfig.SSOLoginRequiredError
+	switch {
+	case errors.As(err, &mfa):
+		model = ui.NewMFAModel(cmd.Context(), cfg, mfa)
+	case errors.As(err, &sso):
+		model = ui.NewSSOLoginModel(cmd.Context(), cfg, sso)
+	case err != nil:
+		return fmt.Errorf("failed to create DynamoDB client: %w", err)
+	default:
+		model = ui.NewModel(cmd.Context(), client)
+	}
+
+	p := tea.NewProgram(model, tea.WithAltScreen())
+	_, err = p.Run()
+	return err
+}
@@ -0,0 +1,71 @@
+package dynamightea
+
+import (
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+var tablesCmd = &cobra.Command{
+	Use:   "tables",
+	Short: "List DynamoDB tables",
+	Long: `List DynamoDB tables.
+
+With --regions the tables of several regions are listed in parallel, with
+the replicas of global tables.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newClient()
+		if err != nil {
+			return err
+		}
+
+		if regions := client.Config().Regions; len(regions) > 0 {
+			return listTablesInRegions(cmd, client, regions)
+		}
+
+		tables, err := client.ListTables(cmd.Context())
+		if err != nil {
+			return err
+		}
+
+		for _, table := range tables {
+			fmt.Fprintln(cmd.OutOrStdout(), table)
+		}
+		return nil
+	},
+}
+
+// listTablesInRegions prints the tables of each region. A region that
+// cannot be listed is reported without hiding the others.
+func listTablesInRegions(cmd *cobra.Command, client *db.DynamoClient, regions []string) error {
+	results, err := client.ListTablesInRegions(cmd.Context(), regions)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "REGION\tTABLE\tREPLICAS")
+	var failed []string
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(cmd.ErrOrStderr(), "%s: %v\n", result.Region, result.Err)
+			failed = append(failed, result.Region)
+			continue
+		}
+		for _, table := range result.Tables {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", result.Region, table.Name, orDash(strings.Join(table.Replicas, ",")))
+		}
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("failed to list tables in %s", strings.Join(failed, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package dynamightea
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+var whoamiCmd = &cobra.Command{
+	Use:   "whoami",
+	Short: "Show the region, profile and credentials in use",
+	Long: `Show the region, profile, endpoint and credential source that the
+other commands would use, when the credentials expire and the account and
+principal that STS GetCallerIdentity reports for them. If no credentials
+are found, every source that was tried is listed with the reason it was
+passed over.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cfg, err := loadConfig()
+		if err != nil {
+			return err
+		}
+
+		var identity db.Identity
+		client, err := db.NewDynamoClientWithConfig(cfg)
+		if err != nil {
+			identity = db.NewIdentity(cfg, err)
+		} else {
+			identity = client.Identity(cmd.Context())
+		}
+
+		printIdentity(cmd.OutOrStdout(), identity)
+		// Demo data needs no credentials, so it is not a failure
+		callerOK := identity.CallerErr == nil || errors.Is(identity.CallerErr, db.ErrNoAWSCredentials)
+		if identity.CredentialsErr != nil || !callerOK {
+			return errors.New("the credentials could not be verified")
+		}
+		return nil
+	},
+}
+
+// printIdentity writes identity as aligned "name: value" lines
+func printIdentity(w io.Writer, identity db.Identity) {
+	profile := identity.Profile
+	if profile == "" {
+		profile = "default"
+	}
+	endpoint := identity.Endpoint
+	if endpoint == "" {
+		endpoint = "AWS"
+	}
+	if identity.Connection != "" {
+		fmt.Fprintf(w, "Connection:  %s\n", identity.Connection)
+	}
+	fmt.Fprintf(w, "Region:      %s\n", identity.Region)
+	fmt.Fprintf(w, "Profile:     %s\n", profile)
+	fmt.Fprintf(w, "Endpoint:    %s\n", endpoint)
+
+	var noCreds *appconfig.NoCredentialsError
+	switch {
+	case errors.As(identity.CredentialsErr, &noCreds):
+		fmt.Fprintf(w, "Credentials: none found\n")
+		for _, reason := range noCreds.Tried {
+			fmt.Fprintf(w, "  - %s\n", reason)
+		}
+		return
+	case identity.CredentialsErr != nil:
+		fmt.Fprintf(w, "Credentials: %v\n", identity.CredentialsErr)
+		return
+	case identity.Credentials.Source == "":
+		fmt.Fprintf(w, "Credentials: none (demo data)\n")
+	default:
+		fmt.Fprintf(w, "Credentials: %s\n", identity.Credentials.Source)
+	}
+	if identity.Credentials.CanExpire {
+		fmt.Fprintf(w, "Expires:     %s (in %s)\n", identity.Credentials.Expires.Local().Format(time.RFC3339), time.Until(identity.Credentials.Expires).Truncate(time.Minute))
+	}
+
+	if identity.CallerErr != nil {
+		fmt.Fprintf(w, "Caller:      %v\n", identity.CallerErr)
+		return
+	}
+	fmt.Fprintf(w, "Account:     %s\n", identity.Account)
+	fmt.Fprintf(w, "ARN:         %s\n", identity.ARN)
+	fmt.Fprintf(w, "User ID:     %s\n", identity.UserID)
+}
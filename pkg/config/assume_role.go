@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// expiryWindow is how long before expiry cached session credentials are
+// considered stale, so a long scan does not start with a dying session
+const expiryWindow = 5 * time.Minute
+
+// defaultSTSRegion is used for STS when neither the profile nor the
+// environment names a region. It serves the global STS endpoint.
+const defaultSTSRegion = "us-east-1"
+
+// MFARequiredError is returned when assuming the role of a profile needs a
+// code from its MFA device. Pass the code to SetMFAToken and try again.
+type MFARequiredError struct {
+	Profile string
+	Serial  string
+}
+
+func (e *MFARequiredError) Error() string {
+	return fmt.Sprintf("profile %s requires an MFA code from %s", e.Profile, e.Serial)
+}
+
+// SetMFAToken sets the code used the next time a role that requires MFA is
+// assumed. Each code is used once.
+func (c *Config) SetMFAToken(code string) {
+	c.mfaToken = code
+}
+
+// assumeRole calls STS AssumeRole for the role of profile, signing the
+// request with source. Results are cached until shortly before they expire.
+func (c *Config) assumeRole(ctx context.Context, profile *Profile, source *Credentials) (*Credentials, error) {
+	key := sessionKey(profile, source)
+	if creds := c.sessions.get(key); creds != nil {
+		return creds, nil
+	}
+
+	region := profile.Region
+	if region == "" {
+		region = c.Region
+	}
+	if region == "" {
+		region = defaultSTSRegion
+	}
+
+	options := sts.Options{
+		Region:      region,
+		Credentials: credentials.NewStaticCredentialsProvider(source.AccessKeyID, source.SecretAccessKey, source.SessionToken),
+	}
+	if c.STSEndpoint != "" {
+		options.BaseEndpoint = aws.String(c.STSEndpoint)
+	}
+	client := sts.New(options)
+
+	sessionName := profile.RoleSessionName
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("dynamightea-%d", time.Now().Unix())
+	}
+	input := &sts.AssumeRoleInput{
+		RoleArn:         aws.String(profile.RoleARN),
+		RoleSessionName: aws.String(sessionName),
+	}
+	if profile.ExternalID != "" {
+		input.ExternalId = aws.String(profile.ExternalID)
+	}
+	if profile.DurationSeconds > 0 {
+		input.DurationSeconds = aws.Int32(profile.DurationSeconds)
+	}
+	if profile.MFASerial != "" {
+		if c.mfaToken == "" {
+			return nil, &MFARequiredError{Profile: profile.Name, Serial: profile.MFASerial}
+		}
+		input.SerialNumber = aws.String(profile.MFASerial)
+		input.TokenCode = aws.String(c.mfaToken)
+		c.mfaToken = ""
+	}
+
+	ctx, cancel := c.metadataContext(ctx)
+	defer cancel()
+
+	resp, err := client.AssumeRole(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume role %s for profile %s: %w", profile.RoleARN, profile.Name, err)
+	}
+	if resp.Credentials == nil {
+		return nil, fmt.Errorf("failed to assume role %s for profile %s: no credentials returned", profile.RoleARN, profile.Name)
+	}
+
+	creds := &Credentials{
+		AccessKeyID:     aws.ToString(resp.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(resp.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(resp.Credentials.SessionToken),
+		Expiration:      aws.ToTime(resp.Credentials.Expiration),
+	}
+	c.sessions.put(key, creds)
+	return creds, nil
+}
+
+// sessionKey identifies the session a profile would assume with source.
+// Generated session names are left out so cached sessions can be reused.
+func sessionKey(profile *Profile, source *Credentials) string {
+	sum := sha1.Sum([]byte(fmt.Sprintf("%s\x00%s\x00%s\x00%s\x00%d\x00%s",
+		source.AccessKeyID, profile.RoleARN, profile.ExternalID, profile.RoleSessionName, profile.DurationSeconds, profile.MFASerial)))
+	return hex.EncodeToString(sum[:])
+}
+
+// sessionCache holds assumed role credentials in memory and, when dir is
+// set, on disk so they survive restarts. A nil cache stores nothing.
+type sessionCache struct {
+	dir string
+
+	mu       sync.Mutex
+	sessions map[string]*Credentials
+}
+
+func newSessionCache(dir string) *sessionCache {
+	return &sessionCache{dir: dir, sessions: map[string]*Credentials{}}
+}
+
+// get returns unexpired credentials stored under key, or nil
+func (s *sessionCache) get(key string) *Credentials {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	creds := s.sessions[key]
+	if creds == nil && s.dir != "" {
+		if data, err := os.ReadFile(s.path(key)); err == nil {
+			creds = &Credentials{}
+			if json.Unmarshal(data, creds) != nil {
+				creds = nil
+			}
+		}
+	}
+	if creds == nil || time.Until(creds.Expiration) < expiryWindow {
+		return nil
+	}
+	s.sessions[key] = creds
+	return creds
+}
+
+// put stores credentials under key. Failing to write the disk cache only
+// means the role is assumed again next time, so errors are ignored.
+func (s *sessionCache) put(key string, creds *Credentials) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[key] = creds
+	if s.dir == "" {
+		return
+	}
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path(key)), 0o700); err != nil {
+		return
+	}
+	_ = os.WriteFile(s.path(key), data, 0o600)
+}
+
+func (s *sessionCache) path(key string) string {
+	return filepath.Join(s.dir, "sts", key+".json")
+}
@@ -0,0 +1,240 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const roleConfigFile = `[profile admin]
+role_arn = arn:aws:iam::111111111111:role/Admin
+source_profile = base
+external_id = ext-1
+role_session_name = ops
+duration_seconds = 900
+
+[profile data]
+role_arn = arn:aws:iam::222222222222:role/Data
+source_profile = admin
+region = eu-central-1
+
+[profile mfa]
+role_arn = arn:aws:iam::444444444444:role/Admin
+source_profile = base
+mfa_serial = arn:aws:iam::111111111111:mfa/jane
+
+[profile denied]
+role_arn = arn:aws:iam::333333333333:role/Denied
+source_profile = base
+`
+
+const roleCredentialsFile = `[base]
+aws_access_key_id = AKIABASE
+aws_secret_access_key = base-secret
+`
+
+// assumeRoleCall is an AssumeRole request received by stubSTS
+type assumeRoleCall struct {
+	SigningKey      string
+	RoleArn         string
+	ExternalID      string
+	RoleSessionName string
+	DurationSeconds string
+	SerialNumber    string
+	TokenCode       string
+	WebIdentity     string
+}
+
+// stubSTS serves AssumeRole and AssumeRoleWithWebIdentity, issuing access keys ASIA1, ASIA2, ... and
+// denying roles whose ARN ends in "Denied"
+type stubSTS struct {
+	mu    sync.Mutex
+	calls []assumeRoleCall
+}
+
+var signingKeyPattern = regexp.MustCompile(`Credential=([^/]+)/`)
+
+func (s *stubSTS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	action := ""
+	if err := r.ParseForm(); err == nil {
+		action = r.Form.Get("Action")
+	}
+	if action != "AssumeRole" && action != "AssumeRoleWithWebIdentity" {
+		http.Error(w, "unexpected request", http.StatusBadRequest)
+		return
+	}
+
+	call := assumeRoleCall{
+		RoleArn:         r.Form.Get("RoleArn"),
+		ExternalID:      r.Form.Get("ExternalId"),
+		RoleSessionName: r.Form.Get("RoleSessionName"),
+		DurationSeconds: r.Form.Get("DurationSeconds"),
+		SerialNumber:    r.Form.Get("SerialNumber"),
+		TokenCode:       r.Form.Get("TokenCode"),
+		WebIdentity:     r.Form.Get("WebIdentityToken"),
+	}
+	if match := signingKeyPattern.FindStringSubmatch(r.Header.Get("Authorization")); match != nil {
+		call.SigningKey = match[1]
+	}
+
+	s.mu.Lock()
+	s.calls = append(s.calls, call)
+	n := len(s.calls)
+	s.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/xml")
+	if strings.HasSuffix(call.RoleArn, "Denied") {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `<ErrorResponse><Error><Type>Sender</Type><Code>AccessDenied</Code><Message>not authorized to assume role</Message></Error><RequestId>1</RequestId></ErrorResponse>`)
+		return
+	}
+	fmt.Fprintf(w, `<%[1]sResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/"><%[1]sResult>
+<Credentials><AccessKeyId>ASIA%[2]d</AccessKeyId><SecretAccessKey>secret-%[2]d</SecretAccessKey><SessionToken>token-%[2]d</SessionToken><Expiration>%[3]s</Expiration></Credentials>
+<AssumedRoleUser><Arn>%[4]s/session</Arn><AssumedRoleId>AROA%[2]d:session</AssumedRoleId></AssumedRoleUser>
+</%[1]sResult><ResponseMetadata><RequestId>%[2]d</RequestId></ResponseMetadata></%[1]sResponse>`,
+		action, n, time.Now().Add(time.Hour).UTC().Format(time.RFC3339), call.RoleArn)
+}
+
+func (s *stubSTS) Calls() []assumeRoleCall {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]assumeRoleCall(nil), s.calls...)
+}
+
+func TestAssumeRoleChain(t *testing.T) {
+	writeProfiles(t, roleConfigFile, roleCredentialsFile)
+	stub := &stubSTS{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+	t.Setenv("AWS_PROFILE", "data")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	creds, err := cfg.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to assume role chain: %v", err)
+	}
+	if creds.AccessKeyID != "ASIA2" || creds.SessionToken != "token-2" || creds.Expiration.IsZero() {
+		t.Errorf("Expected the credentials of the second hop, got %+v", creds)
+	}
+	if want := "profile data (assumed role arn:aws:iam::222222222222:role/Data)"; creds.Source != want {
+		t.Errorf("Expected source %q, got %q", want, creds.Source)
+	}
+
+	calls := stub.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("Expected 2 AssumeRole calls, got %d", len(calls))
+	}
+	first := assumeRoleCall{
+		SigningKey:      "AKIABASE",
+		RoleArn:         "arn:aws:iam::111111111111:role/Admin",
+		ExternalID:      "ext-1",
+		RoleSessionName: "ops",
+		DurationSeconds: "900",
+	}
+	if calls[0] != first {
+		t.Errorf("Expected first hop %+v, got %+v", first, calls[0])
+	}
+	if calls[1].SigningKey != "ASIA1" || calls[1].RoleArn != "arn:aws:iam::222222222222:role/Data" {
+		t.Errorf("Expected the second hop to be signed by the first session, got %+v", calls[1])
+	}
+	if !strings.HasPrefix(calls[1].RoleSessionName, "dynamightea-") || calls[1].ExternalID != "" || calls[1].DurationSeconds != "" {
+		t.Errorf("Expected defaults for the second hop, got %+v", calls[1])
+	}
+
+	// A new process finds both sessions in the disk cache
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cached, err := cfg.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get cached credentials: %v", err)
+	}
+	if cached.AccessKeyID != "ASIA2" {
+		t.Errorf("Expected cached credentials ASIA2, got %s", cached.AccessKeyID)
+	}
+	if n := len(stub.Calls()); n != 2 {
+		t.Errorf("Expected no further AssumeRole calls, got %d in total", n)
+	}
+}
+
+func TestAssumeRoleWithMFA(t *testing.T) {
+	writeProfiles(t, roleConfigFile, roleCredentialsFile)
+	stub := &stubSTS{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+	t.Setenv("AWS_PROFILE", "mfa")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	_, err = cfg.GetCredentials(context.Background())
+	var required *MFARequiredError
+	if !errors.As(err, &required) || required.Profile != "mfa" || required.Serial != "arn:aws:iam::111111111111:mfa/jane" {
+		t.Fatalf("Expected an MFARequiredError for profile mfa, got %v", err)
+	}
+	if n := len(stub.Calls()); n != 0 {
+		t.Errorf("Expected no AssumeRole call without a code, got %d", n)
+	}
+
+	cfg.SetMFAToken("123456")
+	creds, err := cfg.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to assume role with MFA: %v", err)
+	}
+	calls := stub.Calls()
+	if len(calls) != 1 || calls[0].SerialNumber != "arn:aws:iam::111111111111:mfa/jane" || calls[0].TokenCode != "123456" {
+		t.Errorf("Expected one AssumeRole call with the MFA device and code, got %+v", calls)
+	}
+
+	// The session is cached, so a new process is not asked for a code
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cached, err := cfg.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("Expected cached credentials, got %v", err)
+	}
+	if cached.AccessKeyID != creds.AccessKeyID {
+		t.Errorf("Expected cached credentials %s, got %s", creds.AccessKeyID, cached.AccessKeyID)
+	}
+}
+
+func TestAssumeRoleDenied(t *testing.T) {
+	writeProfiles(t, roleConfigFile, roleCredentialsFile)
+	server := httptest.NewServer(&stubSTS{})
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+	t.Setenv("AWS_PROFILE", "denied")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	_, err = cfg.GetCredentials(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "profile denied") || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("Expected an AccessDenied error naming the profile, got %v", err)
+	}
+}
+
+func TestLoadProfilesRejectsBadDuration(t *testing.T) {
+	writeProfiles(t, "[profile admin]\nduration_seconds = an hour\n", "")
+	if _, err := LoadConfig(); err == nil || !strings.Contains(err.Error(), "duration_seconds") {
+		t.Errorf("Expected an invalid duration_seconds error, got %v", err)
+	}
+}
@@ -2,13 +2,17 @@ package config
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
+	"maps"
 	"os"
 	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
 )
 
 // Config holds application configuration
@@ -20,7 +24,56 @@ type Config struct {
 	CredentialsFile string
 	UseIMDS         bool
 	IMDSVersion     string // "v1", "v2"
+	IMDSEndpoint    string // Base URL of the EC2 instance metadata service
 	UseECSMetadata  bool
+	ECSEndpoint     string // Full URL of the container credentials endpoint
+	ECSAuthToken    string // Authorization header for the container credentials endpoint
+	ECSTokenFile    string // File holding ECSAuthToken; takes precedence and is read on every request
+	Demo            bool   // Serve built-in sample tables instead of contacting AWS
+	Timeouts        Timeouts
+	STSEndpoint     string // Custom endpoint for AssumeRole, such as a local stub
+	SSOEndpoint     string // Custom endpoint for the IAM Identity Center portal
+	SSOOIDCEndpoint string // Custom endpoint for the IAM Identity Center sign-in
+	EC2Endpoint     string // Custom endpoint for listing the enabled regions
+	PageSize        int32  // Items per Scan or Query page; zero for the default of each command
+	Theme           string // Color scheme of the UI, one of Themes
+	KeyBindings     map[string]string
+	ReadOnly        bool   // Refuse to change items
+	Production      bool   // The connection is tagged as production
+	Connection      string // Name of the connection in use, if any
+	Connections     map[string]Connection
+	Regions         []string // Regions to list tables in, or "all" for every enabled region
+
+	profiles        map[string]*Profile
+	profileFromEnv  bool
+	regionFromEnv   bool
+	endpointFromEnv bool
+	defaultRegion   string // From the config file, for profiles without a region
+	readOnly        bool   // From the environment or config file, for connections without read_only
+	forceReadOnly   bool   // Set by --read-only or DYNAMIGHTEA_READ_ONLY=true, whatever the connection
+	sessions        *sessionCache
+	mfaToken        string
+	ssoCacheDir     string
+	providerOnce    sync.Once
+	provider        *aws.CredentialsCache
+	imds            *imdsProbe
+}
+
+// Timeouts bounds how long each kind of DynamoDB request may take. Zero
+// means no limit.
+type Timeouts struct {
+	Metadata time.Duration // ListTables and DescribeTable
+	Read     time.Duration // GetItem
+	Scan     time.Duration // Each page of a Scan, Query or PartiQL SELECT
+	Write    time.Duration // PutItem, UpdateItem, DeleteItem, PartiQL writes and each batch of a bulk delete
+}
+
+// DefaultTimeouts are used unless overridden by the environment or flags
+var DefaultTimeouts = Timeouts{
+	Metadata: 30 * time.Second,
+	Read:     30 * time.Second,
+	Scan:     5 * time.Minute,
+	Write:    30 * time.Second,
 }
 
 // Credentials represents AWS credentials
@@ -29,301 +82,414 @@ type Credentials struct {
 	SecretAccessKey string
 	SessionToken    string
 	Expiration      time.Time
+	Source          string // Where the credentials came from, such as "environment variables"
 }
 
-// LoadConfig loads the application configuration
+// LoadConfig loads the application configuration from the environment,
+// with defaults from the config file at ConfigFilePath
 func LoadConfig() (*Config, error) {
-	// Default to environment variables or AWS config file
-	region := os.Getenv("AWS_REGION")
-	if region == "" {
-		region = os.Getenv("AWS_DEFAULT_REGION")
-	}
-	if region == "" {
-		region = "us-east-1" // Default region
+	file, err := LoadFileConfig(ConfigFilePath())
+	if err != nil {
+		return nil, err
 	}
 
 	// Get AWS profile
 	profile := os.Getenv("AWS_PROFILE")
+	profileFromEnv := profile != ""
+	if profile == "" {
+		profile = file.Profile
+	}
 	if profile == "" {
 		profile = "default"
 	}
 
 	// Endpoint for local development (e.g., DynamoDB Local)
 	endpoint := os.Getenv("AWS_DYNAMODB_ENDPOINT")
+	endpointFromEnv := endpoint != ""
+	if endpoint == "" {
+		endpoint = file.Endpoint
+	}
 
 	// AWS config file locations
 	homeDir, _ := os.UserHomeDir()
 	awsConfigDir := filepath.Join(homeDir, ".aws")
-	configFile := filepath.Join(awsConfigDir, "config")
-	credentialsFile := filepath.Join(awsConfigDir, "credentials")
+	configFile := os.Getenv("AWS_CONFIG_FILE")
+	if configFile == "" {
+		configFile = filepath.Join(awsConfigDir, "config")
+	}
+	credentialsFile := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if credentialsFile == "" {
+		credentialsFile = filepath.Join(awsConfigDir, "credentials")
+	}
+
+	profiles, err := LoadProfiles(configFile, credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// The environment takes precedence over the profile's region, which
+	// takes precedence over the config file
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	regionFromEnv := region != ""
+	if !regionFromEnv && profiles[profile] != nil {
+		region = profiles[profile].Region
+	}
+	if region == "" {
+		region = file.Region
+	}
 
 	// IMDS configuration
-	useIMDS := os.Getenv("AWS_USE_IMDS") != "false" // Use IMDS by default in EC2 environment
+	useIMDS := os.Getenv("AWS_USE_IMDS") != "false" && os.Getenv("AWS_EC2_METADATA_DISABLED") != "true" // Use IMDS by default in EC2 environment
 	imdsVersion := os.Getenv("AWS_IMDS_VERSION")
 	if imdsVersion == "" {
 		imdsVersion = "v2" // Default to IMDSv2 which is more secure
 	}
-
-	// ECS metadata configuration
-	useECSMetadata := os.Getenv("AWS_ECS_METADATA_ENDPOINT") != ""
-
-	return &Config{
-		Region:          region,
-		Profile:         profile,
-		Endpoint:        endpoint,
-		ConfigFile:      configFile,
-		CredentialsFile: credentialsFile,
-		UseIMDS:         useIMDS,
-		IMDSVersion:     imdsVersion,
-		UseECSMetadata:  useECSMetadata,
-	}, nil
-}
-
-// GetCredentials attempts to retrieve AWS credentials from various sources
-func (c *Config) GetCredentials() (*Credentials, error) {
-	// First check environment variables (highest precedence)
-	creds := &Credentials{
-		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
-		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
-		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	imdsEndpoint := os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT")
+	if imdsEndpoint == "" {
+		imdsEndpoint = defaultIMDSEndpoint
+		if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT_MODE"), "IPv6") {
+			imdsEndpoint = defaultIMDSEndpointIPv6
+		}
 	}
 
-	if creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
-		return creds, nil
+	// Container credentials, as provided to ECS tasks and by EKS Pod
+	// Identity. A relative URI takes precedence over a full one.
+	ecsEndpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if relative := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); relative != "" {
+		ecsEndpoint = defaultECSHost + relative
 	}
+	useECSMetadata := ecsEndpoint != ""
 
-	// Try ECS metadata service if configured
-	if c.UseECSMetadata {
-		if ecsCreds, err := getECSCredentials(); err == nil {
-			return ecsCreds, nil
+	// STS endpoint and session cache for profiles that assume a role
+	stsEndpoint := os.Getenv("AWS_ENDPOINT_URL_STS")
+	ssoEndpoint := os.Getenv("AWS_ENDPOINT_URL_SSO")
+	ssoOIDCEndpoint := os.Getenv("AWS_ENDPOINT_URL_SSO_OIDC")
+	ec2Endpoint := os.Getenv("AWS_ENDPOINT_URL_EC2")
+	cacheDir := os.Getenv("DYNAMIGHTEA_CACHE_DIR")
+	if cacheDir == "" {
+		if userCacheDir, err := os.UserCacheDir(); err == nil {
+			cacheDir = filepath.Join(userCacheDir, "dynamightea")
 		}
 	}
 
-	// Try IMDS if configured
-	if c.UseIMDS {
-		var imdsCreds *Credentials
-		var err error
-
-		switch c.IMDSVersion {
-		case "v1":
-			imdsCreds, err = getIMDSv1Credentials()
-		case "v2":
-			imdsCreds, err = getIMDSv2Credentials()
-		default:
-			// Try v2 first, fall back to v1
-			imdsCreds, err = getIMDSv2Credentials()
-			if err != nil {
-				imdsCreds, err = getIMDSv1Credentials()
-			}
-		}
+	// Demo mode must be requested explicitly
+	demo := os.Getenv("DYNAMIGHTEA_DEMO") == "true"
 
-		if err == nil && imdsCreds != nil {
-			return imdsCreds, nil
+	// Request timeouts
+	timeouts := DefaultTimeouts
+	if value := os.Getenv("DYNAMIGHTEA_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DYNAMIGHTEA_TIMEOUT: %w", err)
 		}
+		timeouts.Metadata, timeouts.Read, timeouts.Write = timeout, timeout, timeout
 	}
-
-	// Could add logic to parse AWS config/credentials files here
-
-	return nil, fmt.Errorf("unable to locate AWS credentials")
-}
-
-// getIMDSv1Credentials retrieves credentials from EC2 Instance Metadata Service (IMDSv1)
-func getIMDSv1Credentials() (*Credentials, error) {
-	// Get the role name from the instance metadata
-	client := &http.Client{
-		Timeout: 5 * time.Second,
-	}
-
-	resp, err := client.Get("http://169.254.169.254/latest/meta-data/iam/security-credentials/")
-	if err != nil {
-		return nil, fmt.Errorf("failed to get IAM role from IMDS: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get IAM role from IMDS: %s", resp.Status)
+	if value := os.Getenv("DYNAMIGHTEA_SCAN_TIMEOUT"); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid DYNAMIGHTEA_SCAN_TIMEOUT: %w", err)
+		}
+		timeouts.Scan = timeout
 	}
 
-	roleName, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read role name from IMDS: %v", err)
+	// UI and paging settings
+	pageSize := file.PageSize
+	if value := os.Getenv("DYNAMIGHTEA_PAGE_SIZE"); value != "" {
+		size, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || size <= 0 {
+			return nil, fmt.Errorf("invalid DYNAMIGHTEA_PAGE_SIZE %q: expected a positive number", value)
+		}
+		pageSize = int32(size)
 	}
-
-	// Get the credentials using the role name
-	resp, err = client.Get(fmt.Sprintf("http://169.254.169.254/latest/meta-data/iam/security-credentials/%s", roleName))
-	if err != nil {
-		return nil, fmt.Errorf("failed to get credentials from IMDS: %v", err)
+	theme := file.Theme
+	if value := os.Getenv("DYNAMIGHTEA_THEME"); value != "" {
+		if !slices.Contains(Themes, value) {
+			return nil, fmt.Errorf("invalid DYNAMIGHTEA_THEME %q: expected one of %s", value, strings.Join(Themes, ", "))
+		}
+		theme = value
+	}
+	readOnly := file.ReadOnly
+	if value := os.Getenv("DYNAMIGHTEA_READ_ONLY"); value != "" {
+		readOnly = value == "true"
+	}
+	// Read-only mode from the environment holds for every connection
+	forceReadOnly := os.Getenv("DYNAMIGHTEA_READ_ONLY") == "true"
+	regions := file.Regions
+	if value := os.Getenv("DYNAMIGHTEA_REGIONS"); value != "" {
+		if regions, err = ParseRegions(value); err != nil {
+			return nil, fmt.Errorf("invalid DYNAMIGHTEA_REGIONS: %w", err)
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get credentials from IMDS: %s", resp.Status)
+	cfg := &Config{
+		Region:          region,
+		Profile:         profile,
+		Endpoint:        endpoint,
+		ConfigFile:      configFile,
+		CredentialsFile: credentialsFile,
+		UseIMDS:         useIMDS,
+		IMDSVersion:     imdsVersion,
+		IMDSEndpoint:    imdsEndpoint,
+		UseECSMetadata:  useECSMetadata,
+		ECSEndpoint:     ecsEndpoint,
+		ECSAuthToken:    os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"),
+		ECSTokenFile:    os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"),
+		Demo:            demo,
+		Timeouts:        timeouts,
+		STSEndpoint:     stsEndpoint,
+		SSOEndpoint:     ssoEndpoint,
+		SSOOIDCEndpoint: ssoOIDCEndpoint,
+		EC2Endpoint:     ec2Endpoint,
+		PageSize:        pageSize,
+		Theme:           theme,
+		KeyBindings:     file.KeyBindings,
+		ReadOnly:        readOnly,
+		Connections:     file.Connections,
+		Regions:         regions,
+		profiles:        profiles,
+		profileFromEnv:  profileFromEnv,
+		regionFromEnv:   regionFromEnv,
+		endpointFromEnv: endpointFromEnv,
+		defaultRegion:   file.Region,
+		readOnly:        readOnly,
+		forceReadOnly:   forceReadOnly,
+		sessions:        newSessionCache(cacheDir),
+		imds:            &imdsProbe{},
+		ssoCacheDir:     filepath.Join(awsConfigDir, "sso", "cache"),
+	}
+
+	// A connection bundles a profile, region and endpoint. One picked in
+	// the environment replaces the environment's settings, but the default
+	// connection of the file does not.
+	if connection := os.Getenv("DYNAMIGHTEA_CONNECTION"); connection != "" {
+		if err := cfg.UseConnection(connection); err != nil {
+			return nil, err
+		}
+	} else if file.Connection != "" {
+		if err := cfg.useConnection(file.Connection, true); err != nil {
+			return nil, err
+		}
 	}
+	return cfg, nil
+}
 
-	var credResponse struct {
-		AccessKeyID     string `json:"AccessKeyId"`
-		SecretAccessKey string `json:"SecretAccessKey"`
-		Token           string `json:"Token"`
-		Expiration      string `json:"Expiration"`
+// metadataContext bounds a credential request made under ctx by the
+// metadata timeout
+func (c *Config) metadataContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if c.Timeouts.Metadata <= 0 {
+		return context.WithCancel(ctx)
 	}
+	return context.WithTimeout(ctx, c.Timeouts.Metadata)
+}
 
-	if err := json.NewDecoder(resp.Body).Decode(&credResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode credentials from IMDS: %v", err)
+// UseProfile switches to the named profile. The region is taken from the
+// profile unless it was set in the environment.
+func (c *Config) UseProfile(name string) {
+	c.Profile = name
+	if c.regionFromEnv {
+		return
 	}
-
-	expiration, err := time.Parse(time.RFC3339, credResponse.Expiration)
-	if err != nil {
-		expiration = time.Now().Add(1 * time.Hour) // Default expiration
+	c.Region = c.defaultRegion
+	if profile := c.profiles[name]; profile != nil && profile.Region != "" {
+		c.Region = profile.Region
 	}
+}
 
-	return &Credentials{
-		AccessKeyID:     credResponse.AccessKeyID,
-		SecretAccessKey: credResponse.SecretAccessKey,
-		SessionToken:    credResponse.Token,
-		Expiration:      expiration,
-	}, nil
+// UseConnection switches to the named connection of the config file. Its
+// profile, region and endpoint replace those of the environment. Its
+// read-only setting replaces that of the file, but cannot allow writes
+// when the environment or --read-only asked for read-only mode.
+func (c *Config) UseConnection(name string) error {
+	return c.useConnection(name, false)
 }
 
-// getIMDSv2Credentials retrieves credentials from EC2 Instance Metadata Service (IMDSv2)
-func getIMDSv2Credentials() (*Credentials, error) {
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+// useConnection switches to the named connection. With keepEnv, the
+// profile, region and endpoint set in the environment are kept.
+func (c *Config) useConnection(name string, keepEnv bool) error {
+	conn, ok := c.Connections[name]
+	if !ok {
+		return fmt.Errorf("connection %s is not defined in the config file", name)
 	}
-
-	// Step 1: Get a session token
-	tokenReq, err := http.NewRequest("PUT", "http://169.254.169.254/latest/api/token", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create token request: %v", err)
+	c.Connection = name
+	profile := conn.Profile
+	if profile == "" {
+		profile = "default"
 	}
-	tokenReq.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "21600") // 6 hours
-
-	tokenResp, err := client.Do(tokenReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get token from IMDSv2: %v", err)
+	if !keepEnv || !c.profileFromEnv {
+		c.UseProfile(profile)
 	}
-	defer tokenResp.Body.Close()
-
-	if tokenResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get token from IMDSv2: %s", tokenResp.Status)
+	if conn.Region != "" && (!keepEnv || !c.regionFromEnv) {
+		c.Region = conn.Region
 	}
-
-	token, err := io.ReadAll(tokenResp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read token from IMDSv2: %v", err)
+	if !keepEnv || !c.endpointFromEnv {
+		c.Endpoint = conn.Endpoint
 	}
-
-	// Step 2: Get the role name using the token
-	roleReq, err := http.NewRequest("GET", "http://169.254.169.254/latest/meta-data/iam/security-credentials/", nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create role request: %v", err)
+	c.ReadOnly = c.readOnly
+	if conn.ReadOnly != nil {
+		c.ReadOnly = *conn.ReadOnly
 	}
-	roleReq.Header.Set("X-aws-ec2-metadata-token", string(token))
+	c.ReadOnly = c.ReadOnly || c.forceReadOnly
+	c.Production = conn.Production
+	return nil
+}
 
-	roleResp, err := client.Do(roleReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get IAM role from IMDSv2: %v", err)
-	}
-	defer roleResp.Body.Close()
+// ForceReadOnly makes c read-only, including after switching to a
+// connection that allows writes
+func (c *Config) ForceReadOnly() {
+	c.ReadOnly = true
+	c.forceReadOnly = true
+}
 
-	if roleResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get IAM role from IMDSv2: %s", roleResp.Status)
+// WithConnection returns a copy of c that uses the named connection. The
+// copy resolves its own credentials, so it can be used alongside c.
+func (c *Config) WithConnection(name string) (*Config, error) {
+	clone := c.clone()
+	if err := clone.UseConnection(name); err != nil {
+		return nil, err
 	}
+	return clone, nil
+}
 
-	roleName, err := io.ReadAll(roleResp.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read role name from IMDSv2: %v", err)
-	}
+// WithRegion returns a copy of c for another region
+func (c *Config) WithRegion(region string) *Config {
+	clone := c.clone()
+	clone.Region = region
+	return clone
+}
 
-	// Step 3: Get the credentials using the role name and token
-	credsReq, err := http.NewRequest("GET", fmt.Sprintf("http://169.254.169.254/latest/meta-data/iam/security-credentials/%s", roleName), nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create credentials request: %v", err)
+// clone copies c without its credentials, which the copy resolves again
+func (c *Config) clone() *Config {
+	return &Config{
+		Region:          c.Region,
+		Profile:         c.Profile,
+		Endpoint:        c.Endpoint,
+		ConfigFile:      c.ConfigFile,
+		CredentialsFile: c.CredentialsFile,
+		UseIMDS:         c.UseIMDS,
+		IMDSVersion:     c.IMDSVersion,
+		IMDSEndpoint:    c.IMDSEndpoint,
+		UseECSMetadata:  c.UseECSMetadata,
+		ECSEndpoint:     c.ECSEndpoint,
+		ECSAuthToken:    c.ECSAuthToken,
+		ECSTokenFile:    c.ECSTokenFile,
+		Demo:            c.Demo,
+		Timeouts:        c.Timeouts,
+		STSEndpoint:     c.STSEndpoint,
+		SSOEndpoint:     c.SSOEndpoint,
+		SSOOIDCEndpoint: c.SSOOIDCEndpoint,
+		EC2Endpoint:     c.EC2Endpoint,
+		PageSize:        c.PageSize,
+		Theme:           c.Theme,
+		KeyBindings:     c.KeyBindings,
+		ReadOnly:        c.ReadOnly,
+		Production:      c.Production,
+		Connection:      c.Connection,
+		Connections:     c.Connections,
+		Regions:         c.Regions,
+		profiles:        c.profiles,
+		profileFromEnv:  c.profileFromEnv,
+		regionFromEnv:   c.regionFromEnv,
+		endpointFromEnv: c.endpointFromEnv,
+		defaultRegion:   c.defaultRegion,
+		readOnly:        c.readOnly,
+		forceReadOnly:   c.forceReadOnly,
+		sessions:        c.sessions,
+		imds:            c.imds,
+		ssoCacheDir:     c.ssoCacheDir,
 	}
-	credsReq.Header.Set("X-aws-ec2-metadata-token", string(token))
+}
 
-	credsResp, err := client.Do(credsReq)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get credentials from IMDSv2: %v", err)
-	}
-	defer credsResp.Body.Close()
+// ConnectionNames returns the names of the connections in the config
+// file in alphabetical order
+func (c *Config) ConnectionNames() []string {
+	return slices.Sorted(maps.Keys(c.Connections))
+}
 
-	if credsResp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get credentials from IMDSv2: %s", credsResp.Status)
-	}
+// LookupProfile returns a profile from the shared config files
+func (c *Config) LookupProfile(name string) (*Profile, bool) {
+	profile, ok := c.profiles[name]
+	return profile, ok
+}
 
-	var credResponse struct {
-		AccessKeyID     string `json:"AccessKeyId"`
-		SecretAccessKey string `json:"SecretAccessKey"`
-		Token           string `json:"Token"`
-		Expiration      string `json:"Expiration"`
+// GetCredentials attempts to retrieve AWS credentials from various sources
+func (c *Config) GetCredentials(ctx context.Context) (*Credentials, error) {
+	// First check environment variables (highest precedence)
+	creds := &Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Source:          "environment variables",
 	}
 
-	if err := json.NewDecoder(credsResp.Body).Decode(&credResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode credentials from IMDSv2: %v", err)
+	if creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
+		return creds, nil
 	}
 
-	expiration, err := time.Parse(time.RFC3339, credResponse.Expiration)
-	if err != nil {
-		expiration = time.Now().Add(1 * time.Hour) // Default expiration
+	// Then a web identity token, as provided to EKS pods
+	if identity := webIdentityFromEnv(); identity != nil {
+		return c.webIdentityCredentials(ctx, identity)
 	}
 
-	return &Credentials{
-		AccessKeyID:     credResponse.AccessKeyID,
-		SecretAccessKey: credResponse.SecretAccessKey,
-		SessionToken:    credResponse.Token,
-		Expiration:      expiration,
-	}, nil
-}
-
-// getECSCredentials retrieves credentials from ECS Task Metadata Endpoint
-func getECSCredentials() (*Credentials, error) {
-	// Get the credentials endpoint from environment
-	metadataEndpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI")
-	if metadataEndpoint == "" {
-		return nil, fmt.Errorf("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI not set")
+	// Then the selected profile in the shared config and credentials files.
+	// A missing default profile is not an error, but a named one is.
+	if _, ok := c.profiles[c.Profile]; ok || (c.Profile != "" && c.Profile != "default") {
+		return c.profileCredentials(ctx, c.Profile)
 	}
 
-	ecsEndpoint := fmt.Sprintf("http://169.254.170.2%s", metadataEndpoint)
+	// Every source that was tried, for the error if none has credentials
+	tried := []string{
+		"AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY are not set",
+		"no default profile in the shared config or credentials files",
+	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := c.metadataContext(ctx)
 	defer cancel()
 
-	req, err := http.NewRequestWithContext(ctx, "GET", ecsEndpoint, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request for ECS credentials: %v", err)
+	// Try the container credentials endpoint if configured
+	if c.UseECSMetadata {
+		ecsCreds, err := c.ecsCredentials(ctx)
+		if err == nil {
+			return ecsCreds, nil
+		}
+		tried = append(tried, err.Error())
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get credentials from ECS metadata: %v", err)
-	}
-	defer resp.Body.Close()
+	// Try IMDS if configured and the instance metadata service exists
+	if c.UseIMDS && !c.IMDSReachable() {
+		tried = append(tried, fmt.Sprintf("instance metadata service at %s is not reachable", c.IMDSEndpoint))
+	} else if c.UseIMDS {
+		var imdsCreds *Credentials
+		var err error
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("failed to get credentials from ECS metadata: %s", resp.Status)
-	}
+		switch c.IMDSVersion {
+		case "v1", "v2":
+			imdsCreds, err = c.imdsCredentials(ctx, c.IMDSVersion)
+		default:
+			// Try v2 first, fall back to v1
+			imdsCreds, err = c.imdsCredentials(ctx, "v2")
+			if err != nil {
+				imdsCreds, err = c.imdsCredentials(ctx, "v1")
+			}
+		}
 
-	var credResponse struct {
-		AccessKeyID     string `json:"AccessKeyId"`
-		SecretAccessKey string `json:"SecretAccessKey"`
-		Token           string `json:"Token"`
-		Expiration      string `json:"Expiration"`
+		if err == nil {
+			return imdsCreds, nil
+		}
+		tried = append(tried, err.Error())
 	}
 
-	if err := json.NewDecoder(resp.Body).Decode(&credResponse); err != nil {
-		return nil, fmt.Errorf("failed to decode credentials from ECS metadata: %v", err)
-	}
+	return nil, &NoCredentialsError{Tried: tried}
+}
 
-	expiration, err := time.Parse(time.RFC3339, credResponse.Expiration)
-	if err != nil {
-		expiration = time.Now().Add(1 * time.Hour) // Default expiration
-	}
+// NoCredentialsError is returned when no source has credentials. Tried
+// says why each source was passed over.
+type NoCredentialsError struct {
+	Tried []string
+}
 
-	return &Credentials{
-		AccessKeyID:     credResponse.AccessKeyID,
-		SecretAccessKey: credResponse.SecretAccessKey,
-		SessionToken:    credResponse.Token,
-		Expiration:      expiration,
-	}, nil
-}
\ No newline at end of file
+func (e *NoCredentialsError) Error() string {
+	return "unable to locate AWS credentials: " + strings.Join(e.Tried, "; ")
+}
@@ -1,34 +1,36 @@
 package config
 
 import (
+	"context"
 	"os"
 	"testing"
+	"time"
 )
 
 func TestLoadConfig(t *testing.T) {
 	// Save original environment
 	originalRegion := os.Getenv("AWS_REGION")
 	originalProfile := os.Getenv("AWS_PROFILE")
-	
+
 	defer func() {
 		// Restore original environment
 		os.Setenv("AWS_REGION", originalRegion)
 		os.Setenv("AWS_PROFILE", originalProfile)
 	}()
-	
+
 	// Test with environment variables
 	os.Setenv("AWS_REGION", "us-west-2")
 	os.Setenv("AWS_PROFILE", "testprofile")
-	
+
 	cfg, err := LoadConfig()
 	if err != nil {
 		t.Fatalf("Failed to load config: %v", err)
 	}
-	
+
 	if cfg.Region != "us-west-2" {
 		t.Errorf("Expected region to be us-west-2, got %s", cfg.Region)
 	}
-	
+
 	if cfg.Profile != "testprofile" {
 		t.Errorf("Expected profile to be testprofile, got %s", cfg.Profile)
 	}
@@ -39,35 +41,73 @@ func TestGetCredentials(t *testing.T) {
 	originalAccessKey := os.Getenv("AWS_ACCESS_KEY_ID")
 	originalSecretKey := os.Getenv("AWS_SECRET_ACCESS_KEY")
 	originalToken := os.Getenv("AWS_SESSION_TOKEN")
-	
+
 	defer func() {
 		// Restore original environment
 		os.Setenv("AWS_ACCESS_KEY_ID", originalAccessKey)
 		os.Setenv("AWS_SECRET_ACCESS_KEY", originalSecretKey)
 		os.Setenv("AWS_SESSION_TOKEN", originalToken)
 	}()
-	
+
 	// Test with environment variables
 	os.Setenv("AWS_ACCESS_KEY_ID", "test-access-key")
 	os.Setenv("AWS_SECRET_ACCESS_KEY", "test-secret-key")
 	os.Setenv("AWS_SESSION_TOKEN", "test-session-token")
-	
+
 	cfg, _ := LoadConfig()
-	creds, err := cfg.GetCredentials()
-	
+	creds, err := cfg.GetCredentials(context.Background())
+
 	if err != nil {
 		t.Fatalf("Failed to get credentials: %v", err)
 	}
-	
+
 	if creds.AccessKeyID != "test-access-key" {
 		t.Errorf("Expected access key to be test-access-key, got %s", creds.AccessKeyID)
 	}
-	
+
 	if creds.SecretAccessKey != "test-secret-key" {
 		t.Errorf("Expected secret key to be test-secret-key, got %s", creds.SecretAccessKey)
 	}
-	
+
 	if creds.SessionToken != "test-session-token" {
 		t.Errorf("Expected session token to be test-session-token, got %s", creds.SessionToken)
 	}
-}
\ No newline at end of file
+}
+
+func TestLoadConfigTimeouts(t *testing.T) {
+	tests := []struct {
+		name        string
+		timeout     string
+		scanTimeout string
+		want        Timeouts
+		wantErr     bool
+	}{
+		{"defaults", "", "", DefaultTimeouts, false},
+		{"one timeout for metadata, reads and writes", "5s", "", Timeouts{Metadata: 5 * time.Second, Read: 5 * time.Second, Scan: 5 * time.Minute, Write: 5 * time.Second}, false},
+		{"scan timeout", "", "90s", Timeouts{Metadata: 30 * time.Second, Read: 30 * time.Second, Scan: 90 * time.Second, Write: 30 * time.Second}, false},
+		{"both", "5s", "90s", Timeouts{Metadata: 5 * time.Second, Read: 5 * time.Second, Scan: 90 * time.Second, Write: 5 * time.Second}, false},
+		{"invalid timeout", "soon", "", Timeouts{}, true},
+		{"invalid scan timeout", "", "later", Timeouts{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeProfiles(t, "", "")
+			t.Setenv("DYNAMIGHTEA_TIMEOUT", tt.timeout)
+			t.Setenv("DYNAMIGHTEA_SCAN_TIMEOUT", tt.scanTimeout)
+
+			cfg, err := LoadConfig()
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected an error for an invalid timeout, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			if cfg.Timeouts != tt.want {
+				t.Errorf("Expected timeouts %+v, got %+v", tt.want, cfg.Timeouts)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AllRegions in a list of regions stands for every region enabled for the
+// account
+const AllRegions = "all"
+
+// Themes are the color schemes of the UI
+var Themes = []string{"dark", "light"}
+
+// KeyActions are the UI actions that can be bound to other keys in the
+// keybindings section of the config file
+var KeyActions = []string{"quit", "scan", "filter", "query", "credentials", "connections", "regions", "console", "edit", "new", "delete", "mark", "display"}
+
+// FileConfig is the application config file. Every setting is a default:
+// flags and environment variables take precedence.
+type FileConfig struct {
+	Region      string                `yaml:"region"`
+	Profile     string                `yaml:"profile"`
+	Endpoint    string                `yaml:"endpoint"`
+	PageSize    int32                 `yaml:"page_size"`
+	Theme       string                `yaml:"theme"`
+	KeyBindings map[string]string     `yaml:"keybindings"` // Action to key, such as "scan: S"
+	ReadOnly    bool                  `yaml:"read_only"`
+	Connection  string                `yaml:"connection"` // Connection to start with
+	Connections map[string]Connection `yaml:"connections"`
+	Regions     []string              `yaml:"regions"` // Regions to list tables in, or [all]
+}
+
+// Connection is a named combination of profile, region and endpoint, such
+// as "prod-us-east-1" or "local". The profile defaults to "default" and the
+// region to that of the profile. ReadOnly, if set, replaces the read_only
+// setting of the file; Production asks for the table name before items or
+// attributes are deleted.
+type Connection struct {
+	Profile    string `yaml:"profile"`
+	Region     string `yaml:"region"`
+	Endpoint   string `yaml:"endpoint"`
+	ReadOnly   *bool  `yaml:"read_only"`
+	Production bool   `yaml:"production"`
+}
+
+// Tags names the guardrails of the connection, such as "production"
+func (c Connection) Tags() []string {
+	var tags []string
+	if c.Production {
+		tags = append(tags, "production")
+	}
+	if c.ReadOnly != nil && *c.ReadOnly {
+		tags = append(tags, "read-only")
+	}
+	return tags
+}
+
+// ConfigFilePath returns where the config file is read from:
+// DYNAMIGHTEA_CONFIG, or dynamightea/config.yaml under XDG_CONFIG_HOME,
+// which defaults to ~/.config
+func ConfigFilePath() string {
+	if path := os.Getenv("DYNAMIGHTEA_CONFIG"); path != "" {
+		return path
+	}
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		homeDir, _ := os.UserHomeDir()
+		configHome = filepath.Join(homeDir, ".config")
+	}
+	return filepath.Join(configHome, "dynamightea", "config.yaml")
+}
+
+// LoadFileConfig reads and validates the config file at path. A missing
+// file is an empty configuration.
+func LoadFileConfig(path string) (*FileConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &FileConfig{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var file FileConfig
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true) // Report misspelled settings
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("config file %s: %s", path, describeYAMLError(err))
+	}
+	if err := file.validate(); err != nil {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+	return &file, nil
+}
+
+// describeYAMLError lists the problems in err one after another, naming
+// settings the way they appear in the file
+func describeYAMLError(err error) string {
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		return strings.TrimPrefix(err.Error(), "yaml: ")
+	}
+	problems := make([]string, len(typeErr.Errors))
+	for i, problem := range typeErr.Errors {
+		if field, ok := strings.CutSuffix(problem, " not found in type config.FileConfig"); ok {
+			problem = strings.Replace(field, "field ", "unknown setting ", 1)
+		}
+		problems[i] = problem
+	}
+	return strings.Join(problems, "; ")
+}
+
+// validate checks the values that the YAML types do not and normalizes
+// the key names
+func (f *FileConfig) validate() error {
+	if f.PageSize < 0 {
+		return fmt.Errorf("page_size must be positive, got %d", f.PageSize)
+	}
+	if f.Theme != "" && !slices.Contains(Themes, f.Theme) {
+		return fmt.Errorf("unknown theme %q, expected one of %s", f.Theme, strings.Join(Themes, ", "))
+	}
+
+	// Sorted, so a conflict is reported the same way every time
+	boundTo := map[string]string{}
+	for _, action := range slices.Sorted(maps.Keys(f.KeyBindings)) {
+		if !slices.Contains(KeyActions, action) {
+			return fmt.Errorf("unknown action %q in keybindings, expected one of %s", action, strings.Join(KeyActions, ", "))
+		}
+		key := f.KeyBindings[action]
+		if key == "space" {
+			key = " "
+		}
+		if key == "" {
+			return fmt.Errorf("no key for action %q in keybindings", action)
+		}
+		if other, ok := boundTo[key]; ok {
+			return fmt.Errorf("key %q is bound to both %s and %s", f.KeyBindings[action], other, action)
+		}
+		boundTo[key] = action
+		f.KeyBindings[action] = key
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(f.Connections)) {
+		conn := f.Connections[name]
+		if name == "" {
+			return fmt.Errorf("a connection has no name")
+		}
+		if conn.Profile == "" && conn.Region == "" && conn.Endpoint == "" {
+			return fmt.Errorf("connection %s sets none of profile, region and endpoint", name)
+		}
+		if conn.Endpoint != "" {
+			if u, err := url.Parse(conn.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("connection %s: endpoint %q is not an http or https URL", name, conn.Endpoint)
+			}
+		}
+	}
+	if _, ok := f.Connections[f.Connection]; f.Connection != "" && !ok {
+		return fmt.Errorf("connection %s is not defined under connections", f.Connection)
+	}
+	if len(f.Regions) > 0 {
+		regions, err := ParseRegions(strings.Join(f.Regions, ","))
+		if err != nil {
+			return fmt.Errorf("regions: %w", err)
+		}
+		f.Regions = regions
+	}
+	return nil
+}
+
+// regionPattern matches region names such as us-east-1 or us-gov-west-1
+var regionPattern = regexp.MustCompile(`^[a-z]{2}(-[a-z]+)+-[0-9]+$`)
+
+// ParseRegions parses a comma-separated list of regions, or "all" for
+// every region enabled for the account
+func ParseRegions(value string) ([]string, error) {
+	var regions []string
+	for _, region := range strings.Split(value, ",") {
+		region = strings.TrimSpace(region)
+		if region == "" {
+			continue
+		}
+		if region != AllRegions && !regionPattern.MatchString(region) {
+			return nil, fmt.Errorf("%q is not a region name such as us-east-1", region)
+		}
+		if !slices.Contains(regions, region) {
+			regions = append(regions, region)
+		}
+	}
+	if len(regions) == 0 {
+		return nil, fmt.Errorf("no regions given")
+	}
+	if len(regions) > 1 && slices.Contains(regions, AllRegions) {
+		return nil, fmt.Errorf("%q cannot be combined with other regions", AllRegions)
+	}
+	return regions, nil
+}
@@ -0,0 +1,319 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeConfigFile writes the dynamightea config file where LoadConfig
+// looks for it. Call writeProfiles first.
+func writeConfigFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "dynamightea", "config.yaml")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+const testAppConfigFile = `# dynamightea settings
+region: ap-southeast-2
+profile: dev
+endpoint: http://localhost:8000
+page_size: 25
+theme: light
+read_only: true
+regions: [us-east-1, eu-west-1]
+keybindings:
+  scan: S
+  mark: space
+`
+
+func TestLoadConfigFile(t *testing.T) {
+	writeProfiles(t, testConfigFile, testCredentialsFile)
+	writeConfigFile(t, testAppConfigFile)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	// The region of profile dev takes precedence over the file's
+	if cfg.Profile != "dev" || cfg.Region != "us-west-2" || cfg.Endpoint != "http://localhost:8000" {
+		t.Errorf("Expected profile dev in us-west-2 at localhost, got %s in %s at %s", cfg.Profile, cfg.Region, cfg.Endpoint)
+	}
+	if cfg.PageSize != 25 || cfg.Theme != "light" || !cfg.ReadOnly {
+		t.Errorf("Expected the page size, theme and read-only mode of the file, got %d, %q, %v", cfg.PageSize, cfg.Theme, cfg.ReadOnly)
+	}
+	if cfg.KeyBindings["scan"] != "S" || cfg.KeyBindings["mark"] != " " {
+		t.Errorf("Expected the keybindings of the file, got %v", cfg.KeyBindings)
+	}
+	if !slices.Equal(cfg.Regions, []string{"us-east-1", "eu-west-1"}) {
+		t.Errorf("Expected the regions of the file, got %v", cfg.Regions)
+	}
+
+	// A profile without a region uses the file's
+	cfg.UseProfile("chained")
+	if cfg.Region != "ap-southeast-2" {
+		t.Errorf("Expected the region of the file, got %s", cfg.Region)
+	}
+}
+
+func TestLoadConfigEnvOverridesFile(t *testing.T) {
+	writeProfiles(t, testConfigFile, testCredentialsFile)
+	writeConfigFile(t, testAppConfigFile)
+	t.Setenv("AWS_PROFILE", "default")
+	t.Setenv("AWS_REGION", "eu-central-1")
+	t.Setenv("AWS_DYNAMODB_ENDPOINT", "http://localhost:9000")
+	t.Setenv("DYNAMIGHTEA_PAGE_SIZE", "200")
+	t.Setenv("DYNAMIGHTEA_THEME", "dark")
+	t.Setenv("DYNAMIGHTEA_READ_ONLY", "false")
+	t.Setenv("DYNAMIGHTEA_REGIONS", "all")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Profile != "default" || cfg.Region != "eu-central-1" || cfg.Endpoint != "http://localhost:9000" {
+		t.Errorf("Expected the environment's profile, region and endpoint, got %s, %s, %s", cfg.Profile, cfg.Region, cfg.Endpoint)
+	}
+	if cfg.PageSize != 200 || cfg.Theme != "dark" || cfg.ReadOnly {
+		t.Errorf("Expected the environment's page size, theme and read-only mode, got %d, %q, %v", cfg.PageSize, cfg.Theme, cfg.ReadOnly)
+	}
+	if !slices.Equal(cfg.Regions, []string{AllRegions}) {
+		t.Errorf("Expected all regions, got %v", cfg.Regions)
+	}
+}
+
+func TestLoadConfigFileErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"syntax", "region: [us-east-1\n", "line 1"},
+		{"unknown setting", "regoin: us-east-1\n", "line 1: unknown setting regoin"},
+		{"wrong type", "page_size: lots\n", "line 1: cannot unmarshal !!str `lots` into int32"},
+		{"negative page size", "page_size: -5\n", "page_size must be positive, got -5"},
+		{"theme", "theme: solarized\n", `unknown theme "solarized", expected one of dark, light`},
+		{"action", "keybindings:\n  launch: l\n", `unknown action "launch" in keybindings`},
+		{"empty key", "keybindings:\n  scan: \"\"\n", `no key for action "scan" in keybindings`},
+		{"conflict", "keybindings:\n  scan: x\n  quit: x\n", `key "x" is bound to both quit and scan`},
+		{"empty connection", "connections:\n  local: {}\n", "connection local sets none of profile, region and endpoint"},
+		{"endpoint", "connections:\n  local:\n    endpoint: localhost:8000\n", `connection local: endpoint "localhost:8000" is not an http or https URL`},
+		{"missing connection", "connection: prod\n", "connection prod is not defined under connections"},
+		{"region name", "regions: [us-east-1, useast2]\n", `regions: "useast2" is not a region name such as us-east-1`},
+		{"all regions", "regions: [all, us-east-1]\n", `regions: "all" cannot be combined with other regions`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeProfiles(t, "", "")
+			path := writeConfigFile(t, tt.content)
+
+			_, err := LoadConfig()
+			if err == nil {
+				t.Fatal("Expected an error, got nil")
+			}
+			if !strings.HasPrefix(err.Error(), "config file "+path+": ") || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error about %s in %s, got %v", tt.want, path, err)
+			}
+		})
+	}
+}
+
+const testConnectionsFile = `connection: local
+connections:
+  local:
+    region: us-west-2
+    endpoint: http://localhost:8000
+  dev-eu:
+    profile: dev
+    region: eu-west-1
+`
+
+func TestLoadConfigConnection(t *testing.T) {
+	writeProfiles(t, testConfigFile, testCredentialsFile)
+	writeConfigFile(t, testConnectionsFile)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Connection != "local" || cfg.Profile != "default" || cfg.Region != "us-west-2" || cfg.Endpoint != "http://localhost:8000" {
+		t.Errorf("Expected connection local, got %s with profile %s in %s at %s", cfg.Connection, cfg.Profile, cfg.Region, cfg.Endpoint)
+	}
+	if names := strings.Join(cfg.ConnectionNames(), ","); names != "dev-eu,local" {
+		t.Errorf("Expected the connections in alphabetical order, got %s", names)
+	}
+
+	switched, err := cfg.WithConnection("dev-eu")
+	if err != nil {
+		t.Fatalf("Failed to switch connection: %v", err)
+	}
+	if switched.Connection != "dev-eu" || switched.Profile != "dev" || switched.Region != "eu-west-1" || switched.Endpoint != "" {
+		t.Errorf("Expected connection dev-eu, got %s with profile %s in %s at %s", switched.Connection, switched.Profile, switched.Region, switched.Endpoint)
+	}
+	if cfg.Connection != "local" || cfg.Endpoint != "http://localhost:8000" {
+		t.Errorf("Expected the original config to be unchanged, got %s at %s", cfg.Connection, cfg.Endpoint)
+	}
+	if _, err := cfg.WithConnection("prod"); err == nil {
+		t.Error("Expected an error for an unknown connection, got nil")
+	}
+
+	t.Setenv("DYNAMIGHTEA_CONNECTION", "dev-eu")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Connection != "dev-eu" {
+		t.Errorf("Expected DYNAMIGHTEA_CONNECTION to override the file, got %s", cfg.Connection)
+	}
+}
+
+func TestLoadConfigConnectionEnv(t *testing.T) {
+	const file = `connection: dev-eu
+connections:
+  dev-eu:
+    profile: dev
+    region: eu-west-1
+    endpoint: http://localhost:8000
+    read_only: false
+`
+	tests := []struct {
+		name     string
+		env      map[string]string
+		profile  string
+		region   string
+		endpoint string
+		readOnly bool
+	}{
+		{"file only", nil, "dev", "eu-west-1", "http://localhost:8000", false},
+		{"environment over default connection", map[string]string{
+			"AWS_PROFILE":           "default",
+			"AWS_REGION":            "us-east-1",
+			"AWS_DYNAMODB_ENDPOINT": "http://localhost:9000",
+			"DYNAMIGHTEA_READ_ONLY": "true",
+		}, "default", "us-east-1", "http://localhost:9000", true},
+		{"region only", map[string]string{"AWS_REGION": "us-east-1"}, "dev", "us-east-1", "http://localhost:8000", false},
+		{"connection picked in the environment", map[string]string{
+			"DYNAMIGHTEA_CONNECTION": "dev-eu",
+			"AWS_PROFILE":            "default",
+			"AWS_REGION":             "us-east-1",
+			"DYNAMIGHTEA_READ_ONLY":  "true",
+		}, "dev", "eu-west-1", "http://localhost:8000", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writeProfiles(t, testConfigFile, testCredentialsFile)
+			writeConfigFile(t, file)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			if cfg.Connection != "dev-eu" || cfg.Profile != tt.profile || cfg.Region != tt.region || cfg.Endpoint != tt.endpoint {
+				t.Errorf("Expected connection dev-eu with profile %s in %s at %s, got %s with profile %s in %s at %s",
+					tt.profile, tt.region, tt.endpoint, cfg.Connection, cfg.Profile, cfg.Region, cfg.Endpoint)
+			}
+			if cfg.ReadOnly != tt.readOnly {
+				t.Errorf("Expected read-only %v, got %v", tt.readOnly, cfg.ReadOnly)
+			}
+		})
+	}
+}
+
+func TestParseRegions(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"us-east-1", []string{"us-east-1"}},
+		{" eu-west-1, us-gov-west-1 ,eu-west-1,", []string{"eu-west-1", "us-gov-west-1"}},
+		{"all", []string{AllRegions}},
+		{"", nil},
+		{"us-east-1,all", nil},
+		{"US-EAST-1", nil},
+	}
+	for _, tt := range tests {
+		regions, err := ParseRegions(tt.value)
+		if tt.want == nil {
+			if err == nil {
+				t.Errorf("ParseRegions(%q): expected an error, got %v", tt.value, regions)
+			}
+			continue
+		}
+		if err != nil || !slices.Equal(regions, tt.want) {
+			t.Errorf("ParseRegions(%q) = %v, %v; expected %v", tt.value, regions, err, tt.want)
+		}
+	}
+}
+
+const testGuardedConnectionsFile = `read_only: true
+connections:
+  prod:
+    profile: dev
+    production: true
+  local:
+    endpoint: http://localhost:8000
+    read_only: false
+  staging:
+    region: eu-west-1
+`
+
+func TestLoadConfigConnectionReadOnly(t *testing.T) {
+	writeProfiles(t, testConfigFile, testCredentialsFile)
+	writeConfigFile(t, testGuardedConnectionsFile)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	tests := []struct {
+		connection string
+		readOnly   bool
+		production bool
+	}{
+		{"prod", true, true},
+		{"local", false, false},
+		{"staging", true, false},
+	}
+	for _, tt := range tests {
+		switched, err := cfg.WithConnection(tt.connection)
+		if err != nil {
+			t.Fatalf("Failed to switch to %s: %v", tt.connection, err)
+		}
+		if switched.ReadOnly != tt.readOnly || switched.Production != tt.production {
+			t.Errorf("%s: expected read-only %v and production %v, got %v and %v", tt.connection, tt.readOnly, tt.production, switched.ReadOnly, switched.Production)
+		}
+	}
+
+	// The --read-only flag wins over a connection that allows writes
+	cfg.ForceReadOnly()
+	switched, err := cfg.WithConnection("local")
+	if err != nil {
+		t.Fatalf("Failed to switch to local: %v", err)
+	}
+	if !switched.ReadOnly {
+		t.Error("Expected the forced read-only mode to survive switching to local")
+	}
+}
+
+func TestConfigFilePath(t *testing.T) {
+	t.Setenv("DYNAMIGHTEA_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "/etc/xdg")
+	if got := ConfigFilePath(); got != filepath.Join("/etc/xdg", "dynamightea", "config.yaml") {
+		t.Errorf("Expected the file under XDG_CONFIG_HOME, got %s", got)
+	}
+	t.Setenv("DYNAMIGHTEA_CONFIG", "/tmp/dt.yaml")
+	if got := ConfigFilePath(); got != "/tmp/dt.yaml" {
+		t.Errorf("Expected DYNAMIGHTEA_CONFIG, got %s", got)
+	}
+}
@@ -0,0 +1,298 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default metadata endpoints
+const (
+	defaultIMDSEndpoint     = "http://169.254.169.254"
+	defaultIMDSEndpointIPv6 = "http://[fd00:ec2::254]"
+	defaultECSHost          = "http://169.254.170.2"
+)
+
+// imdsProbeTimeout bounds the connection attempt that checks whether the
+// instance metadata service exists. On EC2 it answers within milliseconds.
+const imdsProbeTimeout = 300 * time.Millisecond
+
+// metadataClient fetches credentials from the EC2 instance metadata service
+// and the ECS or EKS Pod Identity container credentials endpoint
+type metadataClient struct {
+	http     *http.Client
+	attempts int
+	backoff  time.Duration // Doubled after each failed attempt
+}
+
+// newMetadataClient creates a client that gives each request a few
+// seconds, because the endpoints are local or do not exist at all
+func newMetadataClient() *metadataClient {
+	return &metadataClient{
+		http:     &http.Client{Timeout: 5 * time.Second},
+		attempts: 3,
+		backoff:  100 * time.Millisecond,
+	}
+}
+
+// metadataError is a response with an unexpected status code
+type metadataError struct {
+	URL    string
+	Status string
+	Code   int
+}
+
+func (e *metadataError) Error() string {
+	return fmt.Sprintf("%s: %s", e.URL, e.Status)
+}
+
+// do sends a request and returns the response body. Server errors,
+// throttling and refused connections are retried; timeouts are not, so a
+// machine without a metadata service does not stall for long.
+func (m *metadataClient) do(ctx context.Context, method, endpoint string, header http.Header) ([]byte, error) {
+	backoff := m.backoff
+	var lastErr error
+	for attempt := 0; attempt < m.attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(backoff):
+				backoff *= 2
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		body, err := m.send(ctx, method, endpoint, header)
+		if err == nil {
+			return body, nil
+		}
+		lastErr = err
+		if !retryable(err) {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+func (m *metadataClient) send(ctx context.Context, method, endpoint string, header http.Header) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range header {
+		req.Header[key] = values
+	}
+
+	resp, err := m.http.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, &metadataError{URL: endpoint, Status: resp.Status, Code: resp.StatusCode}
+	}
+	return body, nil
+}
+
+// retryable reports whether a failed metadata request may succeed later
+func retryable(err error) bool {
+	var statusErr *metadataError
+	if errors.As(err, &statusErr) {
+		return statusErr.Code >= 500 || statusErr.Code == http.StatusTooManyRequests
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return false
+	}
+	return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+}
+
+// metadataCredentials is the credentials document served by IMDS and the
+// container credentials endpoint
+type metadataCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+	Expiration      string `json:"Expiration"`
+}
+
+// parseMetadataCredentials decodes a credentials document from source
+func parseMetadataCredentials(source string, body []byte) (*Credentials, error) {
+	var doc metadataCredentials
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode credentials from %s: %w", source, err)
+	}
+	if doc.AccessKeyID == "" || doc.SecretAccessKey == "" {
+		return nil, fmt.Errorf("no credentials in response from %s", source)
+	}
+
+	expiration, err := time.Parse(time.RFC3339, doc.Expiration)
+	if err != nil {
+		expiration = time.Now().Add(1 * time.Hour) // Default expiration
+	}
+
+	return &Credentials{
+		AccessKeyID:     doc.AccessKeyID,
+		SecretAccessKey: doc.SecretAccessKey,
+		SessionToken:    doc.Token,
+		Expiration:      expiration,
+	}, nil
+}
+
+// imdsProbe holds the result of the IMDS reachability check. Copies of a
+// Config share it, so the check runs once per session.
+type imdsProbe struct {
+	once sync.Once
+	up   bool
+}
+
+// IMDSReachable reports whether a TCP connection to the instance metadata
+// service can be opened. The check runs once per session; outside EC2 it
+// fails quickly and every later credential lookup skips IMDS instead of
+// waiting for request timeouts.
+func (c *Config) IMDSReachable() bool {
+	if c.imds == nil {
+		return dialIMDS(c.IMDSEndpoint)
+	}
+	c.imds.once.Do(func() {
+		c.imds.up = dialIMDS(c.IMDSEndpoint)
+	})
+	return c.imds.up
+}
+
+// dialIMDS opens and closes a TCP connection to endpoint
+func dialIMDS(endpoint string) bool {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Hostname() == "" {
+		return false
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	conn, err := net.DialTimeout("tcp", net.JoinHostPort(u.Hostname(), port), imdsProbeTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+// imdsCredentials retrieves the credentials of the instance role from the
+// EC2 instance metadata service. Version "v2" first gets a session token;
+// "v1" sends unauthenticated requests.
+func (c *Config) imdsCredentials(ctx context.Context, version string) (*Credentials, error) {
+	client := newMetadataClient()
+	base := strings.TrimRight(c.IMDSEndpoint, "/")
+	header := http.Header{}
+
+	if version == "v2" {
+		token, err := client.do(ctx, http.MethodPut, base+"/latest/api/token", http.Header{
+			"X-Aws-Ec2-Metadata-Token-Ttl-Seconds": {"21600"}, // 6 hours
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get token from IMDSv2: %w", err)
+		}
+		header.Set("X-aws-ec2-metadata-token", string(token))
+	}
+
+	source := "IMDS" + version
+	roles, err := client.do(ctx, http.MethodGet, base+"/latest/meta-data/iam/security-credentials/", header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get IAM role from %s: %w", source, err)
+	}
+	role, _, _ := strings.Cut(strings.TrimSpace(string(roles)), "\n")
+	if role == "" {
+		return nil, fmt.Errorf("no IAM role attached to the instance")
+	}
+
+	body, err := client.do(ctx, http.MethodGet, base+"/latest/meta-data/iam/security-credentials/"+url.PathEscape(role), header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials from %s: %w", source, err)
+	}
+	creds, err := parseMetadataCredentials(source, body)
+	if err != nil {
+		return nil, err
+	}
+	creds.Source = fmt.Sprintf("%s instance role %s", source, role)
+	return creds, nil
+}
+
+// ecsCredentials retrieves credentials from the container credentials
+// endpoint used by ECS tasks and EKS Pod Identity
+func (c *Config) ecsCredentials(ctx context.Context) (*Credentials, error) {
+	if c.ECSEndpoint == "" {
+		return nil, fmt.Errorf("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI and AWS_CONTAINER_CREDENTIALS_FULL_URI are not set")
+	}
+	if err := checkContainerEndpoint(c.ECSEndpoint); err != nil {
+		return nil, err
+	}
+
+	header := http.Header{}
+	token := c.ECSAuthToken
+	if c.ECSTokenFile != "" {
+		// The file is rotated, so it is read on every request
+		data, err := os.ReadFile(c.ECSTokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read container authorization token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		header.Set("Authorization", token)
+	}
+
+	body, err := newMetadataClient().do(ctx, http.MethodGet, c.ECSEndpoint, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credentials from container endpoint: %w", err)
+	}
+	creds, err := parseMetadataCredentials("container endpoint", body)
+	if err != nil {
+		return nil, err
+	}
+	creds.Source = "container endpoint " + c.ECSEndpoint
+	return creds, nil
+}
+
+// checkContainerEndpoint only allows plain HTTP to the link-local container
+// endpoints and loopback, so credentials are never sent over the network
+// unencrypted
+func checkContainerEndpoint(endpoint string) error {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return fmt.Errorf("invalid container credentials endpoint %q: %w", endpoint, err)
+	}
+	if u.Scheme == "https" {
+		return nil
+	}
+	if u.Scheme != "http" {
+		return fmt.Errorf("container credentials endpoint %s must use http or https", endpoint)
+	}
+
+	host := u.Hostname()
+	switch host {
+	case "localhost", "169.254.170.2", "169.254.170.23", "fd00:ec2::23":
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("container credentials endpoint %s must use https or a loopback or link-local host", endpoint)
+}
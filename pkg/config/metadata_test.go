@@ -0,0 +1,234 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// stubIMDS serves instance role credentials like the EC2 instance metadata
+// service. Requests for the role name fail with 503 while failures is
+// positive.
+type stubIMDS struct {
+	mu       sync.Mutex
+	failures int
+	requests []string
+}
+
+func (s *stubIMDS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+
+	const token = "imds-token"
+	if r.URL.Path == "/latest/api/token" {
+		if r.Method != http.MethodPut || r.Header.Get("X-aws-ec2-metadata-token-ttl-seconds") == "" {
+			http.Error(w, "bad token request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, token)
+		return
+	}
+	if r.Header.Get("X-aws-ec2-metadata-token") != token && r.Header.Get("X-aws-ec2-metadata-token") != "" {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+
+	switch r.URL.Path {
+	case "/latest/meta-data/iam/security-credentials/":
+		if s.failures > 0 {
+			s.failures--
+			http.Error(w, "busy", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, "web-role\n")
+	case "/latest/meta-data/iam/security-credentials/web-role":
+		fmt.Fprintf(w, `{"Code":"Success","AccessKeyId":"ASIAIMDS","SecretAccessKey":"imds-secret","Token":"imds-session","Expiration":%q}`,
+			time.Now().Add(6*time.Hour).UTC().Format(time.RFC3339))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *stubIMDS) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.requests...)
+}
+
+func TestIMDSCredentials(t *testing.T) {
+	tests := []struct {
+		version  string
+		failures int
+		want     []string
+	}{
+		{
+			version: "v2",
+			want: []string{
+				"PUT /latest/api/token",
+				"GET /latest/meta-data/iam/security-credentials/",
+				"GET /latest/meta-data/iam/security-credentials/web-role",
+			},
+		},
+		{
+			version:  "v1",
+			failures: 2,
+			want: []string{
+				"GET /latest/meta-data/iam/security-credentials/",
+				"GET /latest/meta-data/iam/security-credentials/",
+				"GET /latest/meta-data/iam/security-credentials/",
+				"GET /latest/meta-data/iam/security-credentials/web-role",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			writeProfiles(t, "", "")
+			stub := &stubIMDS{failures: tt.failures}
+			server := httptest.NewServer(stub)
+			defer server.Close()
+			t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+			t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", server.URL)
+			t.Setenv("AWS_IMDS_VERSION", tt.version)
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			creds, err := cfg.GetCredentials(context.Background())
+			if err != nil {
+				t.Fatalf("Failed to get IMDS credentials: %v", err)
+			}
+			if creds.AccessKeyID != "ASIAIMDS" || creds.SessionToken != "imds-session" || time.Until(creds.Expiration) < 5*time.Hour {
+				t.Errorf("Expected the instance role credentials, got %+v", creds)
+			}
+			if want := "IMDS" + tt.version + " instance role web-role"; creds.Source != want {
+				t.Errorf("Expected source %q, got %q", want, creds.Source)
+			}
+			if got := strings.Join(stub.Requests(), "\n"); got != strings.Join(tt.want, "\n") {
+				t.Errorf("Expected requests:\n%s\ngot:\n%s", strings.Join(tt.want, "\n"), got)
+			}
+		})
+	}
+}
+
+func TestIMDSUnreachable(t *testing.T) {
+	writeProfiles(t, "", "")
+	// A port that was just released refuses connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := "http://" + listener.Addr().String()
+	listener.Close()
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", closed)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	start := time.Now()
+	_, err = cfg.GetCredentials(context.Background())
+	var noCreds *NoCredentialsError
+	if !errors.As(err, &noCreds) {
+		t.Fatalf("Expected NoCredentialsError, got %v", err)
+	}
+	if last := noCreds.Tried[len(noCreds.Tried)-1]; last != "instance metadata service at "+closed+" is not reachable" {
+		t.Errorf("Expected IMDS to be reported unreachable, got %q", last)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected the probe to fail fast, took %s", elapsed)
+	}
+
+	// The result is kept, so a later lookup does not probe again
+	stub := &stubIMDS{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	cfg.IMDSEndpoint = server.URL
+	if _, err := cfg.GetCredentials(context.Background()); err == nil {
+		t.Error("Expected IMDS to stay skipped, got credentials")
+	}
+	if requests := stub.Requests(); len(requests) != 0 {
+		t.Errorf("Expected no requests to IMDS, got %v", requests)
+	}
+
+	// Nor do copies of the config for other regions or connections
+	clone := cfg.WithRegion("eu-west-1")
+	if clone.IMDSReachable() {
+		t.Error("Expected a copy of the config to keep the failed probe")
+	}
+	if _, err := clone.GetCredentials(context.Background()); err == nil {
+		t.Error("Expected IMDS to stay skipped for the copy, got credentials")
+	}
+	if requests := stub.Requests(); len(requests) != 0 {
+		t.Errorf("Expected no requests to IMDS, got %v", requests)
+	}
+}
+
+func TestContainerCredentialsFullURI(t *testing.T) {
+	writeProfiles(t, "", "")
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		if r.URL.Path != "/v1/credentials" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, `{"AccessKeyId":"ASIAPOD","SecretAccessKey":"pod-secret","Token":"pod-session","Expiration":"2030-01-01T00:00:00Z"}`)
+	}))
+	defer server.Close()
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("pod-identity-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", server.URL+"/v1/credentials")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN", "ignored")
+	t.Setenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE", tokenFile)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	creds, err := cfg.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get container credentials: %v", err)
+	}
+	if creds.AccessKeyID != "ASIAPOD" || creds.Expiration.Year() != 2030 || creds.Source != "container endpoint "+server.URL+"/v1/credentials" {
+		t.Errorf("Expected the pod credentials, got %+v", creds)
+	}
+	if authorization != "pod-identity-token" {
+		t.Errorf("Expected the token from the file in the Authorization header, got %q", authorization)
+	}
+}
+
+func TestCheckContainerEndpoint(t *testing.T) {
+	allowed := []string{
+		"http://169.254.170.2/v2/credentials/abc",
+		"http://169.254.170.23/v1/credentials",
+		"http://[fd00:ec2::23]/v1/credentials",
+		"http://127.0.0.1:8080/creds",
+		"http://localhost/creds",
+		"https://creds.example.com/",
+	}
+	for _, endpoint := range allowed {
+		if err := checkContainerEndpoint(endpoint); err != nil {
+			t.Errorf("Expected %s to be allowed, got %v", endpoint, err)
+		}
+	}
+	for _, endpoint := range []string{"http://creds.example.com/", "ftp://127.0.0.1/"} {
+		if err := checkContainerEndpoint(endpoint); err == nil {
+			t.Errorf("Expected %s to be rejected, got nil", endpoint)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// processOutput is the JSON a credential_process prints on stdout
+type processOutput struct {
+	Version         int
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string
+	SessionToken    string
+	Expiration      string
+}
+
+// processCredentials runs the credential_process of profile through the
+// shell and parses the credentials it prints
+func (c *Config) processCredentials(ctx context.Context, profile *Profile) (*Credentials, error) {
+	ctx, cancel := c.metadataContext(ctx)
+	defer cancel()
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd.exe", "/C", profile.CredentialProcess)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", profile.CredentialProcess)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, msg)
+		}
+		return nil, fmt.Errorf("credential_process of profile %s failed: %w", profile.Name, err)
+	}
+
+	var output processOutput
+	if err := json.Unmarshal(stdout.Bytes(), &output); err != nil {
+		return nil, fmt.Errorf("credential_process of profile %s printed invalid JSON: %w", profile.Name, err)
+	}
+	if output.Version != 1 {
+		return nil, fmt.Errorf("credential_process of profile %s printed unsupported Version %d, expected 1", profile.Name, output.Version)
+	}
+	if output.AccessKeyID == "" || output.SecretAccessKey == "" {
+		return nil, fmt.Errorf("credential_process of profile %s printed no AccessKeyId or SecretAccessKey", profile.Name)
+	}
+
+	creds := &Credentials{
+		AccessKeyID:     output.AccessKeyID,
+		SecretAccessKey: output.SecretAccessKey,
+		SessionToken:    output.SessionToken,
+	}
+	if output.Expiration != "" {
+		expiration, err := time.Parse(time.RFC3339, output.Expiration)
+		if err != nil {
+			return nil, fmt.Errorf("credential_process of profile %s printed invalid Expiration: %w", profile.Name, err)
+		}
+		creds.Expiration = expiration
+	}
+	return creds, nil
+}
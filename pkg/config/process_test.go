@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCredentialProcessHelper is not a real test. It is run as the
+// credential_process of the tests below and prints what
+// DYNAMIGHTEA_FAKE_PROCESS asks for.
+func TestCredentialProcessHelper(t *testing.T) {
+	switch os.Getenv("DYNAMIGHTEA_FAKE_PROCESS") {
+	case "":
+		return
+	case "ok":
+		fmt.Print(`{"Version": 1, "AccessKeyId": "AKIAPROCESS", "SecretAccessKey": "process-secret", "SessionToken": "process-token", "Expiration": "2030-01-02T03:04:05Z"}`)
+	case "fresh", "expiring":
+		// A new key on every run shows whether the process ran again
+		lifetime := time.Hour
+		if os.Getenv("DYNAMIGHTEA_FAKE_PROCESS") == "expiring" {
+			lifetime = 2 * time.Minute
+		}
+		fmt.Printf(`{"Version": 1, "AccessKeyId": "AKIA%d", "SecretAccessKey": "process-secret", "Expiration": %q}`,
+			time.Now().UnixNano(), time.Now().Add(lifetime).UTC().Format(time.RFC3339))
+	case "version":
+		fmt.Print(`{"Version": 2, "AccessKeyId": "AKIAPROCESS", "SecretAccessKey": "process-secret"}`)
+	case "fail":
+		fmt.Fprint(os.Stderr, "token expired, run vault login")
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// fakeProcessProfile writes a profile whose credential_process runs the
+// test binary in the given mode
+func fakeProcessProfile(t *testing.T, mode string) *Config {
+	t.Helper()
+	command := fmt.Sprintf("'%s' -test.run=TestCredentialProcessHelper", os.Args[0])
+	writeProfiles(t, "[profile tool]\ncredential_process = "+command+"\n", "")
+	t.Setenv("DYNAMIGHTEA_FAKE_PROCESS", mode)
+	t.Setenv("AWS_PROFILE", "tool")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	return cfg
+}
+
+func TestCredentialProcess(t *testing.T) {
+	cfg := fakeProcessProfile(t, "ok")
+	creds, err := cfg.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to run credential_process: %v", err)
+	}
+	if creds.AccessKeyID != "AKIAPROCESS" || creds.SessionToken != "process-token" || creds.Expiration.Year() != 2030 {
+		t.Errorf("Expected the credentials printed by the process, got %+v", creds)
+	}
+}
+
+func TestCredentialProcessErrors(t *testing.T) {
+	tests := []struct {
+		mode string
+		want string
+	}{
+		{mode: "version", want: "unsupported Version 2"},
+		{mode: "fail", want: "token expired, run vault login"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg := fakeProcessProfile(t, tt.mode)
+			_, err := cfg.GetCredentials(context.Background())
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected an error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
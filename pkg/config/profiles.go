@@ -0,0 +1,306 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Profile is a named profile from the shared AWS config and credentials
+// files. Settings from the credentials file take precedence.
+type Profile struct {
+	Name            string
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	SourceProfile   string
+	RoleARN         string
+	ExternalID      string
+	RoleSessionName string
+	DurationSeconds int32 // Zero uses the STS default of one hour
+	MFASerial       string
+	SSOSession      string // Name of an [sso-session] section
+	SSOStartURL     string
+	SSORegion       string
+	SSOAccountID    string
+	SSORoleName     string
+	// CredentialProcess is a command that prints credentials as JSON
+	CredentialProcess    string
+	WebIdentityTokenFile string
+	settings             map[string]string
+	ssoScopes            string
+}
+
+// Setting returns the raw value of a key in the profile, or ""
+func (p *Profile) Setting(key string) string {
+	return p.settings[key]
+}
+
+// HasStaticCredentials reports whether the profile holds access keys
+func (p *Profile) HasStaticCredentials() bool {
+	return p.AccessKeyID != "" && p.SecretAccessKey != ""
+}
+
+// UsesSSO reports whether the profile gets role credentials from AWS IAM
+// Identity Center
+func (p *Profile) UsesSSO() bool {
+	return p.SSOAccountID != "" && p.SSORoleName != ""
+}
+
+// LoadProfiles reads the profiles defined in the shared config and
+// credentials files. Files that do not exist are skipped.
+func LoadProfiles(configFile, credentialsFile string) (map[string]*Profile, error) {
+	configSections, err := parseINIFile(configFile)
+	if err != nil {
+		return nil, err
+	}
+	credentialSections, err := parseINIFile(credentialsFile)
+	if err != nil {
+		return nil, err
+	}
+
+	settings := map[string]map[string]string{}
+	merge := func(name string, values map[string]string) {
+		if settings[name] == nil {
+			settings[name] = map[string]string{}
+		}
+		for key, value := range values {
+			settings[name][key] = value
+		}
+	}
+
+	// In the config file every profile but the default is written as
+	// [profile name]. [sso-session name] sections hold the start URL and
+	// region shared by the profiles that name them in sso_session.
+	ssoSessions := map[string]map[string]string{}
+	for section, values := range configSections {
+		switch {
+		case section == "default":
+			merge(section, values)
+		case strings.HasPrefix(section, "profile "):
+			merge(strings.TrimSpace(strings.TrimPrefix(section, "profile ")), values)
+		case strings.HasPrefix(section, "sso-session "):
+			ssoSessions[strings.TrimSpace(strings.TrimPrefix(section, "sso-session "))] = values
+		}
+	}
+	for section, values := range credentialSections {
+		merge(section, values)
+	}
+
+	profiles := make(map[string]*Profile, len(settings))
+	for name, values := range settings {
+		var duration int64
+		if value := values["duration_seconds"]; value != "" {
+			if duration, err = strconv.ParseInt(value, 10, 32); err != nil || duration <= 0 {
+				return nil, fmt.Errorf("profile %s: invalid duration_seconds %q", name, value)
+			}
+		}
+		profiles[name] = &Profile{
+			Name:                 name,
+			Region:               values["region"],
+			AccessKeyID:          values["aws_access_key_id"],
+			SecretAccessKey:      values["aws_secret_access_key"],
+			SessionToken:         values["aws_session_token"],
+			SourceProfile:        values["source_profile"],
+			RoleARN:              values["role_arn"],
+			ExternalID:           values["external_id"],
+			RoleSessionName:      values["role_session_name"],
+			DurationSeconds:      int32(duration),
+			MFASerial:            values["mfa_serial"],
+			SSOSession:           values["sso_session"],
+			SSOStartURL:          values["sso_start_url"],
+			SSORegion:            values["sso_region"],
+			SSOAccountID:         values["sso_account_id"],
+			SSORoleName:          values["sso_role_name"],
+			CredentialProcess:    values["credential_process"],
+			WebIdentityTokenFile: values["web_identity_token_file"],
+			settings:             values,
+		}
+		if session := values["sso_session"]; session != "" {
+			sessionValues, ok := ssoSessions[session]
+			if !ok {
+				return nil, fmt.Errorf("profile %s: sso-session %s not found in %s", name, session, configFile)
+			}
+			profiles[name].SSOStartURL = sessionValues["sso_start_url"]
+			profiles[name].SSORegion = sessionValues["sso_region"]
+			profiles[name].ssoScopes = sessionValues["sso_registration_scopes"]
+		}
+	}
+	return profiles, nil
+}
+
+// parseINIFile parses path, returning no sections if it does not exist
+func parseINIFile(path string) (map[string]map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer f.Close()
+
+	sections, err := parseINI(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return sections, nil
+}
+
+// parseINI parses the INI dialect of the AWS shared files. Keys are
+// lowercased. Indented lines continue a nested setting such as
+// "s3 =" and are ignored.
+func parseINI(r io.Reader) (map[string]map[string]string, error) {
+	sections := map[string]map[string]string{}
+	var current map[string]string
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		raw := scanner.Text()
+		line := strings.TrimSpace(raw)
+		if line == "" || line[0] == '#' || line[0] == ';' {
+			continue
+		}
+		if raw[0] == ' ' || raw[0] == '\t' {
+			continue
+		}
+
+		if line[0] == '[' {
+			end := strings.IndexByte(line, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated section header", lineNo)
+			}
+			name := strings.Join(strings.Fields(line[1:end]), " ")
+			if sections[name] == nil {
+				sections[name] = map[string]string{}
+			}
+			current = sections[name]
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected key = value", lineNo)
+		}
+		if current == nil {
+			return nil, fmt.Errorf("line %d: setting outside of a section", lineNo)
+		}
+		current[strings.ToLower(strings.TrimSpace(key))] = stripComment(strings.TrimSpace(value))
+	}
+	return sections, scanner.Err()
+}
+
+// stripComment removes a trailing " #" or " ;" comment from a value
+func stripComment(value string) string {
+	for i := 1; i < len(value); i++ {
+		if (value[i] == '#' || value[i] == ';') && (value[i-1] == ' ' || value[i-1] == '\t') {
+			return strings.TrimSpace(value[:i])
+		}
+	}
+	return value
+}
+
+// profileCredentials resolves the credentials of a profile. A profile
+// with role_arn assumes that role with its web identity token or with the
+// credentials of its source_profile, which may itself assume a role. Other
+// profiles use their access keys, SSO, credential_process or the
+// credentials of their source_profile.
+func (c *Config) profileCredentials(ctx context.Context, name string) (*Credentials, error) {
+	creds, err := c.resolveProfile(ctx, name, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+	// Cached sessions are shared, so the source is set on a copy
+	labelled := *creds
+	labelled.Source = fmt.Sprintf("profile %s (%s)", name, c.profiles[name].credentialSource())
+	return &labelled, nil
+}
+
+func (c *Config) resolveProfile(ctx context.Context, name string, visited map[string]bool) (*Credentials, error) {
+	if visited[name] {
+		return nil, fmt.Errorf("source_profile chain loops back to profile %s", name)
+	}
+	visited[name] = true
+
+	profile, ok := c.profiles[name]
+	if !ok {
+		return nil, fmt.Errorf("profile %s not found in the shared config or credentials files", name)
+	}
+
+	if profile.RoleARN != "" && profile.WebIdentityTokenFile != "" {
+		return c.webIdentityCredentials(ctx, &webIdentity{
+			TokenFile:       profile.WebIdentityTokenFile,
+			RoleARN:         profile.RoleARN,
+			RoleSessionName: profile.RoleSessionName,
+			DurationSeconds: profile.DurationSeconds,
+			Region:          profile.Region,
+		})
+	}
+	if profile.RoleARN != "" {
+		var source *Credentials
+		switch profile.SourceProfile {
+		case "":
+			return nil, fmt.Errorf("profile %s sets role_arn without a source_profile", name)
+		case name:
+			// A profile may assume a role with its own access keys
+			if !profile.HasStaticCredentials() {
+				return nil, fmt.Errorf("profile %s has no credentials", name)
+			}
+			source = profile.staticCredentials()
+		default:
+			var err error
+			if source, err = c.resolveProfile(ctx, profile.SourceProfile, visited); err != nil {
+				return nil, err
+			}
+		}
+		return c.assumeRole(ctx, profile, source)
+	}
+
+	if profile.HasStaticCredentials() {
+		return profile.staticCredentials(), nil
+	}
+	if profile.UsesSSO() {
+		return c.ssoCredentials(ctx, profile)
+	}
+	if profile.CredentialProcess != "" {
+		return c.processCredentials(ctx, profile)
+	}
+	if profile.SourceProfile == "" {
+		return nil, fmt.Errorf("profile %s has no credentials", name)
+	}
+	return c.resolveProfile(ctx, profile.SourceProfile, visited)
+}
+
+// credentialSource describes how the credentials of p are obtained
+func (p *Profile) credentialSource() string {
+	switch {
+	case p.RoleARN != "" && p.WebIdentityTokenFile != "":
+		return "web identity for role " + p.RoleARN
+	case p.RoleARN != "":
+		return "assumed role " + p.RoleARN
+	case p.HasStaticCredentials():
+		return "access keys"
+	case p.UsesSSO():
+		return fmt.Sprintf("SSO role %s in account %s", p.SSORoleName, p.SSOAccountID)
+	case p.CredentialProcess != "":
+		return "credential_process"
+	default:
+		return "source_profile " + p.SourceProfile
+	}
+}
+
+func (p *Profile) staticCredentials() *Credentials {
+	return &Credentials{
+		AccessKeyID:     p.AccessKeyID,
+		SecretAccessKey: p.SecretAccessKey,
+		SessionToken:    p.SessionToken,
+	}
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const testConfigFile = `# shared config
+[default]
+region = eu-west-1
+
+[profile dev]
+region = us-west-2 ; the dev account
+source_profile = base
+s3 =
+  max_concurrent_requests = 10
+
+[profile chained]
+source_profile = dev
+
+[profile loop-a]
+source_profile = loop-b
+
+[profile loop-b]
+source_profile = loop-a
+
+[sso-session corp]
+sso_region = us-east-1
+`
+
+const testCredentialsFile = `[default]
+aws_access_key_id = AKIADEFAULT
+aws_secret_access_key = default-secret
+
+[base]
+aws_access_key_id = AKIABASE
+aws_secret_access_key = base-secret
+aws_session_token = base-token
+`
+
+// writeProfiles writes the shared config and credentials files and points
+// the environment at them
+func writeProfiles(t *testing.T, config, credentials string) {
+	t.Helper()
+	dir := t.TempDir()
+	configFile := filepath.Join(dir, "config")
+	credentialsFile := filepath.Join(dir, "credentials")
+	if err := os.WriteFile(configFile, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(credentialsFile, []byte(credentials), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_CONFIG_FILE", configFile)
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("AWS_DEFAULT_REGION", "")
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ENDPOINT_URL_STS", "")
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI", "")
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", "")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	t.Setenv("DYNAMIGHTEA_CACHE_DIR", filepath.Join(dir, "cache"))
+	t.Setenv("HOME", dir)
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	t.Setenv("DYNAMIGHTEA_CONFIG", "")
+	t.Setenv("DYNAMIGHTEA_CONNECTION", "")
+	t.Setenv("DYNAMIGHTEA_REGIONS", "")
+}
+
+func TestParseINI(t *testing.T) {
+	sections, err := parseINI(strings.NewReader(testConfigFile))
+	if err != nil {
+		t.Fatalf("Failed to parse: %v", err)
+	}
+
+	if got := sections["profile dev"]["region"]; got != "us-west-2" {
+		t.Errorf("Expected the inline comment to be stripped, got %q", got)
+	}
+	if _, ok := sections["profile dev"]["max_concurrent_requests"]; ok {
+		t.Error("Expected nested settings to be ignored")
+	}
+	if _, ok := sections["sso-session corp"]; !ok {
+		t.Error("Expected the sso-session section to be parsed")
+	}
+
+	if _, err := parseINI(strings.NewReader("region = us-east-1\n")); err == nil {
+		t.Error("Expected an error for a setting outside of a section, got nil")
+	}
+}
+
+func TestLoadProfiles(t *testing.T) {
+	writeProfiles(t, testConfigFile, testCredentialsFile)
+	profiles, err := LoadProfiles(os.Getenv("AWS_CONFIG_FILE"), os.Getenv("AWS_SHARED_CREDENTIALS_FILE"))
+	if err != nil {
+		t.Fatalf("Failed to load profiles: %v", err)
+	}
+
+	if _, ok := profiles["corp"]; ok {
+		t.Error("Expected sso-session sections not to become profiles")
+	}
+	if p := profiles["default"]; p == nil || p.Region != "eu-west-1" || p.AccessKeyID != "AKIADEFAULT" {
+		t.Errorf("Expected default to merge both files, got %+v", p)
+	}
+
+	cfg := &Config{profiles: profiles}
+	creds, err := cfg.profileCredentials(context.Background(), "chained")
+	if err != nil {
+		t.Fatalf("Failed to resolve chained profile: %v", err)
+	}
+	if creds.AccessKeyID != "AKIABASE" || creds.SessionToken != "base-token" {
+		t.Errorf("Expected credentials from base, got %+v", creds)
+	}
+
+	if _, err := cfg.profileCredentials(context.Background(), "loop-a"); err == nil || !strings.Contains(err.Error(), "loops") {
+		t.Errorf("Expected a loop error, got %v", err)
+	}
+	if _, err := cfg.profileCredentials(context.Background(), "missing"); err == nil {
+		t.Error("Expected an error for a missing profile, got nil")
+	}
+}
+
+func TestLoadConfigUsesProfile(t *testing.T) {
+	writeProfiles(t, testConfigFile, testCredentialsFile)
+	t.Setenv("AWS_PROFILE", "dev")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if cfg.Region != "us-west-2" {
+		t.Errorf("Expected the region of profile dev, got %q", cfg.Region)
+	}
+
+	creds, err := cfg.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get credentials: %v", err)
+	}
+	if creds.AccessKeyID != "AKIABASE" {
+		t.Errorf("Expected credentials from source profile base, got %s", creds.AccessKeyID)
+	}
+
+	cfg.UseProfile("chained")
+	if cfg.Region != "" {
+		t.Errorf("Expected no region for a profile without one, got %q", cfg.Region)
+	}
+
+	t.Setenv("AWS_REGION", "ap-southeast-2")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	cfg.UseProfile("default")
+	if cfg.Region != "ap-southeast-2" {
+		t.Errorf("Expected AWS_REGION to win over the profile, got %q", cfg.Region)
+	}
+}
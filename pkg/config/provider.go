@@ -0,0 +1,37 @@
+package config
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// CredentialsProvider returns a provider for the AWS SDK that resolves
+// credentials with GetCredentials. Credentials that expire, such as those
+// from IMDS, ECS or an assumed role, are renewed five minutes ahead of
+// their expiry. Every call returns the same provider, so the credentials
+// are shared by all clients created from c.
+func (c *Config) CredentialsProvider() aws.CredentialsProvider {
+	c.providerOnce.Do(func() {
+		c.provider = aws.NewCredentialsCache(aws.CredentialsProviderFunc(c.retrieveCredentials), func(o *aws.CredentialsCacheOptions) {
+			o.ExpiryWindow = expiryWindow
+		})
+	})
+	return c.provider
+}
+
+// retrieveCredentials adapts GetCredentials to aws.CredentialsProviderFunc
+func (c *Config) retrieveCredentials(ctx context.Context) (aws.Credentials, error) {
+	creds, err := c.GetCredentials(ctx)
+	if err != nil {
+		return aws.Credentials{}, err
+	}
+	return aws.Credentials{
+		AccessKeyID:     creds.AccessKeyID,
+		SecretAccessKey: creds.SecretAccessKey,
+		SessionToken:    creds.SessionToken,
+		Source:          creds.Source,
+		CanExpire:       !creds.Expiration.IsZero(),
+		Expires:         creds.Expiration,
+	}, nil
+}
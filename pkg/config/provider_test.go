@@ -0,0 +1,66 @@
+package config
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCredentialsProviderRenewsBeforeExpiry(t *testing.T) {
+	ctx := context.Background()
+
+	cfg := fakeProcessProfile(t, "fresh")
+	first, err := cfg.CredentialsProvider().Retrieve(ctx)
+	if err != nil {
+		t.Fatalf("Failed to retrieve credentials: %v", err)
+	}
+	if !first.CanExpire || first.Expires.IsZero() {
+		t.Errorf("Expected credentials with an expiry, got %+v", first)
+	}
+	second, err := cfg.CredentialsProvider().Retrieve(ctx)
+	if err != nil {
+		t.Fatalf("Failed to retrieve credentials: %v", err)
+	}
+	if second.AccessKeyID != first.AccessKeyID {
+		t.Errorf("Expected fresh credentials to be reused, got %s then %s", first.AccessKeyID, second.AccessKeyID)
+	}
+
+	// Credentials that expire within the renewal window are fetched again
+	cfg = fakeProcessProfile(t, "expiring")
+	first, err = cfg.CredentialsProvider().Retrieve(ctx)
+	if err != nil {
+		t.Fatalf("Failed to retrieve credentials: %v", err)
+	}
+	second, err = cfg.CredentialsProvider().Retrieve(ctx)
+	if err != nil {
+		t.Fatalf("Failed to retrieve credentials: %v", err)
+	}
+	if second.AccessKeyID == first.AccessKeyID {
+		t.Errorf("Expected credentials about to expire to be renewed, got %s twice", first.AccessKeyID)
+	}
+}
+
+func TestRetrieveCredentialsHonoursCancel(t *testing.T) {
+	writeProfiles(t, "", "")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	t.Setenv("AWS_CONTAINER_CREDENTIALS_FULL_URI", server.URL+"/v1/credentials")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := cfg.retrieveCredentials(ctx); err == nil {
+		t.Fatal("Expected the cancelled request to fail, got credentials")
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Expected retrieval to stop with the caller's context, took %s", elapsed)
+	}
+}
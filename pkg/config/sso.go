@@ -0,0 +1,269 @@
+package config
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sso"
+	ssotypes "github.com/aws/aws-sdk-go-v2/service/sso/types"
+	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
+	oidctypes "github.com/aws/aws-sdk-go-v2/service/ssooidc/types"
+)
+
+// ssoTokenWindow is how long before expiry a cached SSO token is no longer
+// used, so a new login is not needed in the middle of a request
+const ssoTokenWindow = time.Minute
+
+// deviceCodeGrant is the OAuth grant type of the device authorization flow
+const deviceCodeGrant = "urn:ietf:params:oauth:grant-type:device_code"
+
+// SSOLoginRequiredError is returned when a profile uses AWS IAM Identity
+// Center and there is no valid cached token. Call StartSSOLogin to sign in.
+type SSOLoginRequiredError struct {
+	Profile  string
+	StartURL string
+}
+
+func (e *SSOLoginRequiredError) Error() string {
+	return fmt.Sprintf("profile %s requires an AWS SSO login to %s", e.Profile, e.StartURL)
+}
+
+// ssoToken is an access token as cached under ~/.aws/sso/cache. The AWS CLI
+// and SDKs use the same format, so their logins are shared.
+type ssoToken struct {
+	StartURL              string `json:"startUrl"`
+	Region                string `json:"region"`
+	AccessToken           string `json:"accessToken"`
+	ExpiresAt             string `json:"expiresAt"`
+	ClientID              string `json:"clientId,omitempty"`
+	ClientSecret          string `json:"clientSecret,omitempty"`
+	RegistrationExpiresAt string `json:"registrationExpiresAt,omitempty"`
+	RefreshToken          string `json:"refreshToken,omitempty"`
+}
+
+// valid reports whether the token can still be used
+func (t *ssoToken) valid() bool {
+	if t.AccessToken == "" {
+		return false
+	}
+	// Older versions of the AWS CLI wrote "UTC" instead of "Z"
+	for _, layout := range []string{time.RFC3339, "2006-01-02T15:04:05UTC"} {
+		if expiresAt, err := time.Parse(layout, t.ExpiresAt); err == nil {
+			return time.Until(expiresAt) > ssoTokenWindow
+		}
+	}
+	return false
+}
+
+// ssoTokenPath returns the cache file of the token used by profile. It is
+// named after the sso-session, or the start URL for legacy profiles.
+func (c *Config) ssoTokenPath(profile *Profile) string {
+	key := profile.SSOSession
+	if key == "" {
+		key = profile.SSOStartURL
+	}
+	sum := sha1.Sum([]byte(key))
+	return filepath.Join(c.ssoCacheDir, hex.EncodeToString(sum[:])+".json")
+}
+
+// loadSSOToken returns the valid cached token of profile, or nil
+func (c *Config) loadSSOToken(profile *Profile) *ssoToken {
+	data, err := os.ReadFile(c.ssoTokenPath(profile))
+	if err != nil {
+		return nil
+	}
+	var token ssoToken
+	if json.Unmarshal(data, &token) != nil || !token.valid() {
+		return nil
+	}
+	return &token
+}
+
+// ssoCredentials exchanges the cached SSO token of profile for the
+// credentials of its account and role
+func (c *Config) ssoCredentials(ctx context.Context, profile *Profile) (*Credentials, error) {
+	if profile.SSOStartURL == "" || profile.SSORegion == "" {
+		return nil, fmt.Errorf("profile %s sets sso_account_id and sso_role_name but no sso_start_url and sso_region", profile.Name)
+	}
+
+	sum := sha1.Sum([]byte(fmt.Sprintf("sso\x00%s\x00%s\x00%s", profile.SSOStartURL, profile.SSOAccountID, profile.SSORoleName)))
+	key := hex.EncodeToString(sum[:])
+	if creds := c.sessions.get(key); creds != nil {
+		return creds, nil
+	}
+
+	token := c.loadSSOToken(profile)
+	if token == nil {
+		return nil, &SSOLoginRequiredError{Profile: profile.Name, StartURL: profile.SSOStartURL}
+	}
+
+	options := sso.Options{Region: profile.SSORegion}
+	if c.SSOEndpoint != "" {
+		options.BaseEndpoint = aws.String(c.SSOEndpoint)
+	}
+	client := sso.New(options)
+
+	ctx, cancel := c.metadataContext(ctx)
+	defer cancel()
+
+	resp, err := client.GetRoleCredentials(ctx, &sso.GetRoleCredentialsInput{
+		AccessToken: aws.String(token.AccessToken),
+		AccountId:   aws.String(profile.SSOAccountID),
+		RoleName:    aws.String(profile.SSORoleName),
+	})
+	var unauthorized *ssotypes.UnauthorizedException
+	if errors.As(err, &unauthorized) {
+		// The token was revoked or the user signed out elsewhere
+		return nil, &SSOLoginRequiredError{Profile: profile.Name, StartURL: profile.SSOStartURL}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get SSO role credentials for profile %s: %w", profile.Name, err)
+	}
+	if resp.RoleCredentials == nil {
+		return nil, fmt.Errorf("failed to get SSO role credentials for profile %s: no credentials returned", profile.Name)
+	}
+
+	creds := &Credentials{
+		AccessKeyID:     aws.ToString(resp.RoleCredentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(resp.RoleCredentials.SecretAccessKey),
+		SessionToken:    aws.ToString(resp.RoleCredentials.SessionToken),
+		Expiration:      time.UnixMilli(resp.RoleCredentials.Expiration),
+	}
+	c.sessions.put(key, creds)
+	return creds, nil
+}
+
+// SSOLogin is a device authorization in progress. The user confirms
+// UserCode at VerificationURI, then Wait receives the token.
+type SSOLogin struct {
+	VerificationURI         string
+	VerificationURIComplete string // VerificationURI with the code filled in
+	UserCode                string
+	ExpiresAt               time.Time
+
+	client     *ssooidc.Client
+	token      ssoToken
+	deviceCode string
+	interval   time.Duration
+	path       string
+}
+
+// StartSSOLogin registers a client with AWS IAM Identity Center and starts
+// the device authorization for the profile of required
+func (c *Config) StartSSOLogin(ctx context.Context, required *SSOLoginRequiredError) (*SSOLogin, error) {
+	profile, ok := c.profiles[required.Profile]
+	if !ok {
+		return nil, fmt.Errorf("profile %s not found in the shared config or credentials files", required.Profile)
+	}
+
+	options := ssooidc.Options{Region: profile.SSORegion}
+	if c.SSOOIDCEndpoint != "" {
+		options.BaseEndpoint = aws.String(c.SSOOIDCEndpoint)
+	}
+	client := ssooidc.New(options)
+
+	register := &ssooidc.RegisterClientInput{
+		ClientName: aws.String("dynamightea"),
+		ClientType: aws.String("public"),
+	}
+	if profile.SSOSession != "" {
+		scopes := profile.ssoScopes
+		if scopes == "" {
+			scopes = "sso:account:access"
+		}
+		for _, scope := range strings.Split(scopes, ",") {
+			register.Scopes = append(register.Scopes, strings.TrimSpace(scope))
+		}
+	}
+	registration, err := client.RegisterClient(ctx, register)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register SSO client: %w", err)
+	}
+
+	auth, err := client.StartDeviceAuthorization(ctx, &ssooidc.StartDeviceAuthorizationInput{
+		ClientId:     registration.ClientId,
+		ClientSecret: registration.ClientSecret,
+		StartUrl:     aws.String(profile.SSOStartURL),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to start SSO device authorization: %w", err)
+	}
+
+	interval := time.Duration(auth.Interval) * time.Second
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	return &SSOLogin{
+		VerificationURI:         aws.ToString(auth.VerificationUri),
+		VerificationURIComplete: aws.ToString(auth.VerificationUriComplete),
+		UserCode:                aws.ToString(auth.UserCode),
+		ExpiresAt:               time.Now().Add(time.Duration(auth.ExpiresIn) * time.Second),
+		client:                  client,
+		token: ssoToken{
+			StartURL:              profile.SSOStartURL,
+			Region:                profile.SSORegion,
+			ClientID:              aws.ToString(registration.ClientId),
+			ClientSecret:          aws.ToString(registration.ClientSecret),
+			RegistrationExpiresAt: time.Unix(registration.ClientSecretExpiresAt, 0).UTC().Format(time.RFC3339),
+		},
+		deviceCode: aws.ToString(auth.DeviceCode),
+		interval:   interval,
+		path:       c.ssoTokenPath(profile),
+	}, nil
+}
+
+// Wait polls until the user approves the login, then caches the token
+func (l *SSOLogin) Wait(ctx context.Context) error {
+	for {
+		resp, err := l.client.CreateToken(ctx, &ssooidc.CreateTokenInput{
+			ClientId:     aws.String(l.token.ClientID),
+			ClientSecret: aws.String(l.token.ClientSecret),
+			GrantType:    aws.String(deviceCodeGrant),
+			DeviceCode:   aws.String(l.deviceCode),
+		})
+
+		var pending *oidctypes.AuthorizationPendingException
+		var slowDown *oidctypes.SlowDownException
+		switch {
+		case err == nil:
+			l.token.AccessToken = aws.ToString(resp.AccessToken)
+			l.token.RefreshToken = aws.ToString(resp.RefreshToken)
+			l.token.ExpiresAt = time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second).UTC().Format(time.RFC3339)
+			return l.save()
+		case errors.As(err, &slowDown):
+			l.interval += 5 * time.Second
+		case !errors.As(err, &pending):
+			return fmt.Errorf("SSO login failed: %w", err)
+		}
+
+		select {
+		case <-time.After(l.interval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// save writes the token where the AWS CLI and SDKs look for it
+func (l *SSOLogin) save() error {
+	data, err := json.Marshal(l.token)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0o700); err != nil {
+		return fmt.Errorf("failed to create SSO cache: %w", err)
+	}
+	if err := os.WriteFile(l.path, data, 0o600); err != nil {
+		return fmt.Errorf("failed to save SSO token: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+package config
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+const ssoConfigFile = `[sso-session corp]
+sso_start_url = https://corp.awsapps.com/start
+sso_region = eu-west-1
+
+[profile dev]
+sso_session = corp
+sso_account_id = 123456789012
+sso_role_name = ReadOnly
+region = eu-west-1
+
+[profile legacy]
+sso_start_url = https://legacy.awsapps.com/start
+sso_region = us-east-1
+sso_account_id = 210987654321
+sso_role_name = Admin
+`
+
+// stubSSO serves the IAM Identity Center sign-in and portal APIs. The
+// first token poll is still pending.
+type stubSSO struct {
+	mu     sync.Mutex
+	polls  int
+	scopes []string
+}
+
+func (s *stubSSO) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var body map[string]any
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.URL.Path {
+	case "/client/register":
+		if scopes, ok := body["scopes"].([]any); ok {
+			for _, scope := range scopes {
+				s.scopes = append(s.scopes, fmt.Sprint(scope))
+			}
+		}
+		fmt.Fprintf(w, `{"clientId":"client-1","clientSecret":"secret-1","clientSecretExpiresAt":%d}`, time.Now().Add(24*time.Hour).Unix())
+	case "/device_authorization":
+		fmt.Fprint(w, `{"deviceCode":"device-1","userCode":"ABCD-EFGH","verificationUri":"https://device.sso.eu-west-1.amazonaws.com/","verificationUriComplete":"https://device.sso.eu-west-1.amazonaws.com/?user_code=ABCD-EFGH","expiresIn":600,"interval":1}`)
+	case "/token":
+		s.polls++
+		if s.polls == 1 {
+			w.Header().Set("X-Amzn-ErrorType", "AuthorizationPendingException")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"authorization_pending"}`)
+			return
+		}
+		fmt.Fprint(w, `{"accessToken":"token-1","expiresIn":28800,"refreshToken":"refresh-1","tokenType":"Bearer"}`)
+	case "/federation/credentials":
+		if r.Header.Get("x-amz-sso_bearer_token") != "token-1" {
+			w.Header().Set("X-Amzn-ErrorType", "UnauthorizedException")
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"Session token not found or invalid"}`)
+			return
+		}
+		query := r.URL.Query()
+		fmt.Fprintf(w, `{"roleCredentials":{"accessKeyId":"ASIA-%s-%s","secretAccessKey":"sso-secret","sessionToken":"sso-token","expiration":%d}}`,
+			query.Get("account_id"), query.Get("role_name"), time.Now().Add(time.Hour).UnixMilli())
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestSSOLogin(t *testing.T) {
+	writeProfiles(t, ssoConfigFile, "")
+	stub := &stubSSO{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_SSO", server.URL)
+	t.Setenv("AWS_ENDPOINT_URL_SSO_OIDC", server.URL)
+	t.Setenv("AWS_PROFILE", "dev")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	_, err = cfg.GetCredentials(context.Background())
+	var required *SSOLoginRequiredError
+	if !errors.As(err, &required) || required.Profile != "dev" || required.StartURL != "https://corp.awsapps.com/start" {
+		t.Fatalf("Expected an SSOLoginRequiredError for profile dev, got %v", err)
+	}
+
+	ctx := context.Background()
+	login, err := cfg.StartSSOLogin(ctx, required)
+	if err != nil {
+		t.Fatalf("Failed to start login: %v", err)
+	}
+	if login.UserCode != "ABCD-EFGH" || login.VerificationURIComplete == "" {
+		t.Errorf("Expected the user code and verification URL, got %+v", login)
+	}
+	if len(stub.scopes) != 1 || stub.scopes[0] != "sso:account:access" {
+		t.Errorf("Expected the default registration scope, got %v", stub.scopes)
+	}
+	if err := login.Wait(ctx); err != nil {
+		t.Fatalf("Failed to wait for login: %v", err)
+	}
+
+	// The token is cached where the AWS CLI looks for it, in a file named
+	// after the SHA-1 of the session name
+	sum := sha1.Sum([]byte("corp"))
+	data, err := os.ReadFile(filepath.Join(os.Getenv("HOME"), ".aws", "sso", "cache", hex.EncodeToString(sum[:])+".json"))
+	if err != nil {
+		t.Fatalf("Expected the token to be cached: %v", err)
+	}
+	var token ssoToken
+	if err := json.Unmarshal(data, &token); err != nil || token.AccessToken != "token-1" || token.RefreshToken != "refresh-1" || !token.valid() {
+		t.Errorf("Expected a valid cached token, got %s", data)
+	}
+
+	creds, err := cfg.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get SSO credentials: %v", err)
+	}
+	if creds.AccessKeyID != "ASIA-123456789012-ReadOnly" || creds.Expiration.IsZero() {
+		t.Errorf("Expected role credentials for the account and role, got %+v", creds)
+	}
+}
+
+func TestSSOCachedToken(t *testing.T) {
+	writeProfiles(t, ssoConfigFile, "")
+	server := httptest.NewServer(&stubSSO{})
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_SSO", server.URL)
+	t.Setenv("AWS_PROFILE", "legacy")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+
+	// A token written by the AWS CLI for a legacy profile, in its older
+	// expiry format
+	path := cfg.ssoTokenPath(cfg.profiles["legacy"])
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	token := fmt.Sprintf(`{"startUrl":"https://legacy.awsapps.com/start","region":"us-east-1","accessToken":"token-1","expiresAt":"%s"}`,
+		time.Now().Add(time.Hour).UTC().Format("2006-01-02T15:04:05UTC"))
+	if err := os.WriteFile(path, []byte(token), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	creds, err := cfg.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to get SSO credentials: %v", err)
+	}
+	if creds.AccessKeyID != "ASIA-210987654321-Admin" {
+		t.Errorf("Expected role credentials for the legacy profile, got %s", creds.AccessKeyID)
+	}
+
+	// An expired token needs a new login
+	expired := fmt.Sprintf(`{"startUrl":"https://legacy.awsapps.com/start","accessToken":"token-1","expiresAt":"%s"}`,
+		time.Now().Add(-time.Hour).UTC().Format(time.RFC3339))
+	if err := os.WriteFile(path, []byte(expired), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if token := cfg.loadSSOToken(cfg.profiles["legacy"]); token != nil {
+		t.Errorf("Expected an expired token to be ignored, got %+v", token)
+	}
+}
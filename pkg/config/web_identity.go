@@ -0,0 +1,95 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+// webIdentity is a role assumed with an OIDC token read from a file, as
+// set up by EKS for IAM roles for service accounts
+type webIdentity struct {
+	TokenFile       string
+	RoleARN         string
+	RoleSessionName string
+	DurationSeconds int32
+	Region          string
+}
+
+// webIdentityFromEnv returns the web identity configured by
+// AWS_WEB_IDENTITY_TOKEN_FILE and AWS_ROLE_ARN, or nil
+func webIdentityFromEnv() *webIdentity {
+	tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+	if tokenFile == "" {
+		return nil
+	}
+	return &webIdentity{
+		TokenFile:       tokenFile,
+		RoleARN:         os.Getenv("AWS_ROLE_ARN"),
+		RoleSessionName: os.Getenv("AWS_ROLE_SESSION_NAME"),
+	}
+}
+
+// webIdentityCredentials calls STS AssumeRoleWithWebIdentity with the
+// token in the file. The file is read on every call because it is rotated.
+func (c *Config) webIdentityCredentials(ctx context.Context, identity *webIdentity) (*Credentials, error) {
+	if identity.RoleARN == "" {
+		return nil, fmt.Errorf("web identity token file %s is set without a role ARN", identity.TokenFile)
+	}
+	token, err := os.ReadFile(identity.TokenFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read web identity token: %w", err)
+	}
+
+	region := identity.Region
+	if region == "" {
+		region = c.Region
+	}
+	if region == "" {
+		region = defaultSTSRegion
+	}
+
+	// AssumeRoleWithWebIdentity is not signed, so no credentials are needed
+	options := sts.Options{Region: region}
+	if c.STSEndpoint != "" {
+		options.BaseEndpoint = aws.String(c.STSEndpoint)
+	}
+	client := sts.New(options)
+
+	sessionName := identity.RoleSessionName
+	if sessionName == "" {
+		sessionName = fmt.Sprintf("dynamightea-%d", time.Now().Unix())
+	}
+	input := &sts.AssumeRoleWithWebIdentityInput{
+		RoleArn:          aws.String(identity.RoleARN),
+		RoleSessionName:  aws.String(sessionName),
+		WebIdentityToken: aws.String(strings.TrimSpace(string(token))),
+	}
+	if identity.DurationSeconds > 0 {
+		input.DurationSeconds = aws.Int32(identity.DurationSeconds)
+	}
+
+	ctx, cancel := c.metadataContext(ctx)
+	defer cancel()
+
+	resp, err := client.AssumeRoleWithWebIdentity(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to assume role %s with web identity: %w", identity.RoleARN, err)
+	}
+	if resp.Credentials == nil {
+		return nil, fmt.Errorf("failed to assume role %s with web identity: no credentials returned", identity.RoleARN)
+	}
+
+	return &Credentials{
+		AccessKeyID:     aws.ToString(resp.Credentials.AccessKeyId),
+		SecretAccessKey: aws.ToString(resp.Credentials.SecretAccessKey),
+		SessionToken:    aws.ToString(resp.Credentials.SessionToken),
+		Expiration:      aws.ToTime(resp.Credentials.Expiration),
+		Source:          "web identity token for role " + identity.RoleARN,
+	}, nil
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"context"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWebIdentityFromEnv(t *testing.T) {
+	writeProfiles(t, "", "")
+	stub := &stubSTS{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("eyJhbGciOi.pod-token\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::111111111111:role/Pod")
+	t.Setenv("AWS_ROLE_SESSION_NAME", "pod-1")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	creds, err := cfg.GetCredentials(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to assume role with web identity: %v", err)
+	}
+	if creds.AccessKeyID != "ASIA1" {
+		t.Errorf("Expected credentials from STS, got %s", creds.AccessKeyID)
+	}
+
+	calls := stub.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("Expected 1 STS call, got %d", len(calls))
+	}
+	want := assumeRoleCall{
+		RoleArn:         "arn:aws:iam::111111111111:role/Pod",
+		RoleSessionName: "pod-1",
+		WebIdentity:     "eyJhbGciOi.pod-token",
+	}
+	if calls[0] != want {
+		t.Errorf("Expected an unsigned call %+v, got %+v", want, calls[0])
+	}
+}
+
+func TestWebIdentityProfile(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("ci-token"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	writeProfiles(t, "[profile ci]\nrole_arn = arn:aws:iam::222222222222:role/CI\nweb_identity_token_file = "+tokenFile+"\nduration_seconds = 1800\n", "")
+	stub := &stubSTS{}
+	server := httptest.NewServer(stub)
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+	t.Setenv("AWS_PROFILE", "ci")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load config: %v", err)
+	}
+	if _, err := cfg.GetCredentials(context.Background()); err != nil {
+		t.Fatalf("Failed to assume role with web identity: %v", err)
+	}
+
+	calls := stub.Calls()
+	if len(calls) != 1 || calls[0].WebIdentity != "ci-token" || calls[0].DurationSeconds != "1800" {
+		t.Errorf("Expected one call with the token and duration of the profile, got %+v", calls)
+	}
+
+	// A missing token file is reported rather than skipped
+	if err := os.Remove(tokenFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cfg.GetCredentials(context.Background()); err == nil {
+		t.Error("Expected an error for a missing token file, got nil")
+	}
+}
@@ -3,11 +3,11 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/ec2/imds"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 
@@ -33,47 +33,155 @@ type TableInfo struct {
 	AttributeDefinitions map[string]string
 	GSIs                 []IndexInfo
 	LSIs                 []IndexInfo
+	Replicas             []string // Regions of the replicas of a global table
 }
 
 // DynamoClient provides methods for interacting with DynamoDB
 type DynamoClient struct {
-	client *dynamodb.Client
-	cfg    *appconfig.Config
+	client      Backend
+	cfg         *appconfig.Config
+	demo        bool
+	credentials aws.CredentialsProvider
+	awsConfig   aws.Config // For clients of other AWS services
 }
 
-// NewDynamoClient creates a new DynamoDB client
-func NewDynamoClient() *DynamoClient {
+// NewDynamoClient creates a new DynamoDB client from the environment
+func NewDynamoClient() (*DynamoClient, error) {
 	// Load configuration
 	cfg, err := appconfig.LoadConfig()
 	if err != nil {
-		log.Printf("Warning: Failed to load config: %v", err)
-		return &DynamoClient{client: nil}
+		return nil, fmt.Errorf("failed to load config: %w", err)
+	}
+
+	return NewDynamoClientWithConfig(cfg)
+}
+
+// NewDynamoClientWithConfig creates a new DynamoDB client from an already
+// loaded configuration. If the configuration enables demo mode the client
+// serves built-in sample tables and never contacts AWS.
+func NewDynamoClientWithConfig(cfg *appconfig.Config) (*DynamoClient, error) {
+	if cfg.Demo {
+		return NewDemoClient(cfg), nil
 	}
 
 	// Create AWS SDK config
-	client, err := createDynamoDBClient(cfg)
+	client, awsConfig, err := createDynamoDBClient(cfg)
 	if err != nil {
-		log.Printf("Warning: Failed to create DynamoDB client: %v", err)
-		return &DynamoClient{client: nil, cfg: cfg}
+		return nil, err
 	}
 
 	return &DynamoClient{
-		client: client,
-		cfg:    cfg,
+		client:      client,
+		cfg:         cfg,
+		credentials: awsConfig.Credentials,
+		awsConfig:   awsConfig,
+	}, nil
+}
+
+// NewDemoClient creates a client that serves built-in sample tables from
+// an in-memory backend
+func NewDemoClient(cfg *appconfig.Config) *DynamoClient {
+	return &DynamoClient{client: NewDemoBackend(), cfg: cfg, demo: true}
+}
+
+// NewDynamoClientWithBackend creates a client that sends its requests to
+// backend, such as a MemoryBackend in tests
+func NewDynamoClientWithBackend(cfg *appconfig.Config, backend Backend) *DynamoClient {
+	return &DynamoClient{client: backend, cfg: cfg}
+}
+
+// Config returns the configuration the client was created with
+func (d *DynamoClient) Config() *appconfig.Config {
+	return d.cfg
+}
+
+// Demo reports whether the client serves sample data instead of AWS
+func (d *DynamoClient) Demo() bool {
+	return d.demo
+}
+
+// CredentialsInfo describes the credentials a client signs requests with
+type CredentialsInfo struct {
+	Source    string    // Where they came from, such as "environment variables"
+	Expires   time.Time // Only set if CanExpire
+	CanExpire bool
+}
+
+// CredentialsInfo returns where the current credentials came from and when
+// they expire, renewing them first if they are about to. The zero value is
+// returned if the client does not use AWS credentials.
+func (d *DynamoClient) CredentialsInfo(ctx context.Context) (CredentialsInfo, error) {
+	if d.credentials == nil {
+		return CredentialsInfo{}, nil
 	}
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Metadata)
+	defer cancel()
+
+	creds, err := d.credentials.Retrieve(ctx)
+	if err != nil {
+		return CredentialsInfo{}, err
+	}
+	return CredentialsInfo{Source: creds.Source, Expires: creds.Expires, CanExpire: creds.CanExpire}, nil
+}
+
+// withTimeout bounds ctx by timeout. Zero means no limit.
+func (d *DynamoClient) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timeouts returns the configured per-operation timeouts
+func (d *DynamoClient) timeouts() appconfig.Timeouts {
+	if d.cfg == nil {
+		return appconfig.Timeouts{}
+	}
+	return d.cfg.Timeouts
+}
+
+// ReadOnly reports whether the client refuses to change data
+func (d *DynamoClient) ReadOnly() bool {
+	return d.cfg != nil && d.cfg.ReadOnly
 }
 
-// createDynamoDBClient creates a DynamoDB client with the provided configuration
-func createDynamoDBClient(cfg *appconfig.Config) (*dynamodb.Client, error) {
+// writable returns an error if the client is read-only. Every operation
+// that changes items or tables, including DDL and imports, checks it
+// before sending anything.
+func (d *DynamoClient) writable(op, tableName string) error {
+	if d.ReadOnly() {
+		return &Error{Op: op, Table: tableName, Kind: KindReadOnly, Err: ErrReadOnly}
+	}
+	return nil
+}
+
+// ready returns an error unless the client can send requests to DynamoDB
+func (d *DynamoClient) ready() error {
+	if d.client == nil {
+		return ErrNotConnected
+	}
+	return nil
+}
+
+// createDynamoDBClient creates a DynamoDB client with the provided
+// configuration and returns it with the AWS SDK config it was created from
+func createDynamoDBClient(cfg *appconfig.Config) (*dynamodb.Client, aws.Config, error) {
 	var awsConfig aws.Config
 	var err error
 
-	// If credentials provided via environment or config files
-	// Use the default AWS SDK credential chain
+	if cfg.Region == "" {
+		return nil, aws.Config{}, fmt.Errorf("no AWS region configured: set AWS_REGION, pass --region or add a region to profile %s", cfg.Profile)
+	}
+
 	optFns := []func(*config.LoadOptions) error{
 		config.WithRegion(cfg.Region),
 	}
 
+	// Use a named profile from the shared config files if one was requested
+	if cfg.Profile != "" && cfg.Profile != "default" {
+		optFns = append(optFns, config.WithSharedConfigProfile(cfg.Profile))
+	}
+
 	// If using a custom endpoint (like DynamoDB Local)
 	if cfg.Endpoint != "" {
 		optFns = append(optFns, config.WithEndpointResolverWithOptions(
@@ -88,19 +196,30 @@ func createDynamoDBClient(cfg *appconfig.Config) (*dynamodb.Client, error) {
 		))
 	}
 
-	// Try to get explicit credentials from metadata services if enabled
-	var creds *appconfig.Credentials
-	if cfg.UseIMDS || cfg.UseECSMetadata {
-		creds, err = cfg.GetCredentials()
-		if err == nil && creds != nil {
-			// Use explicit credentials provider
-			optFns = append(optFns, config.WithCredentialsProvider(
-				credentials.NewStaticCredentialsProvider(
-					creds.AccessKeyID,
-					creds.SecretAccessKey,
-					creds.SessionToken,
-				),
-			))
+	// Keep the SDK from probing an instance metadata service that is
+	// disabled or does not exist
+	if !cfg.UseIMDS || !cfg.IMDSReachable() {
+		optFns = append(optFns, config.WithEC2IMDSClientEnableState(imds.ClientDisabled))
+	}
+
+	// Sign requests with our own credential chain rather than the SDK's, so
+	// that assumed roles, MFA, SSO, credential_process and the session cache
+	// apply. The provider renews the credentials before they expire.
+	provider := cfg.CredentialsProvider()
+	optFns = append(optFns, config.WithCredentialsProvider(provider))
+
+	// A profile that fails to resolve, for example because STS refused to
+	// assume its role or an MFA code is needed, fails here rather than on
+	// the first request
+	if _, ok := cfg.LookupProfile(cfg.Profile); ok {
+		ctx := context.Background()
+		if cfg.Timeouts.Metadata > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, cfg.Timeouts.Metadata)
+			defer cancel()
+		}
+		if _, err := provider.Retrieve(ctx); err != nil {
+			return nil, aws.Config{}, err
 		}
 	}
 
@@ -110,69 +229,65 @@ func createDynamoDBClient(cfg *appconfig.Config) (*dynamodb.Client, error) {
 		optFns...,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to load AWS SDK config: %w", err)
+		return nil, aws.Config{}, fmt.Errorf("failed to load AWS SDK config: %w", err)
 	}
 
 	// Create and return the DynamoDB client
-	return dynamodb.NewFromConfig(awsConfig), nil
+	return dynamodb.NewFromConfig(awsConfig), awsConfig, nil
 }
 
 // ListTables lists all DynamoDB tables
-func (d *DynamoClient) ListTables() ([]string, error) {
-	// If in demo mode or client not initialized, return mock data
-	if d.client == nil {
-		// For demo purposes, returning mock data
-		return []string{"Users", "Products", "Orders"}, nil
+func (d *DynamoClient) ListTables(ctx context.Context) ([]string, error) {
+	if err := d.ready(); err != nil {
+		return nil, err
 	}
-	
-	// Use the real DynamoDB client
+
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Metadata)
+	defer cancel()
+
 	var tableNames []string
 	var nextToken *string
-	
+
 	for {
-		resp, err := d.client.ListTables(context.TODO(), &dynamodb.ListTablesInput{
+		resp, err := d.client.ListTables(ctx, &dynamodb.ListTablesInput{
 			ExclusiveStartTableName: nextToken,
 		})
 		if err != nil {
-			log.Printf("Error listing tables: %v", err)
-			// Fall back to mock data on error
-			return []string{"Users", "Products", "Orders"}, nil
+			return nil, newError("list tables", "", err)
 		}
-		
+
 		tableNames = append(tableNames, resp.TableNames...)
-		
+
 		nextToken = resp.LastEvaluatedTableName
 		if nextToken == nil {
 			break
 		}
 	}
-	
+
 	return tableNames, nil
 }
 
 // DescribeTable gets information about a specific table
-func (d *DynamoClient) DescribeTable(tableName string) (*TableInfo, error) {
-	// If in demo mode or client not initialized, return mock data
-	if d.client == nil {
-		// For demo purposes, returning mock data based on table name
-		return getMockTableInfo(tableName)
+func (d *DynamoClient) DescribeTable(ctx context.Context, tableName string) (*TableInfo, error) {
+	if err := d.ready(); err != nil {
+		return nil, err
 	}
-	
-	// Use the real DynamoDB client
-	resp, err := d.client.DescribeTable(context.TODO(), &dynamodb.DescribeTableInput{
+
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Metadata)
+	defer cancel()
+
+	resp, err := d.client.DescribeTable(ctx, &dynamodb.DescribeTableInput{
 		TableName: aws.String(tableName),
 	})
 	if err != nil {
-		log.Printf("Error describing table %s: %v", tableName, err)
-		// Fall back to mock data on error
-		return getMockTableInfo(tableName)
+		return nil, newError("describe table", tableName, err)
 	}
-	
+
 	table := resp.Table
 	if table == nil {
 		return nil, fmt.Errorf("table not found: %s", tableName)
 	}
-	
+
 	result := &TableInfo{
 		TableName:            *table.TableName,
 		KeySchema:            convertKeySchema(table.KeySchema),
@@ -180,7 +295,7 @@ func (d *DynamoClient) DescribeTable(tableName string) (*TableInfo, error) {
 		GSIs:                 []IndexInfo{},
 		LSIs:                 []IndexInfo{},
 	}
-	
+
 	// Add GSIs
 	for _, gsi := range table.GlobalSecondaryIndexes {
 		result.GSIs = append(result.GSIs, IndexInfo{
@@ -188,7 +303,7 @@ func (d *DynamoClient) DescribeTable(tableName string) (*TableInfo, error) {
 			KeySchema: convertKeySchema(gsi.KeySchema),
 		})
 	}
-	
+
 	// Add LSIs
 	for _, lsi := range table.LocalSecondaryIndexes {
 		result.LSIs = append(result.LSIs, IndexInfo{
@@ -196,11 +311,224 @@ func (d *DynamoClient) DescribeTable(tableName string) (*TableInfo, error) {
 			KeySchema: convertKeySchema(lsi.KeySchema),
 		})
 	}
-	
+
+	for _, replica := range table.Replicas {
+		result.Replicas = append(result.Replicas, aws.ToString(replica.RegionName))
+	}
+
 	return result, nil
 }
 
-// Helper functions 
+// QueryInput describes a key lookup against a table or one of its indexes.
+// Filter and Projection are optional.
+type QueryInput struct {
+	TableName         string
+	IndexName         string
+	PartitionKey      string
+	PartitionValue    types.AttributeValue
+	SortKey           string
+	SortCondition     *SortKeyCondition
+	Filter            []FilterCondition
+	Projection        []string // Top-level attributes to return; all when empty
+	Descending        bool
+	Limit             int32
+	ExclusiveStartKey Item
+}
+
+// ScanInput describes one page of a Scan. Filter and Projection are
+// optional.
+type ScanInput struct {
+	TableName         string
+	Filter            []FilterCondition
+	Projection        []string // Top-level attributes to return; all when empty
+	Limit             int32
+	ExclusiveStartKey Item
+}
+
+// Scan reads one page of items from a table
+func (d *DynamoClient) Scan(ctx context.Context, tableName string, limit int32, startKey Item) (*ItemPage, error) {
+	return d.ScanWith(ctx, ScanInput{TableName: tableName, Limit: limit, ExclusiveStartKey: startKey})
+}
+
+// ScanWith reads one page of items from a table, keeping those that match
+// the filter. Limit bounds the items read, so a page may hold fewer items
+// than Limit and still be followed by more.
+func (d *DynamoClient) ScanWith(ctx context.Context, s ScanInput) (*ItemPage, error) {
+	if err := d.ready(); err != nil {
+		return nil, err
+	}
+
+	expr := newExprBuilder()
+	input := &dynamodb.ScanInput{
+		TableName:         aws.String(s.TableName),
+		ExclusiveStartKey: s.ExclusiveStartKey,
+	}
+	filter, projection, err := expr.filterAndProjection(s.Filter, s.Projection)
+	if err != nil {
+		return nil, err
+	}
+	input.FilterExpression = filter
+	input.ProjectionExpression = projection
+	input.ExpressionAttributeNames = expr.attributeNames()
+	input.ExpressionAttributeValues = expr.attributeValues()
+	if s.Limit > 0 {
+		input.Limit = aws.Int32(s.Limit)
+	}
+
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Scan)
+	defer cancel()
+
+	resp, err := d.client.Scan(ctx, input)
+	if err != nil {
+		return nil, newError("scan", s.TableName, err)
+	}
+
+	return &ItemPage{
+		Items:            resp.Items,
+		LastEvaluatedKey: resp.LastEvaluatedKey,
+		ScannedCount:     resp.ScannedCount,
+	}, nil
+}
+
+// Query reads one page of items matching a partition key and an optional
+// sort key condition
+func (d *DynamoClient) Query(ctx context.Context, q QueryInput) (*ItemPage, error) {
+	if err := d.ready(); err != nil {
+		return nil, err
+	}
+
+	expr := newExprBuilder()
+	keyCondition, err := expr.keyCondition(q)
+	if err != nil {
+		return nil, err
+	}
+
+	input := &dynamodb.QueryInput{
+		TableName:              aws.String(q.TableName),
+		KeyConditionExpression: aws.String(keyCondition),
+		ScanIndexForward:       aws.Bool(!q.Descending),
+		ExclusiveStartKey:      q.ExclusiveStartKey,
+	}
+	filter, projection, err := expr.filterAndProjection(q.Filter, q.Projection)
+	if err != nil {
+		return nil, err
+	}
+	input.FilterExpression = filter
+	input.ProjectionExpression = projection
+	input.ExpressionAttributeNames = expr.attributeNames()
+	input.ExpressionAttributeValues = expr.attributeValues()
+	if q.IndexName != "" {
+		input.IndexName = aws.String(q.IndexName)
+	}
+	if q.Limit > 0 {
+		input.Limit = aws.Int32(q.Limit)
+	}
+
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Scan)
+	defer cancel()
+
+	resp, err := d.client.Query(ctx, input)
+	if err != nil {
+		return nil, newError("query", q.TableName, err)
+	}
+
+	return &ItemPage{
+		Items:            resp.Items,
+		LastEvaluatedKey: resp.LastEvaluatedKey,
+		ScannedCount:     resp.ScannedCount,
+	}, nil
+}
+
+// GetItem fetches a single item by its primary key. It returns nil if no
+// item exists with that key.
+func (d *DynamoClient) GetItem(ctx context.Context, tableName string, key Item) (Item, error) {
+	if err := d.ready(); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Read)
+	defer cancel()
+
+	resp, err := d.client.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName: aws.String(tableName),
+		Key:       key,
+	})
+	if err != nil {
+		return nil, newError("get item", tableName, err)
+	}
+
+	if len(resp.Item) == 0 {
+		return nil, nil
+	}
+	return resp.Item, nil
+}
+
+// KeySchemaFor returns the key schema of the table, or of the named index
+// when indexName is not empty
+func (t *TableInfo) KeySchemaFor(indexName string) ([]KeySchemaElement, error) {
+	if indexName == "" {
+		return t.KeySchema, nil
+	}
+	for _, idx := range t.GSIs {
+		if idx.IndexName == indexName {
+			return idx.KeySchema, nil
+		}
+	}
+	for _, idx := range t.LSIs {
+		if idx.IndexName == indexName {
+			return idx.KeySchema, nil
+		}
+	}
+	return nil, fmt.Errorf("index not found on %s: %s", t.TableName, indexName)
+}
+
+// PartitionKey returns the HASH attribute of a key schema
+func PartitionKey(schema []KeySchemaElement) string {
+	for _, key := range schema {
+		if key.KeyType == "HASH" {
+			return key.AttributeName
+		}
+	}
+	return ""
+}
+
+// SortKey returns the RANGE attribute of a key schema, or "" if it has none
+func SortKey(schema []KeySchemaElement) string {
+	for _, key := range schema {
+		if key.KeyType == "RANGE" {
+			return key.AttributeName
+		}
+	}
+	return ""
+}
+
+// BuildKey builds a primary key for the table from string values, using the
+// attribute definitions to pick each attribute's type
+func (t *TableInfo) BuildKey(partitionValue, sortValue string) (Item, error) {
+	key := Item{}
+
+	pk := PartitionKey(t.KeySchema)
+	pkValue, err := KeyValue(t.AttributeDefinitions[pk], partitionValue)
+	if err != nil {
+		return nil, fmt.Errorf("partition key %s: %w", pk, err)
+	}
+	key[pk] = pkValue
+
+	if sk := SortKey(t.KeySchema); sk != "" {
+		if sortValue == "" {
+			return nil, fmt.Errorf("table %s requires a value for sort key %s", t.TableName, sk)
+		}
+		skValue, err := KeyValue(t.AttributeDefinitions[sk], sortValue)
+		if err != nil {
+			return nil, fmt.Errorf("sort key %s: %w", sk, err)
+		}
+		key[sk] = skValue
+	}
+
+	return key, nil
+}
+
+// Helper functions
 func convertKeySchema(schema []types.KeySchemaElement) []KeySchemaElement {
 	result := make([]KeySchemaElement, len(schema))
 	for i, key := range schema {
@@ -219,97 +547,3 @@ func convertAttrDefinitions(attrs []types.AttributeDefinition) map[string]string
 	}
 	return result
 }
-
-// getMockTableInfo provides mock data for demo purposes
-func getMockTableInfo(tableName string) (*TableInfo, error) {
-	switch tableName {
-	case "Users":
-		return &TableInfo{
-			TableName: "Users",
-			KeySchema: []KeySchemaElement{
-				{AttributeName: "UserID", KeyType: "HASH"},
-				{AttributeName: "Email", KeyType: "RANGE"},
-			},
-			AttributeDefinitions: map[string]string{
-				"UserID":    "S",
-				"Email":     "S",
-				"Username":  "S",
-				"CreatedAt": "N",
-			},
-			GSIs: []IndexInfo{
-				{
-					IndexName: "UsernameIndex",
-					KeySchema: []KeySchemaElement{
-						{AttributeName: "Username", KeyType: "HASH"},
-					},
-				},
-			},
-			LSIs: []IndexInfo{
-				{
-					IndexName: "CreatedAtIndex",
-					KeySchema: []KeySchemaElement{
-						{AttributeName: "UserID", KeyType: "HASH"},
-						{AttributeName: "CreatedAt", KeyType: "RANGE"},
-					},
-				},
-			},
-		}, nil
-	case "Products":
-		return &TableInfo{
-			TableName: "Products",
-			KeySchema: []KeySchemaElement{
-				{AttributeName: "ProductID", KeyType: "HASH"},
-			},
-			AttributeDefinitions: map[string]string{
-				"ProductID":  "S",
-				"Category":   "S",
-				"Price":      "N",
-				"CreateDate": "S",
-			},
-			GSIs: []IndexInfo{
-				{
-					IndexName: "CategoryPriceIndex",
-					KeySchema: []KeySchemaElement{
-						{AttributeName: "Category", KeyType: "HASH"},
-						{AttributeName: "Price", KeyType: "RANGE"},
-					},
-				},
-			},
-			LSIs: []IndexInfo{},
-		}, nil
-	case "Orders":
-		return &TableInfo{
-			TableName: "Orders",
-			KeySchema: []KeySchemaElement{
-				{AttributeName: "CustomerID", KeyType: "HASH"},
-				{AttributeName: "OrderID", KeyType: "RANGE"},
-			},
-			AttributeDefinitions: map[string]string{
-				"CustomerID": "S",
-				"OrderID":    "S",
-				"OrderDate":  "S",
-				"Status":     "S",
-			},
-			GSIs: []IndexInfo{
-				{
-					IndexName: "StatusOrderDateIndex",
-					KeySchema: []KeySchemaElement{
-						{AttributeName: "Status", KeyType: "HASH"},
-						{AttributeName: "OrderDate", KeyType: "RANGE"},
-					},
-				},
-			},
-			LSIs: []IndexInfo{
-				{
-					IndexName: "OrderDateIndex",
-					KeySchema: []KeySchemaElement{
-						{AttributeName: "CustomerID", KeyType: "HASH"},
-						{AttributeName: "OrderDate", KeyType: "RANGE"},
-					},
-				},
-			},
-		}, nil
-	default:
-		return nil, fmt.Errorf("table not found: %s", tableName)
-	}
-}
\ No newline at end of file
@@ -1,28 +1,37 @@
 package db
 
 import (
+	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
 )
 
 func TestMockListTables(t *testing.T) {
-	client := NewDynamoClient()
-	tables, err := client.ListTables()
-	
+	client := NewDemoClient(nil)
+	tables, err := client.ListTables(context.Background())
+
 	if err != nil {
 		t.Fatalf("Error listing tables: %v", err)
 	}
-	
+
 	// Verify that we get the three mock tables
 	expectedTables := map[string]bool{
 		"Users":    true,
 		"Products": true,
 		"Orders":   true,
 	}
-	
+
 	if len(tables) != 3 {
 		t.Errorf("Expected 3 tables, got %d", len(tables))
 	}
-	
+
 	for _, table := range tables {
 		if !expectedTables[table] {
 			t.Errorf("Unexpected table: %s", table)
@@ -31,33 +40,248 @@ func TestMockListTables(t *testing.T) {
 }
 
 func TestMockDescribeTable(t *testing.T) {
-	client := NewDynamoClient()
-	
+	client := NewDemoClient(nil)
+
 	// Test Users table
-	userTable, err := client.DescribeTable("Users")
+	userTable, err := client.DescribeTable(context.Background(), "Users")
 	if err != nil {
 		t.Fatalf("Error describing Users table: %v", err)
 	}
-	
+
 	if userTable.TableName != "Users" {
 		t.Errorf("Expected table name to be Users, got %s", userTable.TableName)
 	}
-	
+
 	if len(userTable.KeySchema) != 2 {
 		t.Errorf("Expected 2 key schema elements for Users, got %d", len(userTable.KeySchema))
 	}
-	
+
 	if len(userTable.GSIs) != 1 {
 		t.Errorf("Expected 1 GSI for Users, got %d", len(userTable.GSIs))
 	}
-	
+
 	if len(userTable.LSIs) != 1 {
 		t.Errorf("Expected 1 LSI for Users, got %d", len(userTable.LSIs))
 	}
-	
+
 	// Test non-existent table
-	_, err = client.DescribeTable("NonExistentTable")
+	_, err = client.DescribeTable(context.Background(), "NonExistentTable")
 	if err == nil {
 		t.Error("Expected error for non-existent table, got nil")
 	}
-}
\ No newline at end of file
+}
+
+func TestDemoScanPages(t *testing.T) {
+	tests := []struct {
+		table string
+		limit int32
+		items int
+		pages int
+	}{
+		{"Orders", 7, 20, 3},
+		{"Orders", 0, 20, 1},
+		{"Orders", 20, 20, 1},
+		{"Products", 5, 12, 3},
+	}
+	for _, tt := range tests {
+		client := NewDemoClient(nil)
+		info, err := client.DescribeTable(context.Background(), tt.table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := []string{PartitionKey(info.KeySchema)}
+		if sk := SortKey(info.KeySchema); sk != "" {
+			keys = append(keys, sk)
+		}
+
+		seen := map[string]bool{}
+		var startKey Item
+		pages := 0
+		for {
+			page, err := client.Scan(context.Background(), tt.table, tt.limit, startKey)
+			if err != nil {
+				t.Fatalf("Error scanning %s: %v", tt.table, err)
+			}
+			pages++
+			for _, item := range page.Items {
+				key := FormatKey(item, keys)
+				if seen[key] {
+					t.Errorf("%s: item %s returned twice", tt.table, key)
+				}
+				seen[key] = true
+			}
+			if !page.HasMore() {
+				break
+			}
+			startKey = page.LastEvaluatedKey
+		}
+
+		if len(seen) != tt.items || pages != tt.pages {
+			t.Errorf("Scan(%s, %d): expected %d items in %d pages, got %d in %d", tt.table, tt.limit, tt.items, tt.pages, len(seen), pages)
+		}
+	}
+}
+
+func TestDemoQueryIndex(t *testing.T) {
+	client := NewDemoClient(nil)
+
+	page, err := client.Query(context.Background(), QueryInput{
+		TableName:      "Orders",
+		IndexName:      "StatusOrderDateIndex",
+		PartitionKey:   "Status",
+		PartitionValue: &types.AttributeValueMemberS{Value: "SHIPPED"},
+		SortKey:        "OrderDate",
+		SortCondition: &SortKeyCondition{
+			Operator: SortBetween,
+			Values: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "2024-01-01"},
+				&types.AttributeValueMemberS{Value: "2024-06-30"},
+			},
+		},
+		Descending: true,
+	})
+	if err != nil {
+		t.Fatalf("Error querying index: %v", err)
+	}
+
+	var dates []string
+	for _, item := range page.Items {
+		if status := item["Status"].(*types.AttributeValueMemberS).Value; status != "SHIPPED" {
+			t.Errorf("Expected only SHIPPED orders, got %s", status)
+		}
+		dates = append(dates, item["OrderDate"].(*types.AttributeValueMemberS).Value)
+	}
+	want := []string{"2024-06-18", "2024-06-06", "2024-02-14", "2024-02-02"}
+	if strings.Join(dates, ",") != strings.Join(want, ",") {
+		t.Errorf("Expected order dates %v, got %v", want, dates)
+	}
+}
+
+func TestDemoConditionalUpdate(t *testing.T) {
+	client := NewDemoClient(nil)
+	table, err := client.DescribeTable(context.Background(), "Users")
+	if err != nil {
+		t.Fatalf("Error describing Users: %v", err)
+	}
+	key, err := table.BuildKey("user-2", "bob@example.com")
+	if err != nil {
+		t.Fatalf("Error building key: %v", err)
+	}
+	original, err := client.GetItem(context.Background(), "Users", key)
+	if err != nil || original == nil {
+		t.Fatalf("Expected to find bob, got %v, %v", original, err)
+	}
+
+	edited := copyItem(original)
+	edited["Username"] = &types.AttributeValueMemberS{Value: "robert"}
+	u, err := DiffItems(original, edited, []string{"UserID", "Email"})
+	if err != nil {
+		t.Fatalf("Error diffing items: %v", err)
+	}
+	if err := client.UpdateItem(context.Background(), "Users", u); err != nil {
+		t.Fatalf("Error updating item: %v", err)
+	}
+
+	// The same change is now based on a stale copy of the item
+	if err := client.UpdateItem(context.Background(), "Users", u); !errors.Is(err, ErrConditionFailed) {
+		t.Errorf("Expected ErrConditionFailed for a stale update, got %v", err)
+	}
+	if err := client.PutItem(context.Background(), "Users", edited, []string{"UserID", "Email"}, false); !errors.Is(err, ErrItemExists) {
+		t.Errorf("Expected ErrItemExists when creating a duplicate, got %v", err)
+	}
+
+	page, err := client.Query(context.Background(), QueryInput{
+		TableName:      "Users",
+		IndexName:      "UsernameIndex",
+		PartitionKey:   "Username",
+		PartitionValue: &types.AttributeValueMemberS{Value: "robert"},
+	})
+	if err != nil {
+		t.Fatalf("Error querying UsernameIndex: %v", err)
+	}
+	if len(page.Items) != 1 {
+		t.Errorf("Expected the renamed user in UsernameIndex, got %d items", len(page.Items))
+	}
+}
+
+// slowBackend blocks scans until the request is cancelled or times out
+type slowBackend struct {
+	*MemoryBackend
+}
+
+func (b slowBackend) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestScanTimeoutAndCancel(t *testing.T) {
+	cfg := &appconfig.Config{Timeouts: appconfig.Timeouts{Scan: 20 * time.Millisecond}}
+	client := NewDynamoClientWithBackend(cfg, slowBackend{NewDemoBackend()})
+
+	if _, err := client.Scan(context.Background(), "Orders", 10, nil); !IsKind(err, KindTimeout) {
+		t.Errorf("Expected a timeout error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := client.Scan(ctx, "Orders", 10, nil); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+
+	// Other operations are not bound by the scan timeout
+	if _, err := client.ListTables(context.Background()); err != nil {
+		t.Errorf("Expected ListTables to succeed, got %v", err)
+	}
+}
+
+func TestClientUsesOwnCredentials(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+	}{
+		{"IMDS disabled", map[string]string{"AWS_EC2_METADATA_DISABLED": "true"}},
+		{"IMDS turned off", map[string]string{"AWS_USE_IMDS": "false"}},
+		{"IMDS enabled", map[string]string{"AWS_EC2_METADATA_SERVICE_ENDPOINT": "http://127.0.0.1:1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, value := range map[string]string{
+				"HOME":                                   dir,
+				"XDG_CONFIG_HOME":                        dir,
+				"DYNAMIGHTEA_CONFIG":                     "",
+				"DYNAMIGHTEA_CACHE_DIR":                  dir,
+				"AWS_CONFIG_FILE":                        dir + "/config",
+				"AWS_SHARED_CREDENTIALS_FILE":            dir + "/credentials",
+				"AWS_PROFILE":                            "",
+				"AWS_REGION":                             "us-east-1",
+				"AWS_ACCESS_KEY_ID":                      "AKIDENV",
+				"AWS_SECRET_ACCESS_KEY":                  "secret",
+				"AWS_SESSION_TOKEN":                      "",
+				"AWS_WEB_IDENTITY_TOKEN_FILE":            "",
+				"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "",
+				"AWS_CONTAINER_CREDENTIALS_FULL_URI":     "",
+				"AWS_EC2_METADATA_DISABLED":              "",
+				"AWS_USE_IMDS":                           "",
+			} {
+				t.Setenv(name, value)
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			cfg, err := appconfig.LoadConfig()
+			if err != nil {
+				t.Fatalf("Failed to load config: %v", err)
+			}
+			client, err := NewDynamoClientWithConfig(cfg)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			info, err := client.CredentialsInfo(context.Background())
+			if err != nil || info.Source != "environment variables" {
+				t.Errorf("Expected the credentials of our own chain, got %+v, %v", info, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const (
+	// maxBatchWriteItems is the most requests BatchWriteItem accepts at once
+	maxBatchWriteItems = 25
+	// maxBatchRetries bounds how often unprocessed items are retried
+	maxBatchRetries = 8
+	baseBackoff     = 100 * time.Millisecond
+	maxBackoff      = 5 * time.Second
+)
+
+// DeleteProgress reports how far a bulk delete has got
+type DeleteProgress struct {
+	Deleted int
+	Total   int
+	Retries int
+}
+
+// BatchDeleteError is returned when a bulk delete stops before every key was
+// deleted. Remaining lists the keys that may still exist.
+type BatchDeleteError struct {
+	Remaining []Item
+	Err       error
+}
+
+func (e *BatchDeleteError) Error() string {
+	return fmt.Sprintf("%d item(s) were not deleted: %v", len(e.Remaining), e.Err)
+}
+
+func (e *BatchDeleteError) Unwrap() error {
+	return e.Err
+}
+
+// KeyOf extracts the primary key attributes from an item
+func KeyOf(item Item, keyAttrs []string) Item {
+	key := make(Item, len(keyAttrs))
+	for _, name := range keyAttrs {
+		if av, ok := item[name]; ok {
+			key[name] = av
+		}
+	}
+	return key
+}
+
+// FormatKey renders a primary key as "name=value" pairs in keyAttrs order
+func FormatKey(key Item, keyAttrs []string) string {
+	parts := make([]string, 0, len(keyAttrs))
+	for _, name := range keyAttrs {
+		parts = append(parts, fmt.Sprintf("%s=%v", name, ToPlain(key[name])))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// DeleteItem deletes a single item by primary key
+func (d *DynamoClient) DeleteItem(ctx context.Context, tableName string, key Item) error {
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if err := d.writable("delete item", tableName); err != nil {
+		return err
+	}
+
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Write)
+	defer cancel()
+
+	_, err := d.client.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName: aws.String(tableName),
+		Key:       key,
+	})
+	if err != nil {
+		return newError("delete item", tableName, err)
+	}
+	return nil
+}
+
+// DeleteItems deletes many items with BatchWriteItem, 25 keys at a time.
+// Unprocessed items are retried with exponential backoff. progress, if not
+// nil, is called after every batch request. The write timeout applies to
+// each batch request; cancelling ctx stops the delete between batches.
+func (d *DynamoClient) DeleteItems(ctx context.Context, tableName string, keys []Item, progress func(DeleteProgress)) error {
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if err := d.writable("batch delete", tableName); err != nil {
+		return err
+	}
+
+	write := func(requests []types.WriteRequest) ([]types.WriteRequest, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		ctx, cancel := d.withTimeout(ctx, d.timeouts().Write)
+		defer cancel()
+
+		resp, err := d.client.BatchWriteItem(ctx, &dynamodb.BatchWriteItemInput{
+			RequestItems: map[string][]types.WriteRequest{tableName: requests},
+		})
+		if err != nil {
+			return nil, newError("batch delete", tableName, err)
+		}
+		return resp.UnprocessedItems[tableName], nil
+	}
+
+	sleep := func(delay time.Duration) {
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+		}
+	}
+
+	return deleteBatches(keys, write, sleep, progress)
+}
+
+// deleteBatches drives a bulk delete through write, which sends one batch
+// and returns the requests DynamoDB did not process
+func deleteBatches(keys []Item, write func([]types.WriteRequest) ([]types.WriteRequest, error), sleep func(time.Duration), progress func(DeleteProgress)) error {
+	state := DeleteProgress{Total: len(keys)}
+	report := func() {
+		if progress != nil {
+			progress(state)
+		}
+	}
+
+	for start := 0; start < len(keys); start += maxBatchWriteItems {
+		end := start + maxBatchWriteItems
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		pending := make([]types.WriteRequest, 0, end-start)
+		for _, key := range keys[start:end] {
+			pending = append(pending, types.WriteRequest{
+				DeleteRequest: &types.DeleteRequest{Key: key},
+			})
+		}
+
+		for attempt := 0; len(pending) > 0; attempt++ {
+			if attempt > maxBatchRetries {
+				return &BatchDeleteError{
+					Remaining: remainingKeys(pending, keys[end:]),
+					Err:       fmt.Errorf("gave up after %d retries", maxBatchRetries),
+				}
+			}
+			if attempt > 0 {
+				state.Retries++
+				sleep(backoff(attempt))
+			}
+
+			unprocessed, err := write(pending)
+			if err != nil {
+				return &BatchDeleteError{Remaining: remainingKeys(pending, keys[end:]), Err: err}
+			}
+
+			state.Deleted += len(pending) - len(unprocessed)
+			pending = unprocessed
+			report()
+		}
+	}
+
+	return nil
+}
+
+// backoff returns the delay before retry attempt n, doubling each time with
+// jitter and capped at maxBackoff
+func backoff(attempt int) time.Duration {
+	delay := baseBackoff << (attempt - 1)
+	if delay > maxBackoff || delay <= 0 {
+		delay = maxBackoff
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+}
+
+func remainingKeys(pending []types.WriteRequest, rest []Item) []Item {
+	remaining := make([]Item, 0, len(pending)+len(rest))
+	for _, req := range pending {
+		remaining = append(remaining, req.DeleteRequest.Key)
+	}
+	return append(remaining, rest...)
+}
@@ -0,0 +1,74 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func testKeys(n int) []Item {
+	keys := make([]Item, n)
+	for i := range keys {
+		keys[i] = Item{"UserID": &types.AttributeValueMemberS{Value: fmt.Sprintf("u-%d", i)}}
+	}
+	return keys
+}
+
+func TestDeleteBatchesRetriesUnprocessed(t *testing.T) {
+	var batches []int
+	var sleeps int
+	write := func(requests []types.WriteRequest) ([]types.WriteRequest, error) {
+		batches = append(batches, len(requests))
+		// Leave the last two requests of every first attempt unprocessed
+		if len(requests) > 2 {
+			return requests[len(requests)-2:], nil
+		}
+		return nil, nil
+	}
+
+	var last DeleteProgress
+	err := deleteBatches(testKeys(30), write, func(time.Duration) { sleeps++ }, func(p DeleteProgress) {
+		last = p
+	})
+	if err != nil {
+		t.Fatalf("Error deleting batches: %v", err)
+	}
+
+	expected := []int{25, 2, 5, 2}
+	if fmt.Sprint(batches) != fmt.Sprint(expected) {
+		t.Errorf("Expected batches %v, got %v", expected, batches)
+	}
+	if sleeps != 2 {
+		t.Errorf("Expected 2 backoff sleeps, got %d", sleeps)
+	}
+	if last.Deleted != 30 || last.Total != 30 || last.Retries != 2 {
+		t.Errorf("Unexpected final progress: %+v", last)
+	}
+}
+
+func TestDeleteBatchesGivesUp(t *testing.T) {
+	write := func(requests []types.WriteRequest) ([]types.WriteRequest, error) {
+		return requests, nil
+	}
+
+	err := deleteBatches(testKeys(30), write, func(time.Duration) {}, nil)
+
+	var batchErr *BatchDeleteError
+	if !errors.As(err, &batchErr) {
+		t.Fatalf("Expected BatchDeleteError, got %v", err)
+	}
+	if len(batchErr.Remaining) != 30 {
+		t.Errorf("Expected 30 remaining keys, got %d", len(batchErr.Remaining))
+	}
+}
+
+func TestBackoffIsCapped(t *testing.T) {
+	for attempt := 1; attempt <= 20; attempt++ {
+		if d := backoff(attempt); d > maxBackoff || d <= 0 {
+			t.Errorf("Backoff for attempt %d out of range: %v", attempt, d)
+		}
+	}
+}
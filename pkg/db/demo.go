@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// demoTables are the schemas of the sample tables served in demo mode
+var demoTables = []*dynamodb.CreateTableInput{
+	{
+		TableName: aws.String("Users"),
+		KeySchema: demoKeySchema("UserID", "Email"),
+		AttributeDefinitions: demoAttributes(map[string]types.ScalarAttributeType{
+			"UserID":    types.ScalarAttributeTypeS,
+			"Email":     types.ScalarAttributeTypeS,
+			"Username":  types.ScalarAttributeTypeS,
+			"CreatedAt": types.ScalarAttributeTypeN,
+		}),
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{IndexName: aws.String("UsernameIndex"), KeySchema: demoKeySchema("Username", "")},
+		},
+		LocalSecondaryIndexes: []types.LocalSecondaryIndex{
+			{IndexName: aws.String("CreatedAtIndex"), KeySchema: demoKeySchema("UserID", "CreatedAt")},
+		},
+	},
+	{
+		TableName: aws.String("Products"),
+		KeySchema: demoKeySchema("ProductID", ""),
+		AttributeDefinitions: demoAttributes(map[string]types.ScalarAttributeType{
+			"ProductID": types.ScalarAttributeTypeS,
+			"Category":  types.ScalarAttributeTypeS,
+			"Price":     types.ScalarAttributeTypeN,
+		}),
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{IndexName: aws.String("CategoryPriceIndex"), KeySchema: demoKeySchema("Category", "Price")},
+		},
+	},
+	{
+		TableName: aws.String("Orders"),
+		KeySchema: demoKeySchema("CustomerID", "OrderID"),
+		AttributeDefinitions: demoAttributes(map[string]types.ScalarAttributeType{
+			"CustomerID": types.ScalarAttributeTypeS,
+			"OrderID":    types.ScalarAttributeTypeS,
+			"OrderDate":  types.ScalarAttributeTypeS,
+			"Status":     types.ScalarAttributeTypeS,
+		}),
+		GlobalSecondaryIndexes: []types.GlobalSecondaryIndex{
+			{IndexName: aws.String("StatusOrderDateIndex"), KeySchema: demoKeySchema("Status", "OrderDate")},
+		},
+		LocalSecondaryIndexes: []types.LocalSecondaryIndex{
+			{IndexName: aws.String("OrderDateIndex"), KeySchema: demoKeySchema("CustomerID", "OrderDate")},
+		},
+	},
+}
+
+// NewDemoBackend creates an in-memory backend holding the sample Users,
+// Products and Orders tables
+func NewDemoBackend() *MemoryBackend {
+	backend := NewMemoryBackend()
+	ctx := context.Background()
+	for _, table := range demoTables {
+		if _, err := backend.CreateTable(ctx, table); err != nil {
+			panic(fmt.Sprintf("demo table %s: %v", aws.ToString(table.TableName), err))
+		}
+	}
+	for table, items := range demoItems() {
+		for _, item := range items {
+			if _, err := backend.PutItem(ctx, &dynamodb.PutItemInput{TableName: aws.String(table), Item: item}); err != nil {
+				panic(fmt.Sprintf("demo item in %s: %v", table, err))
+			}
+		}
+	}
+	return backend
+}
+
+// demoItems generates the sample items for each demo table
+func demoItems() map[string][]Item {
+	users := []string{"alice", "bob", "carol", "dave", "erin", "frank", "grace", "heidi"}
+	categories := []string{"Books", "Electronics", "Garden", "Kitchen"}
+	statuses := []string{"PENDING", "SHIPPED", "DELIVERED", "CANCELLED"}
+
+	items := map[string][]Item{}
+
+	for i, name := range users {
+		user := Item{
+			"UserID":    str(fmt.Sprintf("user-%d", i+1)),
+			"Email":     str(name + "@example.com"),
+			"Username":  str(name),
+			"CreatedAt": num(fmt.Sprintf("%d", 1700000000+i*86400)),
+			"Active":    &types.AttributeValueMemberBOOL{Value: i%3 != 0},
+			"Address": &types.AttributeValueMemberM{Value: Item{
+				"City":    str([]string{"Berlin", "Lisbon", "Oslo", "Seattle"}[i%4]),
+				"Country": str([]string{"DE", "PT", "NO", "US"}[i%4]),
+			}},
+		}
+		if i%2 == 0 {
+			user["Roles"] = &types.AttributeValueMemberSS{Value: []string{"admin", "editor"}[:1+i%4/2]}
+		}
+		items["Users"] = append(items["Users"], user)
+	}
+
+	for i := 0; i < 12; i++ {
+		items["Products"] = append(items["Products"], Item{
+			"ProductID": str(fmt.Sprintf("prod-%03d", i+1)),
+			"Name":      str(fmt.Sprintf("%s item %d", categories[i%len(categories)], i/len(categories)+1)),
+			"Category":  str(categories[i%len(categories)]),
+			"Price":     num(fmt.Sprintf("%d.99", 5+i*7)),
+			"Stock":     num(fmt.Sprintf("%d", (i*13)%40)),
+			"Tags": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				str(categories[i%len(categories)]), str([]string{"new", "sale", "popular"}[i%3]),
+			}},
+		})
+	}
+
+	for i := 0; i < 20; i++ {
+		items["Orders"] = append(items["Orders"], Item{
+			"CustomerID": str(fmt.Sprintf("user-%d", i%len(users)+1)),
+			"OrderID":    str(fmt.Sprintf("order-%04d", 1000+i)),
+			"OrderDate":  str(fmt.Sprintf("2024-%02d-%02d", i%12+1, i%28+1)),
+			"Status":     str(statuses[i%len(statuses)]),
+			"Total":      num(fmt.Sprintf("%d.50", 10+i*3)),
+			"Lines": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+				&types.AttributeValueMemberM{Value: Item{
+					"ProductID": str(fmt.Sprintf("prod-%03d", i%12+1)),
+					"Quantity":  num(fmt.Sprintf("%d", i%3+1)),
+				}},
+			}},
+		})
+	}
+
+	return items
+}
+
+func demoKeySchema(partitionKey, sortKey string) []types.KeySchemaElement {
+	schema := []types.KeySchemaElement{{AttributeName: aws.String(partitionKey), KeyType: types.KeyTypeHash}}
+	if sortKey != "" {
+		schema = append(schema, types.KeySchemaElement{AttributeName: aws.String(sortKey), KeyType: types.KeyTypeRange})
+	}
+	return schema
+}
+
+func demoAttributes(attrs map[string]types.ScalarAttributeType) []types.AttributeDefinition {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	defs := make([]types.AttributeDefinition, 0, len(attrs))
+	for _, name := range names {
+		defs = append(defs, types.AttributeDefinition{AttributeName: aws.String(name), AttributeType: attrs[name]})
+	}
+	return defs
+}
+
+func str(s string) types.AttributeValue {
+	return &types.AttributeValueMemberS{Value: s}
+}
+
+func num(n string) types.AttributeValue {
+	return &types.AttributeValueMemberN{Value: n}
+}
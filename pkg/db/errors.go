@@ -0,0 +1,150 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+)
+
+// ErrNotConnected is returned when the client has no DynamoDB connection
+var ErrNotConnected = errors.New("DynamoDB client not initialized")
+
+// ErrReadOnly is returned for a write through a read-only client
+var ErrReadOnly = errors.New("writes are disabled")
+
+// ErrorKind classifies a failed DynamoDB call so callers can explain it
+type ErrorKind int
+
+// Kinds of DynamoDB errors
+const (
+	KindUnknown ErrorKind = iota
+	KindCredentials
+	KindAccessDenied
+	KindNotFound
+	KindThrottled
+	KindValidation
+	KindNetwork
+	KindTimeout
+	KindReadOnly
+)
+
+func (k ErrorKind) String() string {
+	switch k {
+	case KindCredentials:
+		return "invalid or expired credentials"
+	case KindAccessDenied:
+		return "access denied"
+	case KindNotFound:
+		return "not found"
+	case KindThrottled:
+		return "throttled"
+	case KindValidation:
+		return "invalid request"
+	case KindNetwork:
+		return "network error"
+	case KindTimeout:
+		return "timed out"
+	case KindReadOnly:
+		return "read-only connection"
+	default:
+		return "error"
+	}
+}
+
+// Error is returned by DynamoClient when a call to DynamoDB fails
+type Error struct {
+	Op    string
+	Table string
+	Kind  ErrorKind
+	Err   error
+}
+
+func (e *Error) Error() string {
+	target := ""
+	if e.Table != "" {
+		target = " " + e.Table
+	}
+	return fmt.Sprintf("%s%s: %s: %v", e.Op, target, e.Kind, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Hint suggests what the user can do about the error
+func (e *Error) Hint() string {
+	switch e.Kind {
+	case KindCredentials:
+		return "Check AWS_PROFILE, your SSO login or the AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY environment variables"
+	case KindAccessDenied:
+		return "The credentials are valid but lack permission for this operation"
+	case KindThrottled:
+		return "DynamoDB is throttling requests; wait a moment and retry"
+	case KindNetwork:
+		return "Check the region, the endpoint and your network connection"
+	case KindTimeout:
+		return "The request took longer than the configured timeout; raise it with --timeout or --scan-timeout"
+	case KindReadOnly:
+		return "Writes are disabled by --read-only, DYNAMIGHTEA_READ_ONLY or read_only in the config file or connection"
+	default:
+		return ""
+	}
+}
+
+// IsKind reports whether err is a DynamoDB error of the given kind
+func IsKind(err error, kind ErrorKind) bool {
+	var dbErr *Error
+	return errors.As(err, &dbErr) && dbErr.Kind == kind
+}
+
+// newError wraps err from operation op as an *Error
+func newError(op, table string, err error) error {
+	return &Error{
+		Op:    op,
+		Table: table,
+		Kind:  classify(err),
+		Err:   err,
+	}
+}
+
+// classify works out the kind of a failure returned by the SDK
+func classify(err error) ErrorKind {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return KindTimeout
+	}
+
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		switch apiErr.ErrorCode() {
+		case "UnrecognizedClientException", "InvalidSignatureException", "MissingAuthenticationToken",
+			"MissingAuthenticationTokenException", "ExpiredToken", "ExpiredTokenException",
+			"InvalidClientTokenId", "IncompleteSignature", "AuthFailure":
+			return KindCredentials
+		case "AccessDeniedException", "UnauthorizedOperation":
+			return KindAccessDenied
+		case "ResourceNotFoundException":
+			return KindNotFound
+		case "ProvisionedThroughputExceededException", "ThrottlingException", "RequestLimitExceeded":
+			return KindThrottled
+		case "ValidationException", "SerializationException":
+			return KindValidation
+		}
+	}
+
+	// The SDK reports credential provider failures as plain wrapped errors
+	// from the signing step
+	if strings.Contains(err.Error(), "get identity") {
+		return KindCredentials
+	}
+
+	var sendErr *smithyhttp.RequestSendError
+	if errors.As(err, &sendErr) {
+		return KindNetwork
+	}
+
+	return KindUnknown
+}
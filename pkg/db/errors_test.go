@@ -0,0 +1,107 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		err  error
+		kind ErrorKind
+	}{
+		{&smithy.GenericAPIError{Code: "ExpiredTokenException"}, KindCredentials},
+		{&smithy.GenericAPIError{Code: "UnrecognizedClientException"}, KindCredentials},
+		{&smithy.GenericAPIError{Code: "AccessDeniedException"}, KindAccessDenied},
+		{&smithy.GenericAPIError{Code: "ResourceNotFoundException"}, KindNotFound},
+		{&smithy.GenericAPIError{Code: "ThrottlingException"}, KindThrottled},
+		{fmt.Errorf("get identity: %w", errors.New("no EC2 IMDS role found")), KindCredentials},
+		{&smithyhttp.RequestSendError{Err: errors.New("connection refused")}, KindNetwork},
+		{errors.New("something else"), KindUnknown},
+	}
+
+	for _, tt := range tests {
+		if kind := classify(tt.err); kind != tt.kind {
+			t.Errorf("classify(%v) = %v, expected %v", tt.err, kind, tt.kind)
+		}
+	}
+}
+
+func TestErrorsAreTyped(t *testing.T) {
+	err := newError("list tables", "", &smithy.GenericAPIError{Code: "ExpiredTokenException"})
+
+	if !IsKind(err, KindCredentials) {
+		t.Errorf("Expected a credentials error, got %v", err)
+	}
+
+	var dbErr *Error
+	if !errors.As(fmt.Errorf("wrapped: %w", err), &dbErr) {
+		t.Fatal("Expected errors.As to find *Error")
+	}
+	if dbErr.Hint() == "" {
+		t.Error("Expected a hint for a credentials error")
+	}
+}
+
+func TestClientWithoutConnection(t *testing.T) {
+	client := &DynamoClient{}
+
+	if _, err := client.ListTables(context.Background()); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Expected ErrNotConnected instead of mock tables, got %v", err)
+	}
+	if _, err := client.DescribeTable(context.Background(), "Users"); !errors.Is(err, ErrNotConnected) {
+		t.Errorf("Expected ErrNotConnected instead of mock table info, got %v", err)
+	}
+}
+
+func TestReadOnlyClient(t *testing.T) {
+	client := NewDemoClient(&appconfig.Config{ReadOnly: true})
+	ctx := context.Background()
+
+	page, err := client.Scan(ctx, "Users", 0, nil)
+	if err != nil || len(page.Items) == 0 {
+		t.Fatalf("Expected reads to work, got %v", err)
+	}
+	item := page.Items[0]
+	keyAttrs := []string{"UserID", "Email"}
+	key := KeyOf(item, keyAttrs)
+	updated := Item{"Name": &types.AttributeValueMemberS{Value: "Changed"}}
+	for attr, value := range key {
+		updated[attr] = value
+	}
+	update, err := DiffItems(item, updated, keyAttrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writes := map[string]func() error{
+		"put item":     func() error { return client.PutItem(ctx, "Users", updated, keyAttrs, true) },
+		"update item":  func() error { return client.UpdateItem(ctx, "Users", update) },
+		"delete item":  func() error { return client.DeleteItem(ctx, "Users", key) },
+		"batch delete": func() error { return client.DeleteItems(ctx, "Users", []Item{key}, nil) },
+		"execute statement": func() error {
+			_, err := client.ExecuteStatement(ctx, Statement{Text: `DELETE FROM Users WHERE UserID = ? AND Email = ?`, Parameters: []types.AttributeValue{key["UserID"], key["Email"]}}, 0, "")
+			return err
+		},
+	}
+	for op, write := range writes {
+		err := write()
+		if !errors.Is(err, ErrReadOnly) || !IsKind(err, KindReadOnly) {
+			t.Errorf("%s: expected a read-only error, got %v", op, err)
+		}
+	}
+
+	after, err := client.GetItem(ctx, "Users", key)
+	if err != nil || !reflect.DeepEqual(after, item) {
+		t.Errorf("Expected the item to be unchanged, got %v, %v", after, err)
+	}
+}
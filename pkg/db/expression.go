@@ -0,0 +1,369 @@
+package db
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// SortOperator is a comparison allowed on a sort key in a key condition
+type SortOperator string
+
+// Sort key operators supported by Query
+const (
+	SortEqual          SortOperator = "="
+	SortLess           SortOperator = "<"
+	SortLessOrEqual    SortOperator = "<="
+	SortGreater        SortOperator = ">"
+	SortGreaterOrEqual SortOperator = ">="
+	SortBetween        SortOperator = "BETWEEN"
+	SortBeginsWith     SortOperator = "begins_with"
+)
+
+// SortOperators lists the sort key operators in display order
+var SortOperators = []SortOperator{
+	SortEqual,
+	SortLess,
+	SortLessOrEqual,
+	SortGreater,
+	SortGreaterOrEqual,
+	SortBetween,
+	SortBeginsWith,
+}
+
+// SortKeyCondition restricts the sort key of a Query. BETWEEN takes two
+// values; every other operator takes one.
+type SortKeyCondition struct {
+	Operator SortOperator
+	Values   []types.AttributeValue
+}
+
+// FilterOperator compares an attribute in a filter condition
+type FilterOperator string
+
+// Filter operators supported by Scan and Query
+const (
+	FilterEqual       FilterOperator = "="
+	FilterNotEqual    FilterOperator = "<>"
+	FilterLess        FilterOperator = "<"
+	FilterGreater     FilterOperator = ">"
+	FilterContains    FilterOperator = "contains"
+	FilterBeginsWith  FilterOperator = "begins_with"
+	FilterExists      FilterOperator = "attribute_exists"
+	FilterNotExists   FilterOperator = "attribute_not_exists"
+	FilterIn          FilterOperator = "IN"
+	FilterSizeEqual   FilterOperator = "size ="
+	FilterSizeLess    FilterOperator = "size <"
+	FilterSizeGreater FilterOperator = "size >"
+)
+
+// FilterOperators lists the filter operators in display order
+var FilterOperators = []FilterOperator{
+	FilterEqual,
+	FilterNotEqual,
+	FilterLess,
+	FilterGreater,
+	FilterContains,
+	FilterBeginsWith,
+	FilterExists,
+	FilterNotExists,
+	FilterIn,
+	FilterSizeEqual,
+	FilterSizeLess,
+	FilterSizeGreater,
+}
+
+// maxInValues is the most values DynamoDB accepts in an IN condition
+const maxInValues = 100
+
+// TakesValue reports whether the operator compares the attribute with a
+// value. attribute_exists and attribute_not_exists do not.
+func (o FilterOperator) TakesValue() bool {
+	return o != FilterExists && o != FilterNotExists
+}
+
+// FilterCondition is one condition of a Scan or Query filter. IN takes one
+// or more values, attribute_exists and attribute_not_exists none, and
+// every other operator one. Or joins the condition to the one before it
+// with OR instead of AND; AND binds tighter, as in DynamoDB.
+type FilterCondition struct {
+	Attribute string // A name or a path such as Address.City or Lines[0]
+	Operator  FilterOperator
+	Values    []types.AttributeValue
+	Or        bool
+}
+
+// numberPattern matches the numbers ParseFilterValue reads as N
+var numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// ParseFilterValue reads a value typed for a filter: a number, true, false
+// or null, or a string otherwise. Double quotes force a string, so "42" is
+// the string 42.
+func ParseFilterValue(raw string) types.AttributeValue {
+	raw = strings.TrimSpace(raw)
+	if len(raw) >= 2 && raw[0] == '"' && raw[len(raw)-1] == '"' {
+		if s, err := strconv.Unquote(raw); err == nil {
+			return &types.AttributeValueMemberS{Value: s}
+		}
+	}
+	switch {
+	case raw == "true", raw == "false":
+		return &types.AttributeValueMemberBOOL{Value: raw == "true"}
+	case raw == "null":
+		return &types.AttributeValueMemberNULL{Value: true}
+	case numberPattern.MatchString(raw):
+		return &types.AttributeValueMemberN{Value: raw}
+	}
+	return &types.AttributeValueMemberS{Value: raw}
+}
+
+// ParseFilterValues reads the values typed for a condition with operator
+// op. IN takes a comma-separated list; the size operators take a whole
+// number.
+func ParseFilterValues(op FilterOperator, raw string) ([]types.AttributeValue, error) {
+	if !op.TakesValue() {
+		return nil, nil
+	}
+	if strings.TrimSpace(raw) == "" {
+		return nil, fmt.Errorf("enter a value for %s", op)
+	}
+
+	switch op {
+	case FilterIn:
+		var values []types.AttributeValue
+		for _, part := range splitOutsideQuotes(raw, ',') {
+			if strings.TrimSpace(part) == "" {
+				return nil, fmt.Errorf("empty value in the list for IN")
+			}
+			values = append(values, ParseFilterValue(part))
+		}
+		return values, nil
+	case FilterSizeEqual, FilterSizeLess, FilterSizeGreater:
+		size := strings.TrimSpace(raw)
+		if n, err := strconv.Atoi(size); err != nil || n < 0 {
+			return nil, fmt.Errorf("size takes a whole number, got %q", size)
+		}
+		return []types.AttributeValue{&types.AttributeValueMemberN{Value: size}}, nil
+	}
+	return []types.AttributeValue{ParseFilterValue(raw)}, nil
+}
+
+// splitOutsideQuotes splits s at each sep that is not between double
+// quotes
+func splitOutsideQuotes(s string, sep byte) []string {
+	var parts []string
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// exprBuilder hands out placeholders for expression attribute names and
+// values so that user supplied names never collide with reserved words
+type exprBuilder struct {
+	names  map[string]string
+	values map[string]types.AttributeValue
+}
+
+func newExprBuilder() *exprBuilder {
+	return &exprBuilder{
+		names:  map[string]string{},
+		values: map[string]types.AttributeValue{},
+	}
+}
+
+// name returns the placeholder for an attribute name, reusing the same
+// placeholder when a name is referenced more than once
+func (e *exprBuilder) name(attr string) string {
+	for placeholder, existing := range e.names {
+		if existing == attr {
+			return placeholder
+		}
+	}
+	placeholder := fmt.Sprintf("#n%d", len(e.names))
+	e.names[placeholder] = attr
+	return placeholder
+}
+
+// value returns a new placeholder bound to av
+func (e *exprBuilder) value(av types.AttributeValue) string {
+	placeholder := fmt.Sprintf(":v%d", len(e.values))
+	e.values[placeholder] = av
+	return placeholder
+}
+
+// attributeNames returns the name placeholders, or nil if none were used
+func (e *exprBuilder) attributeNames() map[string]string {
+	if len(e.names) == 0 {
+		return nil
+	}
+	return e.names
+}
+
+// attributeValues returns the value placeholders, or nil if none were used
+func (e *exprBuilder) attributeValues() map[string]types.AttributeValue {
+	if len(e.values) == 0 {
+		return nil
+	}
+	return e.values
+}
+
+// keyCondition builds the KeyConditionExpression for a query
+func (e *exprBuilder) keyCondition(q QueryInput) (string, error) {
+	if q.PartitionKey == "" || q.PartitionValue == nil {
+		return "", fmt.Errorf("a partition key value is required")
+	}
+	expr := fmt.Sprintf("%s = %s", e.name(q.PartitionKey), e.value(q.PartitionValue))
+
+	if q.SortCondition == nil {
+		return expr, nil
+	}
+	if q.SortKey == "" {
+		return "", fmt.Errorf("a sort key condition requires a sort key")
+	}
+
+	cond := q.SortCondition
+	want := 1
+	if cond.Operator == SortBetween {
+		want = 2
+	}
+	if len(cond.Values) != want {
+		return "", fmt.Errorf("sort key operator %s takes %d value(s), got %d", cond.Operator, want, len(cond.Values))
+	}
+
+	sk := e.name(q.SortKey)
+	switch cond.Operator {
+	case SortEqual, SortLess, SortLessOrEqual, SortGreater, SortGreaterOrEqual:
+		expr += fmt.Sprintf(" AND %s %s %s", sk, cond.Operator, e.value(cond.Values[0]))
+	case SortBetween:
+		expr += fmt.Sprintf(" AND %s BETWEEN %s AND %s", sk, e.value(cond.Values[0]), e.value(cond.Values[1]))
+	case SortBeginsWith:
+		expr += fmt.Sprintf(" AND begins_with(%s, %s)", sk, e.value(cond.Values[0]))
+	default:
+		return "", fmt.Errorf("unsupported sort key operator %q", cond.Operator)
+	}
+
+	return expr, nil
+}
+
+// listIndexes matches the list indexes that may follow a name in a path
+var listIndexes = regexp.MustCompile(`^(\[[0-9]+\])+$`)
+
+// path returns the expression for an attribute path such as Address.City
+// or Lines[0].ProductID, with a placeholder for every name
+func (e *exprBuilder) path(attr string) (string, error) {
+	if attr == "" {
+		return "", fmt.Errorf("an attribute name is required")
+	}
+	parts := strings.Split(attr, ".")
+	for i, part := range parts {
+		name, indexes := part, ""
+		if j := strings.IndexByte(part, '['); j >= 0 {
+			name, indexes = part[:j], part[j:]
+			if !listIndexes.MatchString(indexes) {
+				return "", fmt.Errorf("invalid list index in attribute path %q", attr)
+			}
+		}
+		if name == "" {
+			return "", fmt.Errorf("invalid attribute path %q", attr)
+		}
+		parts[i] = e.name(name) + indexes
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// filter builds the FilterExpression for a Scan or Query
+func (e *exprBuilder) filter(conditions []FilterCondition) (string, error) {
+	var b strings.Builder
+	for i, c := range conditions {
+		path, err := e.path(c.Attribute)
+		if err != nil {
+			return "", err
+		}
+
+		want := 1
+		switch c.Operator {
+		case FilterExists, FilterNotExists:
+			want = 0
+		case FilterIn:
+			if len(c.Values) == 0 || len(c.Values) > maxInValues {
+				return "", fmt.Errorf("IN takes 1 to %d values, got %d", maxInValues, len(c.Values))
+			}
+			want = len(c.Values)
+		}
+		if len(c.Values) != want {
+			return "", fmt.Errorf("filter operator %s takes %d value(s), got %d", c.Operator, want, len(c.Values))
+		}
+
+		var cond string
+		switch c.Operator {
+		case FilterEqual, FilterNotEqual, FilterLess, FilterGreater:
+			cond = fmt.Sprintf("%s %s %s", path, c.Operator, e.value(c.Values[0]))
+		case FilterContains, FilterBeginsWith:
+			cond = fmt.Sprintf("%s(%s, %s)", c.Operator, path, e.value(c.Values[0]))
+		case FilterExists, FilterNotExists:
+			cond = fmt.Sprintf("%s(%s)", c.Operator, path)
+		case FilterIn:
+			placeholders := make([]string, len(c.Values))
+			for j, v := range c.Values {
+				placeholders[j] = e.value(v)
+			}
+			cond = fmt.Sprintf("%s IN (%s)", path, strings.Join(placeholders, ", "))
+		case FilterSizeEqual, FilterSizeLess, FilterSizeGreater:
+			cond = fmt.Sprintf("size(%s) %s %s", path, strings.TrimPrefix(string(c.Operator), "size "), e.value(c.Values[0]))
+		default:
+			return "", fmt.Errorf("unsupported filter operator %q", c.Operator)
+		}
+
+		if i > 0 {
+			if c.Or {
+				b.WriteString(" OR ")
+			} else {
+				b.WriteString(" AND ")
+			}
+		}
+		b.WriteString(cond)
+	}
+	return b.String(), nil
+}
+
+// projection builds the ProjectionExpression that reads only the top-level
+// attributes attrs. Unlike filter paths, a name with a dot or bracket is
+// one attribute.
+func (e *exprBuilder) projection(attrs []string) string {
+	names := make([]string, len(attrs))
+	for i, attr := range attrs {
+		names[i] = e.name(attr)
+	}
+	return strings.Join(names, ", ")
+}
+
+// filterAndProjection builds the filter and projection expressions of a
+// request. Each is nil when there are no conditions or attributes.
+func (e *exprBuilder) filterAndProjection(conditions []FilterCondition, attrs []string) (filter, projection *string, err error) {
+	if len(conditions) > 0 {
+		expr, err := e.filter(conditions)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter = &expr
+	}
+	if len(attrs) > 0 {
+		expr := e.projection(attrs)
+		projection = &expr
+	}
+	return filter, projection, nil
+}
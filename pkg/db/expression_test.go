@@ -0,0 +1,289 @@
+package db
+
+import (
+	"context"
+	"maps"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestKeyConditionPartitionOnly(t *testing.T) {
+	expr := newExprBuilder()
+	cond, err := expr.keyCondition(QueryInput{
+		PartitionKey:   "CustomerID",
+		PartitionValue: &types.AttributeValueMemberS{Value: "c-1"},
+	})
+	if err != nil {
+		t.Fatalf("Error building key condition: %v", err)
+	}
+
+	if cond != "#n0 = :v0" {
+		t.Errorf("Unexpected key condition: %s", cond)
+	}
+	if expr.names["#n0"] != "CustomerID" {
+		t.Errorf("Expected #n0 to map to CustomerID, got %s", expr.names["#n0"])
+	}
+}
+
+func TestKeyConditionSortOperators(t *testing.T) {
+	tests := []struct {
+		op       SortOperator
+		values   int
+		expected string
+	}{
+		{SortEqual, 1, "#n0 = :v0 AND #n1 = :v1"},
+		{SortLessOrEqual, 1, "#n0 = :v0 AND #n1 <= :v1"},
+		{SortBetween, 2, "#n0 = :v0 AND #n1 BETWEEN :v1 AND :v2"},
+		{SortBeginsWith, 1, "#n0 = :v0 AND begins_with(#n1, :v1)"},
+	}
+
+	for _, tt := range tests {
+		values := make([]types.AttributeValue, tt.values)
+		for i := range values {
+			values[i] = &types.AttributeValueMemberS{Value: "2024"}
+		}
+
+		expr := newExprBuilder()
+		cond, err := expr.keyCondition(QueryInput{
+			PartitionKey:   "Status",
+			PartitionValue: &types.AttributeValueMemberS{Value: "SHIPPED"},
+			SortKey:        "OrderDate",
+			SortCondition:  &SortKeyCondition{Operator: tt.op, Values: values},
+		})
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.op, err)
+			continue
+		}
+		if cond != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.op, tt.expected, cond)
+		}
+	}
+}
+
+func TestKeyConditionValidation(t *testing.T) {
+	expr := newExprBuilder()
+	_, err := expr.keyCondition(QueryInput{
+		PartitionKey:   "Status",
+		PartitionValue: &types.AttributeValueMemberS{Value: "SHIPPED"},
+		SortKey:        "OrderDate",
+		SortCondition: &SortKeyCondition{
+			Operator: SortBetween,
+			Values:   []types.AttributeValue{&types.AttributeValueMemberS{Value: "2024"}},
+		},
+	})
+	if err == nil {
+		t.Error("Expected error for BETWEEN with a single value, got nil")
+	}
+
+	_, err = newExprBuilder().keyCondition(QueryInput{PartitionKey: "Status"})
+	if err == nil {
+		t.Error("Expected error for missing partition value, got nil")
+	}
+}
+
+func TestFilterExpression(t *testing.T) {
+	one := []types.AttributeValue{&types.AttributeValueMemberS{Value: "x"}}
+	tests := []struct {
+		cond     FilterCondition
+		expected string
+	}{
+		{FilterCondition{Attribute: "Status", Operator: FilterEqual, Values: one}, "#n0 = :v0"},
+		{FilterCondition{Attribute: "Status", Operator: FilterNotEqual, Values: one}, "#n0 <> :v0"},
+		{FilterCondition{Attribute: "Tags", Operator: FilterContains, Values: one}, "contains(#n0, :v0)"},
+		{FilterCondition{Attribute: "Name", Operator: FilterBeginsWith, Values: one}, "begins_with(#n0, :v0)"},
+		{FilterCondition{Attribute: "Roles", Operator: FilterExists}, "attribute_exists(#n0)"},
+		{FilterCondition{Attribute: "Roles", Operator: FilterNotExists}, "attribute_not_exists(#n0)"},
+		{FilterCondition{Attribute: "Status", Operator: FilterIn, Values: append(one, one...)}, "#n0 IN (:v0, :v1)"},
+		{FilterCondition{Attribute: "Lines", Operator: FilterSizeGreater, Values: one}, "size(#n0) > :v0"},
+		{FilterCondition{Attribute: "Address.City", Operator: FilterEqual, Values: one}, "#n0.#n1 = :v0"},
+		{FilterCondition{Attribute: "Lines[0].ProductID", Operator: FilterEqual, Values: one}, "#n0[0].#n1 = :v0"},
+	}
+	for _, tt := range tests {
+		expr := newExprBuilder()
+		got, err := expr.filter([]FilterCondition{tt.cond})
+		if err != nil {
+			t.Errorf("%s %s: unexpected error: %v", tt.cond.Attribute, tt.cond.Operator, err)
+			continue
+		}
+		if got != tt.expected {
+			t.Errorf("%s %s: expected %q, got %q", tt.cond.Attribute, tt.cond.Operator, tt.expected, got)
+		}
+	}
+}
+
+func TestFilterCombinesConditions(t *testing.T) {
+	expr := newExprBuilder()
+	filter, err := expr.filter([]FilterCondition{
+		{Attribute: "Status", Operator: FilterEqual, Values: []types.AttributeValue{&types.AttributeValueMemberS{Value: "SHIPPED"}}},
+		{Attribute: "Total", Operator: FilterGreater, Values: []types.AttributeValue{&types.AttributeValueMemberN{Value: "40"}}},
+		{Attribute: "Status", Operator: FilterEqual, Values: []types.AttributeValue{&types.AttributeValueMemberS{Value: "PENDING"}}, Or: true},
+	})
+	if err != nil {
+		t.Fatalf("Error building filter: %v", err)
+	}
+	if filter != "#n0 = :v0 AND #n1 > :v1 OR #n0 = :v2" {
+		t.Errorf("Unexpected filter: %s", filter)
+	}
+	if len(expr.names) != 2 || expr.names["#n0"] != "Status" {
+		t.Errorf("Expected Status to be named once, got %v", expr.names)
+	}
+
+	invalid := []FilterCondition{
+		{Attribute: "", Operator: FilterExists},
+		{Attribute: "Lines[x]", Operator: FilterExists},
+		{Attribute: "Address..City", Operator: FilterExists},
+		{Attribute: "Status", Operator: FilterEqual},
+		{Attribute: "Status", Operator: FilterIn},
+		{Attribute: "Status", Operator: "LIKE", Values: []types.AttributeValue{&types.AttributeValueMemberS{Value: "x"}}},
+	}
+	for _, cond := range invalid {
+		if _, err := newExprBuilder().filter([]FilterCondition{cond}); err == nil {
+			t.Errorf("Expected an error for %+v", cond)
+		}
+	}
+}
+
+func TestProjectionExpression(t *testing.T) {
+	tests := []struct {
+		attrs    []string
+		expected string
+		names    map[string]string
+	}{
+		{[]string{"OrderID"}, "#n0", map[string]string{"#n0": "OrderID"}},
+		{[]string{"OrderID", "Status"}, "#n0, #n1", map[string]string{"#n0": "OrderID", "#n1": "Status"}},
+		{[]string{"Address.City"}, "#n0", map[string]string{"#n0": "Address.City"}},
+		{[]string{"Lines[0]", "Lines"}, "#n0, #n1", map[string]string{"#n0": "Lines[0]", "#n1": "Lines"}},
+	}
+	for _, tt := range tests {
+		expr := newExprBuilder()
+		got := expr.projection(tt.attrs)
+		if got != tt.expected || !maps.Equal(expr.names, tt.names) {
+			t.Errorf("%v: expected %q with %v, got %q with %v", tt.attrs, tt.expected, tt.names, got, expr.names)
+		}
+	}
+
+	// A top-level attribute with a dot in its name is read as it is
+	client := NewDemoClient(nil)
+	ctx := context.Background()
+	item := Item{
+		"UserID":       &types.AttributeValueMemberS{Value: "user-dotted"},
+		"Email":        &types.AttributeValueMemberS{Value: "dotted@example.com"},
+		"Address.City": &types.AttributeValueMemberS{Value: "Lisbon"},
+	}
+	if err := client.PutItem(ctx, "Users", item, []string{"UserID", "Email"}, false); err != nil {
+		t.Fatalf("Error putting item: %v", err)
+	}
+	page, err := client.ScanWith(ctx, ScanInput{TableName: "Users", Projection: []string{"UserID", "Email", "Address.City"}})
+	if err != nil {
+		t.Fatalf("Error scanning: %v", err)
+	}
+	found := false
+	for _, item := range page.Items {
+		if city, ok := item["Address.City"].(*types.AttributeValueMemberS); ok && city.Value == "Lisbon" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("Expected Address.City to be projected, got %v", page.Items)
+	}
+}
+
+func TestParseFilterValues(t *testing.T) {
+	tests := []struct {
+		op       FilterOperator
+		raw      string
+		expected []types.AttributeValue
+	}{
+		{FilterEqual, "SHIPPED", []types.AttributeValue{&types.AttributeValueMemberS{Value: "SHIPPED"}}},
+		{FilterEqual, "-12.5", []types.AttributeValue{&types.AttributeValueMemberN{Value: "-12.5"}}},
+		{FilterEqual, `"42"`, []types.AttributeValue{&types.AttributeValueMemberS{Value: "42"}}},
+		{FilterEqual, "true", []types.AttributeValue{&types.AttributeValueMemberBOOL{Value: true}}},
+		{FilterEqual, "null", []types.AttributeValue{&types.AttributeValueMemberNULL{Value: true}}},
+		{FilterExists, "ignored", nil},
+		{FilterIn, `PENDING, "a,b", 7`, []types.AttributeValue{
+			&types.AttributeValueMemberS{Value: "PENDING"},
+			&types.AttributeValueMemberS{Value: "a,b"},
+			&types.AttributeValueMemberN{Value: "7"},
+		}},
+		{FilterSizeLess, " 3 ", []types.AttributeValue{&types.AttributeValueMemberN{Value: "3"}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFilterValues(tt.op, tt.raw)
+		if err != nil {
+			t.Errorf("%s %q: unexpected error: %v", tt.op, tt.raw, err)
+			continue
+		}
+		if len(got) != len(tt.expected) {
+			t.Errorf("%s %q: expected %v, got %v", tt.op, tt.raw, tt.expected, got)
+			continue
+		}
+		for i := range got {
+			if ToPlain(got[i]) != ToPlain(tt.expected[i]) {
+				t.Errorf("%s %q: expected %#v, got %#v", tt.op, tt.raw, tt.expected[i], got[i])
+			}
+		}
+	}
+
+	for _, tt := range []struct {
+		op  FilterOperator
+		raw string
+	}{{FilterEqual, " "}, {FilterIn, "a,,b"}, {FilterSizeEqual, "two"}, {FilterSizeGreater, "-1"}} {
+		if _, err := ParseFilterValues(tt.op, tt.raw); err == nil {
+			t.Errorf("%s %q: expected an error", tt.op, tt.raw)
+		}
+	}
+}
+
+func TestFilteredScanAndQuery(t *testing.T) {
+	client := NewDemoClient(nil)
+	ctx := context.Background()
+
+	// Status is a reserved word, so it only works through a placeholder
+	page, err := client.ScanWith(ctx, ScanInput{
+		TableName: "Orders",
+		Filter: []FilterCondition{
+			{Attribute: "Status", Operator: FilterIn, Values: []types.AttributeValue{
+				&types.AttributeValueMemberS{Value: "SHIPPED"},
+				&types.AttributeValueMemberS{Value: "CANCELLED"},
+			}},
+			{Attribute: "Total", Operator: FilterGreater, Values: []types.AttributeValue{&types.AttributeValueMemberN{Value: "40"}}},
+		},
+		Projection: []string{"CustomerID", "OrderID", "Status"},
+	})
+	if err != nil {
+		t.Fatalf("Error scanning: %v", err)
+	}
+	if len(page.Items) != 5 {
+		t.Errorf("Expected 5 shipped or cancelled orders over 40, got %d", len(page.Items))
+	}
+	for _, item := range page.Items {
+		if len(item) != 3 || item["Status"] == nil {
+			t.Errorf("Expected only the key and Status, got %v", item)
+		}
+	}
+
+	page, err = client.Query(ctx, QueryInput{
+		TableName:      "Orders",
+		IndexName:      "StatusOrderDateIndex",
+		PartitionKey:   "Status",
+		PartitionValue: &types.AttributeValueMemberS{Value: "PENDING"},
+		Filter: []FilterCondition{
+			{Attribute: "Lines[0].Quantity", Operator: FilterEqual, Values: []types.AttributeValue{&types.AttributeValueMemberN{Value: "1"}}},
+			{Attribute: "Lines", Operator: FilterSizeGreater, Values: []types.AttributeValue{&types.AttributeValueMemberN{Value: "5"}}, Or: true},
+		},
+		Projection: []string{"OrderID", "Lines"},
+	})
+	if err != nil {
+		t.Fatalf("Error querying: %v", err)
+	}
+	// Pending orders are every fourth one, with quantities 1, 2, 3, 1, 2
+	if len(page.Items) != 2 {
+		t.Errorf("Expected 2 pending orders of one unit, got %v", page.Items)
+	}
+	for _, item := range page.Items {
+		if len(item) != 2 || item["Lines"] == nil {
+			t.Errorf("Expected OrderID and Lines, got %v", item)
+		}
+	}
+}
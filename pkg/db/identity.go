@@ -0,0 +1,86 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+)
+
+// ErrNoAWSCredentials is returned for the caller identity of a client that
+// does not sign requests, such as one serving demo data
+var ErrNoAWSCredentials = errors.New("the client does not use AWS credentials")
+
+// Identity reports where a client connects and who its credentials belong
+// to. A failed step is recorded in the report instead of returned, so a
+// broken setup can still be diagnosed. CallerErr is only set if the
+// credentials were resolved.
+type Identity struct {
+	Connection     string // Name of the connection in the config file, if any
+	Region         string
+	Profile        string
+	Endpoint       string // Empty for the AWS endpoint of Region
+	Credentials    CredentialsInfo
+	CredentialsErr error
+
+	Account   string
+	ARN       string
+	UserID    string
+	CallerErr error
+}
+
+// NewIdentity reports the configuration of cfg with err as the reason no
+// client could be created from it
+func NewIdentity(cfg *appconfig.Config, err error) Identity {
+	identity := Identity{CredentialsErr: err}
+	if cfg != nil {
+		identity.Connection = cfg.Connection
+		identity.Region = cfg.Region
+		identity.Profile = cfg.Profile
+		identity.Endpoint = cfg.Endpoint
+	}
+	return identity
+}
+
+// Identity resolves the credentials of the client and asks STS
+// GetCallerIdentity for the account and principal they belong to
+func (d *DynamoClient) Identity(ctx context.Context) Identity {
+	identity := NewIdentity(d.cfg, nil)
+	if d.demo || d.credentials == nil {
+		identity.CallerErr = ErrNoAWSCredentials
+		return identity
+	}
+
+	info, err := d.CredentialsInfo(ctx)
+	if err != nil {
+		// The SDK reports only its own last error; resolving again says
+		// why each source was passed over
+		if _, resolveErr := d.cfg.GetCredentials(ctx); resolveErr != nil {
+			err = resolveErr
+		}
+		identity.CredentialsErr = err
+		return identity
+	}
+	identity.Credentials = info
+
+	options := sts.Options{Region: d.cfg.Region, Credentials: d.credentials}
+	if d.cfg.STSEndpoint != "" {
+		options.BaseEndpoint = aws.String(d.cfg.STSEndpoint)
+	}
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Metadata)
+	defer cancel()
+
+	resp, err := sts.New(options).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		identity.CallerErr = fmt.Errorf("failed to get caller identity: %w", err)
+		return identity
+	}
+	identity.Account = aws.ToString(resp.Account)
+	identity.ARN = aws.ToString(resp.Arn)
+	identity.UserID = aws.ToString(resp.UserId)
+	return identity
+}
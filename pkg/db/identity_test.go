@@ -0,0 +1,71 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+)
+
+func TestIdentity(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "GetCallerIdentity" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, `<GetCallerIdentityResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <GetCallerIdentityResult>
+    <Arn>arn:aws:sts::123456789012:assumed-role/Data/dev</Arn>
+    <UserId>AROAEXAMPLE:dev</UserId>
+    <Account>123456789012</Account>
+  </GetCallerIdentityResult>
+  <ResponseMetadata><RequestId>1</RequestId></ResponseMetadata>
+</GetCallerIdentityResponse>`)
+	}))
+	defer server.Close()
+
+	expires := time.Now().Add(time.Hour)
+	client := &DynamoClient{
+		cfg: &appconfig.Config{Region: "eu-west-1", Profile: "dev", STSEndpoint: server.URL},
+		credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{
+				AccessKeyID:     "ASIADEV",
+				SecretAccessKey: "secret",
+				Source:          "profile dev (assumed role arn:aws:iam::123456789012:role/Data)",
+				CanExpire:       true,
+				Expires:         expires,
+			}, nil
+		}),
+	}
+
+	identity := client.Identity(context.Background())
+	if identity.CredentialsErr != nil || identity.CallerErr != nil {
+		t.Fatalf("Expected a verified identity, got %v and %v", identity.CredentialsErr, identity.CallerErr)
+	}
+	if identity.Region != "eu-west-1" || identity.Profile != "dev" {
+		t.Errorf("Expected the region and profile of the config, got %+v", identity)
+	}
+	if identity.Credentials.Source != "profile dev (assumed role arn:aws:iam::123456789012:role/Data)" || !identity.Credentials.Expires.Equal(expires) {
+		t.Errorf("Expected the source and expiry of the credentials, got %+v", identity.Credentials)
+	}
+	if identity.Account != "123456789012" || identity.ARN != "arn:aws:sts::123456789012:assumed-role/Data/dev" || identity.UserID != "AROAEXAMPLE:dev" {
+		t.Errorf("Expected the caller identity from STS, got %+v", identity)
+	}
+}
+
+func TestIdentityDemo(t *testing.T) {
+	identity := NewDemoClient(&appconfig.Config{Region: "us-west-2"}).Identity(context.Background())
+	if identity.Region != "us-west-2" || identity.CredentialsErr != nil {
+		t.Errorf("Expected the demo config without a credentials error, got %+v", identity)
+	}
+	if !errors.Is(identity.CallerErr, ErrNoAWSCredentials) {
+		t.Errorf("Expected ErrNoAWSCredentials, got %v", identity.CallerErr)
+	}
+}
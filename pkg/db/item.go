@@ -0,0 +1,265 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Item is a single DynamoDB item as returned by the SDK
+type Item = map[string]types.AttributeValue
+
+// ItemPage is one page of items returned by a Scan or Query
+type ItemPage struct {
+	Items            []Item
+	LastEvaluatedKey Item
+	NextToken        string // Set instead of LastEvaluatedKey by PartiQL
+	ScannedCount     int32
+}
+
+// HasMore reports whether another page can be requested
+func (p *ItemPage) HasMore() bool {
+	return len(p.LastEvaluatedKey) > 0 || p.NextToken != ""
+}
+
+// ToPlain converts an attribute value into a plain Go value suitable for
+// encoding/json. Numbers are kept as json.Number to avoid losing precision.
+func ToPlain(av types.AttributeValue) interface{} {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return json.Number(v.Value)
+	case *types.AttributeValueMemberB:
+		return v.Value
+	case *types.AttributeValueMemberBOOL:
+		return v.Value
+	case *types.AttributeValueMemberNULL:
+		return nil
+	case *types.AttributeValueMemberSS:
+		return append([]string{}, v.Value...)
+	case *types.AttributeValueMemberNS:
+		result := make([]json.Number, len(v.Value))
+		for i, n := range v.Value {
+			result[i] = json.Number(n)
+		}
+		return result
+	case *types.AttributeValueMemberBS:
+		return append([][]byte{}, v.Value...)
+	case *types.AttributeValueMemberL:
+		result := make([]interface{}, len(v.Value))
+		for i, elem := range v.Value {
+			result[i] = ToPlain(elem)
+		}
+		return result
+	case *types.AttributeValueMemberM:
+		return ItemToPlain(v.Value)
+	default:
+		return nil
+	}
+}
+
+// ItemToPlain converts an item into a map of plain Go values
+func ItemToPlain(item Item) map[string]interface{} {
+	result := make(map[string]interface{}, len(item))
+	for name, av := range item {
+		result[name] = ToPlain(av)
+	}
+	return result
+}
+
+// TypeOf returns the DynamoDB type descriptor of an attribute value
+func TypeOf(av types.AttributeValue) string {
+	switch av.(type) {
+	case *types.AttributeValueMemberS:
+		return "S"
+	case *types.AttributeValueMemberN:
+		return "N"
+	case *types.AttributeValueMemberB:
+		return "B"
+	case *types.AttributeValueMemberSS:
+		return "SS"
+	case *types.AttributeValueMemberNS:
+		return "NS"
+	case *types.AttributeValueMemberBS:
+		return "BS"
+	case *types.AttributeValueMemberL:
+		return "L"
+	case *types.AttributeValueMemberM:
+		return "M"
+	case *types.AttributeValueMemberBOOL:
+		return "BOOL"
+	case *types.AttributeValueMemberNULL:
+		return "NULL"
+	default:
+		return "?"
+	}
+}
+
+// ToDynamoJSON converts an attribute value into its DynamoDB JSON form, where
+// every value is wrapped in an object keyed by its type descriptor
+func ToDynamoJSON(av types.AttributeValue) map[string]interface{} {
+	var value interface{}
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		value = v.Value
+	case *types.AttributeValueMemberN:
+		value = v.Value
+	case *types.AttributeValueMemberB:
+		value = v.Value
+	case *types.AttributeValueMemberSS:
+		value = append([]string{}, v.Value...)
+	case *types.AttributeValueMemberNS:
+		value = append([]string{}, v.Value...)
+	case *types.AttributeValueMemberBS:
+		value = append([][]byte{}, v.Value...)
+	case *types.AttributeValueMemberL:
+		list := make([]interface{}, len(v.Value))
+		for i, elem := range v.Value {
+			list[i] = ToDynamoJSON(elem)
+		}
+		value = list
+	case *types.AttributeValueMemberM:
+		value = ItemToDynamoJSON(v.Value)
+	case *types.AttributeValueMemberBOOL:
+		value = v.Value
+	case *types.AttributeValueMemberNULL:
+		value = true
+	}
+	return map[string]interface{}{TypeOf(av): value}
+}
+
+// ItemToDynamoJSON converts an item into DynamoDB JSON
+func ItemToDynamoJSON(item Item) map[string]interface{} {
+	result := make(map[string]interface{}, len(item))
+	for name, av := range item {
+		result[name] = ToDynamoJSON(av)
+	}
+	return result
+}
+
+// FromDynamoJSON converts a decoded DynamoDB JSON value, an object with a
+// single type descriptor key, into an attribute value
+func FromDynamoJSON(raw json.RawMessage) (types.AttributeValue, error) {
+	var wrapper map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &wrapper); err != nil {
+		return nil, fmt.Errorf("expected an object like {\"S\": \"value\"}: %w", err)
+	}
+	if len(wrapper) != 1 {
+		return nil, fmt.Errorf("expected exactly one type descriptor, got %d", len(wrapper))
+	}
+
+	for typ, value := range wrapper {
+		switch typ {
+		case "S":
+			var v string
+			err := json.Unmarshal(value, &v)
+			return &types.AttributeValueMemberS{Value: v}, err
+		case "N":
+			var v string
+			if err := json.Unmarshal(value, &v); err != nil {
+				return nil, fmt.Errorf("N values must be strings: %w", err)
+			}
+			if _, err := strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("invalid number %q", v)
+			}
+			return &types.AttributeValueMemberN{Value: v}, nil
+		case "B":
+			var v []byte
+			err := json.Unmarshal(value, &v)
+			return &types.AttributeValueMemberB{Value: v}, err
+		case "BOOL":
+			var v bool
+			err := json.Unmarshal(value, &v)
+			return &types.AttributeValueMemberBOOL{Value: v}, err
+		case "NULL":
+			return &types.AttributeValueMemberNULL{Value: true}, nil
+		case "SS":
+			var v []string
+			err := json.Unmarshal(value, &v)
+			return &types.AttributeValueMemberSS{Value: v}, err
+		case "NS":
+			var v []string
+			if err := json.Unmarshal(value, &v); err != nil {
+				return nil, fmt.Errorf("NS values must be strings: %w", err)
+			}
+			return &types.AttributeValueMemberNS{Value: v}, nil
+		case "BS":
+			var v [][]byte
+			err := json.Unmarshal(value, &v)
+			return &types.AttributeValueMemberBS{Value: v}, err
+		case "L":
+			var elems []json.RawMessage
+			if err := json.Unmarshal(value, &elems); err != nil {
+				return nil, err
+			}
+			list := make([]types.AttributeValue, len(elems))
+			for i, elem := range elems {
+				av, err := FromDynamoJSON(elem)
+				if err != nil {
+					return nil, fmt.Errorf("[%d]: %w", i, err)
+				}
+				list[i] = av
+			}
+			return &types.AttributeValueMemberL{Value: list}, nil
+		case "M":
+			item, err := ItemFromDynamoJSON(value)
+			if err != nil {
+				return nil, err
+			}
+			return &types.AttributeValueMemberM{Value: item}, nil
+		default:
+			return nil, fmt.Errorf("unknown type descriptor %q", typ)
+		}
+	}
+	return nil, nil
+}
+
+// ItemFromDynamoJSON parses an item written in DynamoDB JSON
+func ItemFromDynamoJSON(data []byte) (Item, error) {
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, err
+	}
+
+	item := make(Item, len(attrs))
+	for name, raw := range attrs {
+		av, err := FromDynamoJSON(raw)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		item[name] = av
+	}
+	return item, nil
+}
+
+// AttributeNames returns the attribute names of an item in sorted order
+func AttributeNames(item Item) []string {
+	names := make([]string, 0, len(item))
+	for name := range item {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// KeyValue builds an attribute value for a key attribute from its string
+// form, using the scalar type from the table's attribute definitions.
+func KeyValue(attrType, raw string) (types.AttributeValue, error) {
+	switch attrType {
+	case "S", "":
+		return &types.AttributeValueMemberS{Value: raw}, nil
+	case "N":
+		if _, err := strconv.ParseFloat(raw, 64); err != nil {
+			return nil, fmt.Errorf("invalid number %q", raw)
+		}
+		return &types.AttributeValueMemberN{Value: raw}, nil
+	case "B":
+		return &types.AttributeValueMemberB{Value: []byte(raw)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported key attribute type %q", attrType)
+	}
+}
@@ -0,0 +1,65 @@
+package db
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func testItem() Item {
+	return Item{
+		"OrderID": &types.AttributeValueMemberS{Value: "o-1"},
+		"Total":   &types.AttributeValueMemberN{Value: "12.50"},
+		"Paid":    &types.AttributeValueMemberBOOL{Value: true},
+		"Notes":   &types.AttributeValueMemberNULL{Value: true},
+		"Tags":    &types.AttributeValueMemberSS{Value: []string{"gift"}},
+		"Lines": &types.AttributeValueMemberL{Value: []types.AttributeValue{
+			&types.AttributeValueMemberM{Value: map[string]types.AttributeValue{
+				"SKU": &types.AttributeValueMemberS{Value: "sku-1"},
+				"Qty": &types.AttributeValueMemberN{Value: "2"},
+			}},
+		}},
+	}
+}
+
+func TestItemToPlain(t *testing.T) {
+	data, err := json.Marshal(ItemToPlain(testItem()))
+	if err != nil {
+		t.Fatalf("Error marshaling item: %v", err)
+	}
+
+	expected := `{"Lines":[{"Qty":2,"SKU":"sku-1"}],"Notes":null,"OrderID":"o-1","Paid":true,"Tags":["gift"],"Total":12.50}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestItemToDynamoJSON(t *testing.T) {
+	data, err := json.Marshal(ItemToDynamoJSON(testItem()))
+	if err != nil {
+		t.Fatalf("Error marshaling item: %v", err)
+	}
+
+	expected := `{"Lines":{"L":[{"M":{"Qty":{"N":"2"},"SKU":{"S":"sku-1"}}}]},"Notes":{"NULL":true},"OrderID":{"S":"o-1"},"Paid":{"BOOL":true},"Tags":{"SS":["gift"]},"Total":{"N":"12.50"}}`
+	if string(data) != expected {
+		t.Errorf("Expected %s, got %s", expected, data)
+	}
+}
+
+func TestTypeOf(t *testing.T) {
+	item := testItem()
+	expected := map[string]string{
+		"OrderID": "S",
+		"Total":   "N",
+		"Paid":    "BOOL",
+		"Notes":   "NULL",
+		"Tags":    "SS",
+		"Lines":   "L",
+	}
+	for name, want := range expected {
+		if got := TypeOf(item[name]); got != want {
+			t.Errorf("Expected type %s for %s, got %s", want, name, got)
+		}
+	}
+}
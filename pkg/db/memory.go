@@ -0,0 +1,808 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// MemoryBackend is an in-memory implementation of Backend. It stores tables
+// and items, reads through secondary indexes and evaluates key condition,
+// filter, condition, projection and update expressions, so it behaves like
+// a real table in demo mode and in tests.
+type MemoryBackend struct {
+	mu     sync.RWMutex
+	tables map[string]*memTable
+}
+
+type memTable struct {
+	description types.TableDescription
+	keyAttrs    []string // partition key, then the sort key if any
+	items       map[string]Item
+}
+
+// NewMemoryBackend creates an empty in-memory backend
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{tables: map[string]*memTable{}}
+}
+
+// CreateTable creates an empty table with the given key schema and indexes
+func (m *MemoryBackend) CreateTable(ctx context.Context, params *dynamodb.CreateTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.CreateTableOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	name := aws.ToString(params.TableName)
+	if name == "" {
+		return nil, validationError("TableName must be specified")
+	}
+
+	defined := map[string]bool{}
+	for _, attr := range params.AttributeDefinitions {
+		defined[aws.ToString(attr.AttributeName)] = true
+	}
+	checkSchema := func(schema []types.KeySchemaElement) ([]string, error) {
+		attrs := schemaAttrs(schema)
+		if len(attrs) == 0 || len(attrs) != len(schema) {
+			return nil, validationError("invalid key schema for table %s", name)
+		}
+		for _, attr := range attrs {
+			if !defined[attr] {
+				return nil, validationError("key attribute %s is missing from the attribute definitions", attr)
+			}
+		}
+		return attrs, nil
+	}
+
+	keyAttrs, err := checkSchema(params.KeySchema)
+	if err != nil {
+		return nil, err
+	}
+
+	description := types.TableDescription{
+		TableName:            aws.String(name),
+		TableStatus:          types.TableStatusActive,
+		KeySchema:            params.KeySchema,
+		AttributeDefinitions: params.AttributeDefinitions,
+		CreationDateTime:     aws.Time(time.Now()),
+	}
+	for _, gsi := range params.GlobalSecondaryIndexes {
+		if _, err := checkSchema(gsi.KeySchema); err != nil {
+			return nil, err
+		}
+		description.GlobalSecondaryIndexes = append(description.GlobalSecondaryIndexes, types.GlobalSecondaryIndexDescription{
+			IndexName:   gsi.IndexName,
+			KeySchema:   gsi.KeySchema,
+			Projection:  gsi.Projection,
+			IndexStatus: types.IndexStatusActive,
+		})
+	}
+	for _, lsi := range params.LocalSecondaryIndexes {
+		attrs, err := checkSchema(lsi.KeySchema)
+		if err != nil {
+			return nil, err
+		}
+		if attrs[0] != keyAttrs[0] {
+			return nil, validationError("local secondary index %s must use the table's partition key", aws.ToString(lsi.IndexName))
+		}
+		description.LocalSecondaryIndexes = append(description.LocalSecondaryIndexes, types.LocalSecondaryIndexDescription{
+			IndexName:  lsi.IndexName,
+			KeySchema:  lsi.KeySchema,
+			Projection: lsi.Projection,
+		})
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tables[name]; ok {
+		return nil, &types.ResourceInUseException{Message: aws.String("Table already exists: " + name)}
+	}
+	m.tables[name] = &memTable{
+		description: description,
+		keyAttrs:    keyAttrs,
+		items:       map[string]Item{},
+	}
+	return &dynamodb.CreateTableOutput{TableDescription: &description}, nil
+}
+
+// ListTables returns the table names in alphabetical order
+func (m *MemoryBackend) ListTables(ctx context.Context, params *dynamodb.ListTablesInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ListTablesOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	names := make([]string, 0, len(m.tables))
+	for name := range m.tables {
+		if params.ExclusiveStartTableName == nil || name > *params.ExclusiveStartTableName {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	out := &dynamodb.ListTablesOutput{TableNames: names}
+	if limit := int(aws.ToInt32(params.Limit)); limit > 0 && len(names) > limit {
+		out.TableNames = names[:limit]
+		out.LastEvaluatedTableName = aws.String(names[limit-1])
+	}
+	return out, nil
+}
+
+// DescribeTable returns the schema and item count of a table
+func (m *MemoryBackend) DescribeTable(ctx context.Context, params *dynamodb.DescribeTableInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DescribeTableOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, err := m.table(params.TableName)
+	if err != nil {
+		return nil, err
+	}
+	description := t.description
+	description.ItemCount = aws.Int64(int64(len(t.items)))
+	return &dynamodb.DescribeTableOutput{Table: &description}, nil
+}
+
+// Scan reads a table or index in primary key order
+func (m *MemoryBackend) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, err := m.table(params.TableName)
+	if err != nil {
+		return nil, err
+	}
+	indexAttrs, err := t.indexAttrs(params.IndexName)
+	if err != nil {
+		return nil, err
+	}
+
+	exprs := newExprContext(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	filter, projection, err := exprs.filterAndProjection(params.FilterExpression, params.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := exprs.checkUnused(); err != nil {
+		return nil, validationError("%v", err)
+	}
+
+	order := compareOn(appendMissing(indexAttrs, t.keyAttrs))
+	candidates := t.itemsWith(indexAttrs)
+	sort.Slice(candidates, func(i, j int) bool { return order(candidates[i], candidates[j]) < 0 })
+
+	page := t.readPage(candidates, order, appendMissing(t.keyAttrs, indexAttrs), params.ExclusiveStartKey, params.Limit, filter, projection)
+	return &dynamodb.ScanOutput{
+		Items:            page.items,
+		Count:            int32(len(page.items)),
+		ScannedCount:     page.scanned,
+		LastEvaluatedKey: page.lastKey,
+	}, nil
+}
+
+// Query reads the items of one partition of a table or index in sort key
+// order
+func (m *MemoryBackend) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, err := m.table(params.TableName)
+	if err != nil {
+		return nil, err
+	}
+	indexAttrs, err := t.indexAttrs(params.IndexName)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.KeyConditionExpression == nil {
+		return nil, validationError("KeyConditionExpression must be specified")
+	}
+	exprs := newExprContext(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	keyCondition, attrs, err := exprs.parseCondition(*params.KeyConditionExpression)
+	if err != nil {
+		return nil, validationError("Invalid KeyConditionExpression: %v", err)
+	}
+	if !attrs[indexAttrs[0]] {
+		return nil, validationError("Query condition missed key schema element: %s", indexAttrs[0])
+	}
+	for attr := range attrs {
+		if attr != indexAttrs[0] && (len(indexAttrs) < 2 || attr != indexAttrs[1]) {
+			return nil, validationError("Query key condition not supported: %s is not a key attribute", attr)
+		}
+	}
+	filter, projection, err := exprs.filterAndProjection(params.FilterExpression, params.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := exprs.checkUnused(); err != nil {
+		return nil, validationError("%v", err)
+	}
+
+	var candidates []Item
+	for _, item := range t.itemsWith(indexAttrs) {
+		if keyCondition(item) {
+			candidates = append(candidates, item)
+		}
+	}
+
+	order := compareOn(appendMissing(indexAttrs[1:], t.keyAttrs))
+	if params.ScanIndexForward != nil && !*params.ScanIndexForward {
+		ascending := order
+		order = func(a, b Item) int { return -ascending(a, b) }
+	}
+	sort.Slice(candidates, func(i, j int) bool { return order(candidates[i], candidates[j]) < 0 })
+
+	page := t.readPage(candidates, order, appendMissing(t.keyAttrs, indexAttrs), params.ExclusiveStartKey, params.Limit, filter, projection)
+	return &dynamodb.QueryOutput{
+		Items:            page.items,
+		Count:            int32(len(page.items)),
+		ScannedCount:     page.scanned,
+		LastEvaluatedKey: page.lastKey,
+	}, nil
+}
+
+// GetItem returns the item with the given primary key, if any
+func (m *MemoryBackend) GetItem(ctx context.Context, params *dynamodb.GetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.GetItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	t, err := m.table(params.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validateKey(params.Key); err != nil {
+		return nil, err
+	}
+
+	exprs := newExprContext(params.ExpressionAttributeNames, nil)
+	_, projection, err := exprs.filterAndProjection(nil, params.ProjectionExpression)
+	if err != nil {
+		return nil, err
+	}
+	if err := exprs.checkUnused(); err != nil {
+		return nil, validationError("%v", err)
+	}
+
+	item, ok := t.items[itemKey(params.Key, t.keyAttrs)]
+	if !ok {
+		return &dynamodb.GetItemOutput{}, nil
+	}
+	return &dynamodb.GetItemOutput{Item: project(item, projection)}, nil
+}
+
+// PutItem creates or replaces an item
+func (m *MemoryBackend) PutItem(ctx context.Context, params *dynamodb.PutItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.PutItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, err := m.table(params.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validateItem(params.Item); err != nil {
+		return nil, err
+	}
+
+	key := itemKey(params.Item, t.keyAttrs)
+	old := t.items[key]
+	if err := checkCondition(params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues, old); err != nil {
+		return nil, err
+	}
+
+	t.items[key] = copyItem(params.Item)
+
+	out := &dynamodb.PutItemOutput{}
+	if params.ReturnValues == types.ReturnValueAllOld && old != nil {
+		out.Attributes = copyItem(old)
+	}
+	return out, nil
+}
+
+// UpdateItem applies an update expression to an item, creating the item if
+// it does not exist
+func (m *MemoryBackend) UpdateItem(ctx context.Context, params *dynamodb.UpdateItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.UpdateItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, err := m.table(params.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validateKey(params.Key); err != nil {
+		return nil, err
+	}
+
+	exprs := newExprContext(params.ExpressionAttributeNames, params.ExpressionAttributeValues)
+	var actions []updateAction
+	if params.UpdateExpression != nil {
+		if actions, err = exprs.parseUpdate(*params.UpdateExpression); err != nil {
+			return nil, validationError("Invalid UpdateExpression: %v", err)
+		}
+	}
+	var cond condition
+	if params.ConditionExpression != nil {
+		if cond, _, err = exprs.parseCondition(*params.ConditionExpression); err != nil {
+			return nil, validationError("Invalid ConditionExpression: %v", err)
+		}
+	}
+	if err := exprs.checkUnused(); err != nil {
+		return nil, validationError("%v", err)
+	}
+
+	key := itemKey(params.Key, t.keyAttrs)
+	old := t.items[key]
+	if cond != nil && !cond(orEmpty(old)) {
+		return nil, conditionFailed()
+	}
+
+	// Every value is computed from the item as it was before the update
+	before := orEmpty(old)
+	updated := copyItem(params.Key)
+	if old != nil {
+		updated = copyItem(old)
+	}
+	for _, action := range actions {
+		for _, attr := range t.keyAttrs {
+			if action.path[0].name == attr {
+				return nil, validationError("Cannot update attribute %s. This attribute is part of the key", attr)
+			}
+		}
+		if action.value == nil {
+			action.path.remove(updated)
+			continue
+		}
+		value, err := action.value(before)
+		if err != nil {
+			return nil, validationError("Invalid UpdateExpression: %v", err)
+		}
+		if err := action.path.set(updated, copyValue(value)); err != nil {
+			return nil, validationError("%v", err)
+		}
+	}
+	if err := t.validateItem(updated); err != nil {
+		return nil, err
+	}
+	t.items[key] = updated
+
+	out := &dynamodb.UpdateItemOutput{}
+	switch params.ReturnValues {
+	case types.ReturnValueAllNew:
+		out.Attributes = copyItem(updated)
+	case types.ReturnValueAllOld:
+		if old != nil {
+			out.Attributes = copyItem(old)
+		}
+	}
+	return out, nil
+}
+
+// DeleteItem removes an item by primary key
+func (m *MemoryBackend) DeleteItem(ctx context.Context, params *dynamodb.DeleteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.DeleteItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	t, err := m.table(params.TableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.validateKey(params.Key); err != nil {
+		return nil, err
+	}
+
+	key := itemKey(params.Key, t.keyAttrs)
+	old := t.items[key]
+	if err := checkCondition(params.ConditionExpression, params.ExpressionAttributeNames, params.ExpressionAttributeValues, old); err != nil {
+		return nil, err
+	}
+	delete(t.items, key)
+
+	out := &dynamodb.DeleteItemOutput{}
+	if params.ReturnValues == types.ReturnValueAllOld && old != nil {
+		out.Attributes = copyItem(old)
+	}
+	return out, nil
+}
+
+// BatchWriteItem applies up to 25 puts and deletes. Every request is
+// processed, so UnprocessedItems is always empty.
+func (m *MemoryBackend) BatchWriteItem(ctx context.Context, params *dynamodb.BatchWriteItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchWriteItemOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	count := 0
+	for name, requests := range params.RequestItems {
+		t, err := m.table(aws.String(name))
+		if err != nil {
+			return nil, err
+		}
+		for _, req := range requests {
+			count++
+			switch {
+			case req.PutRequest != nil:
+				err = t.validateItem(req.PutRequest.Item)
+			case req.DeleteRequest != nil:
+				err = t.validateKey(req.DeleteRequest.Key)
+			default:
+				err = validationError("a write request must contain a PutRequest or a DeleteRequest")
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	if count == 0 || count > maxBatchWriteItems {
+		return nil, validationError("BatchWriteItem accepts between 1 and %d write requests, got %d", maxBatchWriteItems, count)
+	}
+
+	for name, requests := range params.RequestItems {
+		t := m.tables[name]
+		for _, req := range requests {
+			if req.PutRequest != nil {
+				t.items[itemKey(req.PutRequest.Item, t.keyAttrs)] = copyItem(req.PutRequest.Item)
+			} else {
+				delete(t.items, itemKey(req.DeleteRequest.Key, t.keyAttrs))
+			}
+		}
+	}
+
+	return &dynamodb.BatchWriteItemOutput{UnprocessedItems: map[string][]types.WriteRequest{}}, nil
+}
+
+func (m *MemoryBackend) table(name *string) (*memTable, error) {
+	t, ok := m.tables[aws.ToString(name)]
+	if !ok {
+		return nil, &types.ResourceNotFoundException{
+			Message: aws.String("Requested resource not found: Table: " + aws.ToString(name) + " not found"),
+		}
+	}
+	return t, nil
+}
+
+// indexAttrs returns the key attributes of the named index, or of the
+// table itself when name is nil
+func (t *memTable) indexAttrs(name *string) ([]string, error) {
+	if name == nil {
+		return t.keyAttrs, nil
+	}
+	for _, gsi := range t.description.GlobalSecondaryIndexes {
+		if aws.ToString(gsi.IndexName) == *name {
+			return schemaAttrs(gsi.KeySchema), nil
+		}
+	}
+	for _, lsi := range t.description.LocalSecondaryIndexes {
+		if aws.ToString(lsi.IndexName) == *name {
+			return schemaAttrs(lsi.KeySchema), nil
+		}
+	}
+	return nil, validationError("The table does not have the specified index: %s", *name)
+}
+
+// itemsWith returns the items that have every one of attrs. Items missing
+// an index key attribute do not appear in that index.
+func (t *memTable) itemsWith(attrs []string) []Item {
+	items := make([]Item, 0, len(t.items))
+	for _, item := range t.items {
+		present := true
+		for _, attr := range attrs {
+			if _, ok := item[attr]; !ok {
+				present = false
+				break
+			}
+		}
+		if present {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// attrType returns the type an attribute was defined with
+func (t *memTable) attrType(name string) string {
+	for _, def := range t.description.AttributeDefinitions {
+		if aws.ToString(def.AttributeName) == name {
+			return string(def.AttributeType)
+		}
+	}
+	return ""
+}
+
+// validateKey checks that key holds exactly the table's key attributes
+func (t *memTable) validateKey(key Item) error {
+	if len(key) != len(t.keyAttrs) {
+		return validationError("The provided key element does not match the schema")
+	}
+	for _, attr := range t.keyAttrs {
+		value, ok := key[attr]
+		if !ok || TypeOf(value) != t.attrType(attr) {
+			return validationError("The provided key element does not match the schema")
+		}
+	}
+	return nil
+}
+
+// validateItem checks that item has the table's key attributes and that
+// every key attribute, including index keys, has its defined type
+func (t *memTable) validateItem(item Item) error {
+	for _, attr := range t.keyAttrs {
+		if _, ok := item[attr]; !ok {
+			return validationError("One or more parameter values were invalid: Missing the key %s in the item", attr)
+		}
+	}
+	for _, def := range t.description.AttributeDefinitions {
+		name := aws.ToString(def.AttributeName)
+		if value, ok := item[name]; ok && TypeOf(value) != string(def.AttributeType) {
+			return validationError("One or more parameter values were invalid: Type mismatch for key %s expected: %s actual: %s", name, def.AttributeType, TypeOf(value))
+		}
+	}
+	return nil
+}
+
+// memPage is one page of a Scan or Query
+type memPage struct {
+	items   []Item
+	lastKey Item
+	scanned int32
+}
+
+// readPage reads candidates, which are already in read order, starting
+// after startKey. Limit bounds the number of items read before filtering.
+func (t *memTable) readPage(candidates []Item, order func(a, b Item) int, pageKeyAttrs []string, startKey Item, limit *int32, filter condition, projection []docPath) memPage {
+	if len(startKey) > 0 {
+		skip := 0
+		for skip < len(candidates) && order(candidates[skip], startKey) <= 0 {
+			skip++
+		}
+		candidates = candidates[skip:]
+	}
+
+	var page memPage
+	for i, item := range candidates {
+		if max := aws.ToInt32(limit); max > 0 && page.scanned == max {
+			page.lastKey = KeyOf(candidates[i-1], pageKeyAttrs)
+			break
+		}
+		page.scanned++
+		if filter == nil || filter(item) {
+			page.items = append(page.items, project(item, projection))
+		}
+	}
+	return page
+}
+
+// filterAndProjection parses the optional filter and projection
+// expressions of a read
+func (c *exprContext) filterAndProjection(filterExpr, projectionExpr *string) (condition, []docPath, error) {
+	var filter condition
+	var projection []docPath
+	var err error
+	if filterExpr != nil {
+		if filter, _, err = c.parseCondition(*filterExpr); err != nil {
+			return nil, nil, validationError("Invalid FilterExpression: %v", err)
+		}
+	}
+	if projectionExpr != nil {
+		if projection, err = c.parseProjection(*projectionExpr); err != nil {
+			return nil, nil, validationError("Invalid ProjectionExpression: %v", err)
+		}
+	}
+	return filter, projection, nil
+}
+
+// checkCondition evaluates an optional condition expression against the
+// existing item, which is nil if there is none
+func checkCondition(expr *string, names map[string]string, values map[string]types.AttributeValue, existing Item) error {
+	exprs := newExprContext(names, values)
+	if expr != nil {
+		cond, _, err := exprs.parseCondition(*expr)
+		if err != nil {
+			return validationError("Invalid ConditionExpression: %v", err)
+		}
+		if err := exprs.checkUnused(); err != nil {
+			return validationError("%v", err)
+		}
+		if !cond(orEmpty(existing)) {
+			return conditionFailed()
+		}
+		return nil
+	}
+	if err := exprs.checkUnused(); err != nil {
+		return validationError("%v", err)
+	}
+	return nil
+}
+
+// project copies the projected paths of item, or all of it when there is
+// no projection
+func project(item Item, projection []docPath) Item {
+	if len(projection) == 0 {
+		return copyItem(item)
+	}
+	result := Item{}
+	for _, path := range projection {
+		value := path.get(item)
+		if value == nil {
+			continue
+		}
+		// Build the maps and lists leading to the value, keeping any that
+		// an earlier path already created
+		var parent types.AttributeValue = &types.AttributeValueMemberM{Value: result}
+		for i, step := range path {
+			var child types.AttributeValue
+			switch {
+			case i == len(path)-1:
+				child = copyValue(value)
+			case path[i+1].isIndex:
+				child = &types.AttributeValueMemberL{}
+			default:
+				child = &types.AttributeValueMemberM{Value: Item{}}
+			}
+			switch p := parent.(type) {
+			case *types.AttributeValueMemberM:
+				if existing, ok := p.Value[step.name]; ok && i < len(path)-1 {
+					child = existing
+				} else {
+					p.Value[step.name] = child
+				}
+			case *types.AttributeValueMemberL:
+				p.Value = append(p.Value, child)
+			}
+			parent = child
+		}
+	}
+	return result
+}
+
+// compareOn orders items by the given attributes in turn
+func compareOn(attrs []string) func(a, b Item) int {
+	return func(a, b Item) int {
+		for _, attr := range attrs {
+			if c, ok := compareValues(a[attr], b[attr]); ok && c != 0 {
+				return c
+			}
+		}
+		return 0
+	}
+}
+
+// itemKey identifies an item by the values of its key attributes
+func itemKey(item Item, keyAttrs []string) string {
+	parts := make([]string, len(keyAttrs))
+	for i, attr := range keyAttrs {
+		switch v := item[attr].(type) {
+		case *types.AttributeValueMemberS:
+			parts[i] = "S:" + v.Value
+		case *types.AttributeValueMemberN:
+			if n, ok := numberOf(v); ok {
+				parts[i] = "N:" + formatNumber(n)
+			}
+		case *types.AttributeValueMemberB:
+			parts[i] = "B:" + base64.StdEncoding.EncodeToString(v.Value)
+		}
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// schemaAttrs lists the attributes of a key schema, partition key first
+func schemaAttrs(schema []types.KeySchemaElement) []string {
+	var attrs []string
+	for _, keyType := range []types.KeyType{types.KeyTypeHash, types.KeyTypeRange} {
+		for _, key := range schema {
+			if key.KeyType == keyType {
+				attrs = append(attrs, aws.ToString(key.AttributeName))
+			}
+		}
+	}
+	return attrs
+}
+
+// appendMissing appends the elements of extra that are not already in attrs
+func appendMissing(attrs, extra []string) []string {
+	result := append([]string{}, attrs...)
+	for _, attr := range extra {
+		found := false
+		for _, existing := range result {
+			if existing == attr {
+				found = true
+				break
+			}
+		}
+		if !found {
+			result = append(result, attr)
+		}
+	}
+	return result
+}
+
+func orEmpty(item Item) Item {
+	if item == nil {
+		return Item{}
+	}
+	return item
+}
+
+// copyItem deep copies an item so stored items never alias caller data
+func copyItem(item Item) Item {
+	if item == nil {
+		return nil
+	}
+	result := make(Item, len(item))
+	for name, value := range item {
+		result[name] = copyValue(value)
+	}
+	return result
+}
+
+func copyValue(av types.AttributeValue) types.AttributeValue {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberS:
+		return &types.AttributeValueMemberS{Value: v.Value}
+	case *types.AttributeValueMemberN:
+		return &types.AttributeValueMemberN{Value: v.Value}
+	case *types.AttributeValueMemberB:
+		return &types.AttributeValueMemberB{Value: append([]byte{}, v.Value...)}
+	case *types.AttributeValueMemberBOOL:
+		return &types.AttributeValueMemberBOOL{Value: v.Value}
+	case *types.AttributeValueMemberNULL:
+		return &types.AttributeValueMemberNULL{Value: v.Value}
+	case *types.AttributeValueMemberSS:
+		return &types.AttributeValueMemberSS{Value: append([]string{}, v.Value...)}
+	case *types.AttributeValueMemberNS:
+		return &types.AttributeValueMemberNS{Value: append([]string{}, v.Value...)}
+	case *types.AttributeValueMemberBS:
+		values := make([][]byte, len(v.Value))
+		for i, b := range v.Value {
+			values[i] = append([]byte{}, b...)
+		}
+		return &types.AttributeValueMemberBS{Value: values}
+	case *types.AttributeValueMemberL:
+		values := make([]types.AttributeValue, len(v.Value))
+		for i, elem := range v.Value {
+			values[i] = copyValue(elem)
+		}
+		return &types.AttributeValueMemberL{Value: values}
+	case *types.AttributeValueMemberM:
+		return &types.AttributeValueMemberM{Value: copyItem(v.Value)}
+	default:
+		return av
+	}
+}
+
+func validationError(format string, args ...interface{}) error {
+	return &smithy.GenericAPIError{Code: "ValidationException", Message: fmt.Sprintf(format, args...)}
+}
+
+func conditionFailed() error {
+	return &types.ConditionalCheckFailedException{Message: aws.String("The conditional request failed")}
+}
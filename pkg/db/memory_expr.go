@@ -0,0 +1,916 @@
+package db
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// This file implements the expression language used by MemoryBackend:
+// condition, filter and key condition expressions, projection expressions
+// and update expressions. Expressions are compiled into closures that are
+// evaluated against stored items.
+
+type tokenKind int
+
+const (
+	tokEOF    tokenKind = iota
+	tokIdent            // attribute name, keyword or function name
+	tokName             // #name placeholder
+	tokValue            // :value placeholder
+	tokNumber           // list index
+	tokPunct            // operators and punctuation
+)
+
+type token struct {
+	kind tokenKind
+	text string
+}
+
+// tokenize splits an expression into tokens
+func tokenize(expr string) ([]token, error) {
+	var tokens []token
+	for i := 0; i < len(expr); {
+		c := expr[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || c == ':' || isIdentByte(c):
+			start := i
+			i++
+			for i < len(expr) && isIdentByte(expr[i]) {
+				i++
+			}
+			text := expr[start:i]
+			kind := tokIdent
+			switch {
+			case c == '#':
+				kind = tokName
+			case c == ':':
+				kind = tokValue
+			case c >= '0' && c <= '9':
+				if _, err := strconv.Atoi(text); err != nil {
+					return nil, fmt.Errorf("invalid token %q", text)
+				}
+				kind = tokNumber
+			}
+			if len(text) == 1 && kind != tokIdent && kind != tokNumber {
+				return nil, fmt.Errorf("empty placeholder at position %d", start)
+			}
+			tokens = append(tokens, token{kind: kind, text: text})
+		case strings.HasPrefix(expr[i:], "<>"), strings.HasPrefix(expr[i:], "<="), strings.HasPrefix(expr[i:], ">="):
+			tokens = append(tokens, token{kind: tokPunct, text: expr[i : i+2]})
+			i += 2
+		case strings.IndexByte("()[],.=<>+-", c) >= 0:
+			tokens = append(tokens, token{kind: tokPunct, text: expr[i : i+1]})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	return append(tokens, token{kind: tokEOF}), nil
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
+
+// pathStep is one element of a document path: a map key or a list index
+type pathStep struct {
+	name    string
+	index   int
+	isIndex bool
+}
+
+// docPath addresses an attribute, possibly nested inside maps and lists
+type docPath []pathStep
+
+// get returns the value at the path, or nil if it does not exist
+func (p docPath) get(item Item) types.AttributeValue {
+	var current types.AttributeValue = &types.AttributeValueMemberM{Value: item}
+	for _, step := range p {
+		switch v := current.(type) {
+		case *types.AttributeValueMemberM:
+			if step.isIndex {
+				return nil
+			}
+			current = v.Value[step.name]
+		case *types.AttributeValueMemberL:
+			if !step.isIndex || step.index >= len(v.Value) {
+				return nil
+			}
+			current = v.Value[step.index]
+		default:
+			return nil
+		}
+		if current == nil {
+			return nil
+		}
+	}
+	return current
+}
+
+// set stores value at the path. Every element but the last must exist.
+func (p docPath) set(item Item, value types.AttributeValue) error {
+	parent := p[:len(p)-1].get(item)
+	last := p[len(p)-1]
+	switch v := parent.(type) {
+	case *types.AttributeValueMemberM:
+		if last.isIndex {
+			return errInvalidPath
+		}
+		v.Value[last.name] = value
+	case *types.AttributeValueMemberL:
+		if !last.isIndex {
+			return errInvalidPath
+		}
+		if last.index < len(v.Value) {
+			v.Value[last.index] = value
+		} else {
+			v.Value = append(v.Value, value)
+		}
+	default:
+		return errInvalidPath
+	}
+	return nil
+}
+
+// remove deletes the value at the path if it exists
+func (p docPath) remove(item Item) {
+	parent := p[:len(p)-1].get(item)
+	last := p[len(p)-1]
+	switch v := parent.(type) {
+	case *types.AttributeValueMemberM:
+		delete(v.Value, last.name)
+	case *types.AttributeValueMemberL:
+		if last.isIndex && last.index < len(v.Value) {
+			v.Value = append(v.Value[:last.index], v.Value[last.index+1:]...)
+		}
+	}
+}
+
+var errInvalidPath = fmt.Errorf("the document path provided in the update expression is invalid for update")
+
+// operand evaluates to a value, or nil when it refers to a missing attribute
+type operand func(item Item) types.AttributeValue
+
+// condition evaluates a condition, filter or key condition expression
+type condition func(item Item) bool
+
+// exprContext holds the placeholders of one request, which may be shared by
+// several expressions, and records which of them were used
+type exprContext struct {
+	names      map[string]string
+	values     map[string]types.AttributeValue
+	usedNames  map[string]bool
+	usedValues map[string]bool
+}
+
+func newExprContext(names map[string]string, values map[string]types.AttributeValue) *exprContext {
+	return &exprContext{
+		names:      names,
+		values:     values,
+		usedNames:  map[string]bool{},
+		usedValues: map[string]bool{},
+	}
+}
+
+// checkUnused rejects placeholders that no expression referred to, as
+// DynamoDB does
+func (c *exprContext) checkUnused() error {
+	for name := range c.names {
+		if !c.usedNames[name] {
+			return fmt.Errorf("value provided in ExpressionAttributeNames unused in expressions: keys: {%s}", name)
+		}
+	}
+	for name := range c.values {
+		if !c.usedValues[name] {
+			return fmt.Errorf("value provided in ExpressionAttributeValues unused in expressions: keys: {%s}", name)
+		}
+	}
+	return nil
+}
+
+// exprParser is a recursive descent parser over the tokens of one expression
+type exprParser struct {
+	ctx    *exprContext
+	tokens []token
+	pos    int
+	attrs  map[string]bool // top-level attributes referenced
+}
+
+func (c *exprContext) parser(expr string) (*exprParser, error) {
+	tokens, err := tokenize(expr)
+	if err != nil {
+		return nil, err
+	}
+	return &exprParser{ctx: c, tokens: tokens, attrs: map[string]bool{}}, nil
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) peekAt(offset int) token {
+	if p.pos+offset >= len(p.tokens) {
+		return token{kind: tokEOF}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *exprParser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// punct consumes the punctuation s if it is next
+func (p *exprParser) punct(s string) bool {
+	if t := p.peek(); t.kind == tokPunct && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// keyword consumes the case-insensitive keyword word if it is next
+func (p *exprParser) keyword(word string) bool {
+	if t := p.peek(); t.kind == tokIdent && strings.EqualFold(t.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) expect(s string) error {
+	if !p.punct(s) {
+		return p.unexpected(fmt.Sprintf("%q", s))
+	}
+	return nil
+}
+
+func (p *exprParser) unexpected(want string) error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return fmt.Errorf("syntax error: expected %s at end of expression", want)
+	}
+	return fmt.Errorf("syntax error: expected %s, got %q", want, t.text)
+}
+
+// end fails unless the whole expression was consumed
+func (p *exprParser) end() error {
+	if p.peek().kind != tokEOF {
+		return p.unexpected("end of expression")
+	}
+	return nil
+}
+
+// isCall reports whether the next tokens are a call of the function name
+func (p *exprParser) isCall(name string) bool {
+	t, open := p.peek(), p.peekAt(1)
+	return t.kind == tokIdent && t.text == name && open.kind == tokPunct && open.text == "("
+}
+
+func (p *exprParser) pathName() (string, error) {
+	t := p.next()
+	switch t.kind {
+	case tokName:
+		name, ok := p.ctx.names[t.text]
+		if !ok {
+			return "", fmt.Errorf("an expression attribute name used in the document path is not defined: %s", t.text)
+		}
+		p.ctx.usedNames[t.text] = true
+		return name, nil
+	case tokIdent:
+		return t.text, nil
+	default:
+		p.pos--
+		return "", p.unexpected("an attribute name")
+	}
+}
+
+func (p *exprParser) path() (docPath, error) {
+	name, err := p.pathName()
+	if err != nil {
+		return nil, err
+	}
+	p.attrs[name] = true
+	path := docPath{{name: name}}
+	for {
+		switch {
+		case p.punct("."):
+			name, err := p.pathName()
+			if err != nil {
+				return nil, err
+			}
+			path = append(path, pathStep{name: name})
+		case p.punct("["):
+			t := p.next()
+			if t.kind != tokNumber {
+				p.pos--
+				return nil, p.unexpected("a list index")
+			}
+			index, _ := strconv.Atoi(t.text)
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			path = append(path, pathStep{index: index, isIndex: true})
+		default:
+			return path, nil
+		}
+	}
+}
+
+func (p *exprParser) value() (types.AttributeValue, error) {
+	t := p.next()
+	if t.kind != tokValue {
+		p.pos--
+		return nil, p.unexpected("a value placeholder")
+	}
+	av, ok := p.ctx.values[t.text]
+	if !ok {
+		return nil, fmt.Errorf("an expression attribute value used in expression is not defined: %s", t.text)
+	}
+	p.ctx.usedValues[t.text] = true
+	return av, nil
+}
+
+// operand parses a value placeholder, a document path or size(path)
+func (p *exprParser) operand() (operand, error) {
+	switch {
+	case p.peek().kind == tokValue:
+		av, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		return func(Item) types.AttributeValue { return av }, nil
+	case p.isCall("size"):
+		p.pos += 2
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(item Item) types.AttributeValue { return sizeOf(path.get(item)) }, nil
+	default:
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		return path.get, nil
+	}
+}
+
+// condition parses OR-separated terms
+func (p *exprParser) condition() (condition, error) {
+	left, err := p.andCondition()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("OR") {
+		right, err := p.andCondition()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(item Item) bool { return l(item) || right(item) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) andCondition() (condition, error) {
+	left, err := p.notCondition()
+	if err != nil {
+		return nil, err
+	}
+	for p.keyword("AND") {
+		right, err := p.notCondition()
+		if err != nil {
+			return nil, err
+		}
+		l := left
+		left = func(item Item) bool { return l(item) && right(item) }
+	}
+	return left, nil
+}
+
+func (p *exprParser) notCondition() (condition, error) {
+	if p.keyword("NOT") {
+		inner, err := p.notCondition()
+		if err != nil {
+			return nil, err
+		}
+		return func(item Item) bool { return !inner(item) }, nil
+	}
+	return p.primaryCondition()
+}
+
+func (p *exprParser) primaryCondition() (condition, error) {
+	if p.punct("(") {
+		inner, err := p.condition()
+		if err != nil {
+			return nil, err
+		}
+		return inner, p.expect(")")
+	}
+
+	if t := p.peek(); t.kind == tokIdent && !p.isCall("size") && p.peekAt(1).text == "(" && p.peekAt(1).kind == tokPunct {
+		return p.function()
+	}
+
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case p.keyword("BETWEEN"):
+		low, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		if !p.keyword("AND") {
+			return nil, p.unexpected("AND")
+		}
+		high, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return func(item Item) bool {
+			v := left(item)
+			lo, okLow := compareValues(v, low(item))
+			hi, okHigh := compareValues(v, high(item))
+			return okLow && okHigh && lo >= 0 && hi <= 0
+		}, nil
+	case p.keyword("IN"):
+		if err := p.expect("("); err != nil {
+			return nil, err
+		}
+		var candidates []operand
+		for {
+			candidate, err := p.operand()
+			if err != nil {
+				return nil, err
+			}
+			candidates = append(candidates, candidate)
+			if !p.punct(",") {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(item Item) bool {
+			v := left(item)
+			for _, candidate := range candidates {
+				if equalValues(v, candidate(item)) {
+					return true
+				}
+			}
+			return false
+		}, nil
+	}
+
+	op := p.next()
+	if op.kind != tokPunct || !isComparator(op.text) {
+		p.pos--
+		return nil, p.unexpected("a comparison operator")
+	}
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return comparison(op.text, left, right), nil
+}
+
+func isComparator(op string) bool {
+	switch op {
+	case "=", "<>", "<", "<=", ">", ">=":
+		return true
+	}
+	return false
+}
+
+func comparison(op string, left, right operand) condition {
+	switch op {
+	case "=":
+		return func(item Item) bool { return equalValues(left(item), right(item)) }
+	case "<>":
+		return func(item Item) bool { return !equalValues(left(item), right(item)) }
+	}
+	return func(item Item) bool {
+		c, ok := compareValues(left(item), right(item))
+		if !ok {
+			return false
+		}
+		switch op {
+		case "<":
+			return c < 0
+		case "<=":
+			return c <= 0
+		case ">":
+			return c > 0
+		default:
+			return c >= 0
+		}
+	}
+}
+
+// function parses a condition function call
+func (p *exprParser) function() (condition, error) {
+	name := p.next().text
+	p.next() // (
+
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+
+	var cond condition
+	switch name {
+	case "attribute_exists":
+		cond = func(item Item) bool { return path.get(item) != nil }
+	case "attribute_not_exists":
+		cond = func(item Item) bool { return path.get(item) == nil }
+	case "attribute_type":
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		want, err := p.value()
+		if err != nil {
+			return nil, err
+		}
+		s, ok := want.(*types.AttributeValueMemberS)
+		if !ok {
+			return nil, fmt.Errorf("attribute_type expects a string type name")
+		}
+		cond = func(item Item) bool {
+			v := path.get(item)
+			return v != nil && TypeOf(v) == s.Value
+		}
+	case "begins_with", "contains":
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		arg, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		match := beginsWith
+		if name == "contains" {
+			match = contains
+		}
+		cond = func(item Item) bool { return match(path.get(item), arg(item)) }
+	default:
+		return nil, fmt.Errorf("invalid function name: %s", name)
+	}
+
+	return cond, p.expect(")")
+}
+
+// parseCondition compiles a complete condition expression
+func (c *exprContext) parseCondition(expr string) (condition, map[string]bool, error) {
+	p, err := c.parser(expr)
+	if err != nil {
+		return nil, nil, err
+	}
+	cond, err := p.condition()
+	if err != nil {
+		return nil, nil, err
+	}
+	return cond, p.attrs, p.end()
+}
+
+// parseProjection compiles a comma separated list of document paths
+func (c *exprContext) parseProjection(expr string) ([]docPath, error) {
+	p, err := c.parser(expr)
+	if err != nil {
+		return nil, err
+	}
+	var paths []docPath
+	for {
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, path)
+		if !p.punct(",") {
+			break
+		}
+	}
+	return paths, p.end()
+}
+
+// updateAction is one SET or REMOVE of an update expression. value is nil
+// for REMOVE.
+type updateAction struct {
+	path  docPath
+	value func(item Item) (types.AttributeValue, error)
+}
+
+// parseUpdate compiles an update expression with SET and REMOVE clauses
+func (c *exprContext) parseUpdate(expr string) ([]updateAction, error) {
+	p, err := c.parser(expr)
+	if err != nil {
+		return nil, err
+	}
+
+	var actions []updateAction
+	seen := map[string]bool{}
+	for p.peek().kind != tokEOF {
+		clause := strings.ToUpper(p.next().text)
+		if seen[clause] {
+			return nil, fmt.Errorf("the %s section can only be used once in an update expression", clause)
+		}
+		seen[clause] = true
+
+		switch clause {
+		case "SET":
+			for {
+				path, err := p.path()
+				if err != nil {
+					return nil, err
+				}
+				if err := p.expect("="); err != nil {
+					return nil, err
+				}
+				value, err := p.setValue()
+				if err != nil {
+					return nil, err
+				}
+				actions = append(actions, updateAction{path: path, value: value})
+				if !p.punct(",") {
+					break
+				}
+			}
+		case "REMOVE":
+			for {
+				path, err := p.path()
+				if err != nil {
+					return nil, err
+				}
+				actions = append(actions, updateAction{path: path})
+				if !p.punct(",") {
+					break
+				}
+			}
+		default:
+			return nil, fmt.Errorf("unsupported update expression clause %q", clause)
+		}
+	}
+
+	if len(actions) == 0 {
+		return nil, fmt.Errorf("the update expression is empty")
+	}
+	return actions, nil
+}
+
+// setValue parses the right hand side of a SET action
+func (p *exprParser) setValue() (func(Item) (types.AttributeValue, error), error) {
+	left, err := p.setOperand()
+	if err != nil {
+		return nil, err
+	}
+	var sign int64
+	switch {
+	case p.punct("+"):
+		sign = 1
+	case p.punct("-"):
+		sign = -1
+	default:
+		return left, nil
+	}
+	right, err := p.setOperand()
+	if err != nil {
+		return nil, err
+	}
+	return func(item Item) (types.AttributeValue, error) {
+		a, err := left(item)
+		if err != nil {
+			return nil, err
+		}
+		b, err := right(item)
+		if err != nil {
+			return nil, err
+		}
+		x, okA := numberOf(a)
+		y, okB := numberOf(b)
+		if !okA || !okB {
+			return nil, fmt.Errorf("an operand in the update expression has an incorrect data type")
+		}
+		result := new(big.Rat).Mul(y, big.NewRat(sign, 1))
+		return &types.AttributeValueMemberN{Value: formatNumber(result.Add(x, result))}, nil
+	}, nil
+}
+
+func (p *exprParser) setOperand() (func(Item) (types.AttributeValue, error), error) {
+	switch {
+	case p.isCall("if_not_exists"):
+		p.pos += 2
+		path, err := p.path()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		fallback, err := p.setOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(item Item) (types.AttributeValue, error) {
+			if v := path.get(item); v != nil {
+				return v, nil
+			}
+			return fallback(item)
+		}, nil
+	case p.isCall("list_append"):
+		p.pos += 2
+		first, err := p.setOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+		second, err := p.setOperand()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+		return func(item Item) (types.AttributeValue, error) {
+			a, err := first(item)
+			if err != nil {
+				return nil, err
+			}
+			b, err := second(item)
+			if err != nil {
+				return nil, err
+			}
+			la, okA := a.(*types.AttributeValueMemberL)
+			lb, okB := b.(*types.AttributeValueMemberL)
+			if !okA || !okB {
+				return nil, fmt.Errorf("an operand in the update expression has an incorrect data type")
+			}
+			joined := append(append([]types.AttributeValue{}, la.Value...), lb.Value...)
+			return &types.AttributeValueMemberL{Value: joined}, nil
+		}, nil
+	default:
+		op, err := p.operand()
+		if err != nil {
+			return nil, err
+		}
+		return func(item Item) (types.AttributeValue, error) {
+			v := op(item)
+			if v == nil {
+				return nil, fmt.Errorf("the provided expression refers to an attribute that does not exist in the item")
+			}
+			return v, nil
+		}, nil
+	}
+}
+
+// numberOf parses an N value
+func numberOf(av types.AttributeValue) (*big.Rat, bool) {
+	n, ok := av.(*types.AttributeValueMemberN)
+	if !ok {
+		return nil, false
+	}
+	return new(big.Rat).SetString(n.Value)
+}
+
+// formatNumber renders a number with as few decimal places as it needs
+func formatNumber(r *big.Rat) string {
+	if r.IsInt() {
+		return r.Num().String()
+	}
+	for digits := 1; digits < 38; digits++ {
+		s := r.FloatString(digits)
+		if back, ok := new(big.Rat).SetString(s); ok && back.Cmp(r) == 0 {
+			return s
+		}
+	}
+	return r.FloatString(38)
+}
+
+// compareValues orders two scalar values of the same type. ok is false if
+// the values cannot be compared.
+func compareValues(a, b types.AttributeValue) (c int, ok bool) {
+	switch x := a.(type) {
+	case *types.AttributeValueMemberS:
+		if y, ok := b.(*types.AttributeValueMemberS); ok {
+			return strings.Compare(x.Value, y.Value), true
+		}
+	case *types.AttributeValueMemberN:
+		rx, okX := numberOf(x)
+		ry, okY := numberOf(b)
+		if okX && okY {
+			return rx.Cmp(ry), true
+		}
+	case *types.AttributeValueMemberB:
+		if y, ok := b.(*types.AttributeValueMemberB); ok {
+			return bytes.Compare(x.Value, y.Value), true
+		}
+	}
+	return 0, false
+}
+
+// equalValues reports whether two values are equal. Missing values are
+// never equal to anything.
+func equalValues(a, b types.AttributeValue) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	if c, ok := compareValues(a, b); ok {
+		return c == 0
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func beginsWith(v, prefix types.AttributeValue) bool {
+	switch x := v.(type) {
+	case *types.AttributeValueMemberS:
+		p, ok := prefix.(*types.AttributeValueMemberS)
+		return ok && strings.HasPrefix(x.Value, p.Value)
+	case *types.AttributeValueMemberB:
+		p, ok := prefix.(*types.AttributeValueMemberB)
+		return ok && bytes.HasPrefix(x.Value, p.Value)
+	}
+	return false
+}
+
+func contains(v, elem types.AttributeValue) bool {
+	switch x := v.(type) {
+	case *types.AttributeValueMemberS:
+		s, ok := elem.(*types.AttributeValueMemberS)
+		return ok && strings.Contains(x.Value, s.Value)
+	case *types.AttributeValueMemberB:
+		b, ok := elem.(*types.AttributeValueMemberB)
+		return ok && bytes.Contains(x.Value, b.Value)
+	case *types.AttributeValueMemberSS:
+		for _, s := range x.Value {
+			if equalValues(&types.AttributeValueMemberS{Value: s}, elem) {
+				return true
+			}
+		}
+	case *types.AttributeValueMemberNS:
+		for _, n := range x.Value {
+			if equalValues(&types.AttributeValueMemberN{Value: n}, elem) {
+				return true
+			}
+		}
+	case *types.AttributeValueMemberBS:
+		for _, b := range x.Value {
+			if equalValues(&types.AttributeValueMemberB{Value: b}, elem) {
+				return true
+			}
+		}
+	case *types.AttributeValueMemberL:
+		for _, e := range x.Value {
+			if equalValues(e, elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sizeOf implements size(): the length of a string or binary value, or the
+// number of elements of a set, list or map
+func sizeOf(v types.AttributeValue) types.AttributeValue {
+	n := -1
+	switch x := v.(type) {
+	case *types.AttributeValueMemberS:
+		n = len(x.Value)
+	case *types.AttributeValueMemberB:
+		n = len(x.Value)
+	case *types.AttributeValueMemberSS:
+		n = len(x.Value)
+	case *types.AttributeValueMemberNS:
+		n = len(x.Value)
+	case *types.AttributeValueMemberBS:
+		n = len(x.Value)
+	case *types.AttributeValueMemberL:
+		n = len(x.Value)
+	case *types.AttributeValueMemberM:
+		n = len(x.Value)
+	}
+	if n < 0 {
+		return nil
+	}
+	return &types.AttributeValueMemberN{Value: strconv.Itoa(n)}
+}
@@ -0,0 +1,858 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
+)
+
+// This file implements the PartiQL subset that DynamoDB supports for
+// MemoryBackend: SELECT, INSERT, UPDATE and DELETE. Statements are
+// translated into the equivalent Scan, PutItem, UpdateItem and DeleteItem
+// requests, with WHERE clauses and SET values rewritten as expressions.
+
+type pqKind int
+
+const (
+	pqEOF    pqKind = iota
+	pqIdent         // keyword, function or attribute name
+	pqQuoted        // "quoted" table or attribute name
+	pqString        // 'string' literal
+	pqNumber        // number literal
+	pqParam         // ? parameter
+	pqPunct         // operators and punctuation
+)
+
+type pqToken struct {
+	kind pqKind
+	text string
+}
+
+// lexPartiQL splits a statement into tokens. A trailing semicolon is
+// dropped.
+func lexPartiQL(statement string) ([]pqToken, error) {
+	var tokens []pqToken
+	for i := 0; i < len(statement); {
+		c := statement[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '\'' || c == '"':
+			// Quotes are escaped by doubling them
+			var text strings.Builder
+			j := i + 1
+			for {
+				if j >= len(statement) {
+					return nil, fmt.Errorf("unterminated %c at position %d", c, i)
+				}
+				if statement[j] == c {
+					if j+1 < len(statement) && statement[j+1] == c {
+						text.WriteByte(c)
+						j += 2
+						continue
+					}
+					break
+				}
+				text.WriteByte(statement[j])
+				j++
+			}
+			kind := pqString
+			if c == '"' {
+				kind = pqQuoted
+			}
+			tokens = append(tokens, pqToken{kind: kind, text: text.String()})
+			i = j + 1
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(statement) && (statement[i] >= '0' && statement[i] <= '9' || statement[i] == '.') {
+				i++
+			}
+			if i < len(statement) && (statement[i] == 'e' || statement[i] == 'E') {
+				i++
+				if i < len(statement) && (statement[i] == '+' || statement[i] == '-') {
+					i++
+				}
+				for i < len(statement) && statement[i] >= '0' && statement[i] <= '9' {
+					i++
+				}
+			}
+			tokens = append(tokens, pqToken{kind: pqNumber, text: statement[start:i]})
+		case isIdentByte(c):
+			start := i
+			for i < len(statement) && isIdentByte(statement[i]) {
+				i++
+			}
+			tokens = append(tokens, pqToken{kind: pqIdent, text: statement[start:i]})
+		case c == '?':
+			tokens = append(tokens, pqToken{kind: pqParam, text: "?"})
+			i++
+		case strings.HasPrefix(statement[i:], "<<"), strings.HasPrefix(statement[i:], ">>"),
+			strings.HasPrefix(statement[i:], "<>"), strings.HasPrefix(statement[i:], "<="), strings.HasPrefix(statement[i:], ">="):
+			tokens = append(tokens, pqToken{kind: pqPunct, text: statement[i : i+2]})
+			i += 2
+		case strings.HasPrefix(statement[i:], "!="):
+			tokens = append(tokens, pqToken{kind: pqPunct, text: "<>"})
+			i += 2
+		case strings.IndexByte("()[]{},.=<>+-*:;", c) >= 0:
+			tokens = append(tokens, pqToken{kind: pqPunct, text: statement[i : i+1]})
+			i++
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", c, i)
+		}
+	}
+	if n := len(tokens); n > 0 && tokens[n-1].kind == pqPunct && tokens[n-1].text == ";" {
+		tokens = tokens[:n-1]
+	}
+	return append(tokens, pqToken{kind: pqEOF}), nil
+}
+
+// pqParams hands out the values of the ? parameters in order
+type pqParams struct {
+	values []types.AttributeValue
+	next   int
+}
+
+// pqParser is a recursive descent parser over the tokens of a statement
+type pqParser struct {
+	tokens []pqToken
+	pos    int
+	params *pqParams
+}
+
+func (p *pqParser) peek() pqToken {
+	return p.tokens[p.pos]
+}
+
+func (p *pqParser) peekAt(offset int) pqToken {
+	if p.pos+offset >= len(p.tokens) {
+		return pqToken{kind: pqEOF}
+	}
+	return p.tokens[p.pos+offset]
+}
+
+func (p *pqParser) next() pqToken {
+	t := p.tokens[p.pos]
+	if t.kind != pqEOF {
+		p.pos++
+	}
+	return t
+}
+
+// punct consumes the punctuation s if it is next
+func (p *pqParser) punct(s string) bool {
+	if t := p.peek(); t.kind == pqPunct && t.text == s {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// keyword consumes the case-insensitive keyword word if it is next
+func (p *pqParser) keyword(word string) bool {
+	if isKeyword(p.peek(), word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func isKeyword(t pqToken, word string) bool {
+	return t.kind == pqIdent && strings.EqualFold(t.text, word)
+}
+
+func (p *pqParser) expect(s string) error {
+	if !p.punct(s) {
+		return p.unexpected(fmt.Sprintf("%q", s))
+	}
+	return nil
+}
+
+func (p *pqParser) expectKeyword(word string) error {
+	if !p.keyword(word) {
+		return p.unexpected(word)
+	}
+	return nil
+}
+
+func (p *pqParser) unexpected(want string) error {
+	t := p.peek()
+	if t.kind == pqEOF {
+		return fmt.Errorf("syntax error: expected %s at end of statement", want)
+	}
+	return fmt.Errorf("syntax error: expected %s, got %q", want, t.text)
+}
+
+// end fails unless the whole statement was consumed
+func (p *pqParser) end() error {
+	if p.peek().kind != pqEOF {
+		return p.unexpected("end of statement")
+	}
+	return nil
+}
+
+// name parses a table, index or attribute name
+func (p *pqParser) name() (string, error) {
+	t := p.next()
+	if t.kind != pqQuoted && t.kind != pqIdent {
+		p.pos--
+		return "", p.unexpected("a name")
+	}
+	return t.text, nil
+}
+
+// value parses a literal: a string, number, boolean, null, map, list, set
+// or ? parameter
+func (p *pqParser) value() (types.AttributeValue, error) {
+	t := p.next()
+	switch {
+	case t.kind == pqString:
+		return &types.AttributeValueMemberS{Value: t.text}, nil
+	case t.kind == pqNumber:
+		return &types.AttributeValueMemberN{Value: t.text}, nil
+	case t.kind == pqParam:
+		if p.params.next >= len(p.params.values) {
+			return nil, validationError("Number of parameters in request and statement don't match.")
+		}
+		p.params.next++
+		return p.params.values[p.params.next-1], nil
+	case isKeyword(t, "true"), isKeyword(t, "false"):
+		return &types.AttributeValueMemberBOOL{Value: strings.EqualFold(t.text, "true")}, nil
+	case isKeyword(t, "null"):
+		return &types.AttributeValueMemberNULL{Value: true}, nil
+	case t.kind == pqPunct && t.text == "-" && p.peek().kind == pqNumber:
+		return &types.AttributeValueMemberN{Value: "-" + p.next().text}, nil
+	case t.kind == pqPunct && t.text == "{":
+		m := Item{}
+		for !p.punct("}") {
+			if len(m) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			key := p.next()
+			if key.kind != pqString {
+				p.pos--
+				return nil, p.unexpected("an attribute name in single quotes")
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			m[key.text] = v
+		}
+		return &types.AttributeValueMemberM{Value: m}, nil
+	case t.kind == pqPunct && t.text == "[":
+		list := []types.AttributeValue{}
+		for !p.punct("]") {
+			if len(list) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, v)
+		}
+		return &types.AttributeValueMemberL{Value: list}, nil
+	case t.kind == pqPunct && t.text == "<<":
+		var strs, nums []string
+		for !p.punct(">>") {
+			if len(strs)+len(nums) > 0 {
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+			v, err := p.value()
+			if err != nil {
+				return nil, err
+			}
+			switch v := v.(type) {
+			case *types.AttributeValueMemberS:
+				strs = append(strs, v.Value)
+			case *types.AttributeValueMemberN:
+				nums = append(nums, v.Value)
+			default:
+				return nil, errors.New("a set holds only strings or only numbers")
+			}
+		}
+		switch {
+		case len(strs) > 0 && len(nums) == 0:
+			return &types.AttributeValueMemberSS{Value: strs}, nil
+		case len(nums) > 0 && len(strs) == 0:
+			return &types.AttributeValueMemberNS{Value: nums}, nil
+		default:
+			return nil, errors.New("a set holds only strings or only numbers and cannot be empty")
+		}
+	}
+	p.pos--
+	if t.kind == pqEOF {
+		return nil, p.unexpected("a value")
+	}
+	return nil, fmt.Errorf("syntax error: expected a value, got %q", t.text)
+}
+
+// startsValue reports whether t begins a literal. A [ or - only does where
+// an operand is expected.
+func startsValue(t pqToken, operandExpected bool) bool {
+	switch {
+	case t.kind == pqString, t.kind == pqNumber, t.kind == pqParam:
+		return true
+	case isKeyword(t, "true"), isKeyword(t, "false"), isKeyword(t, "null"):
+		return true
+	case t.kind == pqPunct && (t.text == "{" || t.text == "<<"):
+		return true
+	case t.kind == pqPunct && (t.text == "[" || t.text == "-"):
+		return operandExpected
+	}
+	return false
+}
+
+// path parses an attribute path such as "Address".City or Tags[0] into an
+// expression path. top is the name of the top-level attribute.
+func (p *pqParser) path(expr *exprBuilder) (path, top string, err error) {
+	if top, err = p.name(); err != nil {
+		return "", "", err
+	}
+	path = expr.name(top)
+	for {
+		switch {
+		case p.punct("."):
+			name, err := p.name()
+			if err != nil {
+				return "", "", err
+			}
+			path += "." + expr.name(name)
+		case p.punct("["):
+			index := p.next()
+			if index.kind != pqNumber {
+				p.pos--
+				return "", "", p.unexpected("a list index")
+			}
+			if err := p.expect("]"); err != nil {
+				return "", "", err
+			}
+			path += "[" + index.text + "]"
+		default:
+			return path, top, nil
+		}
+	}
+}
+
+// expression translates the tokens up to stop, or the end of the
+// statement, into a condition or update expression. stop is only consulted
+// outside parentheses and never for the AND of a BETWEEN.
+func (p *pqParser) expression(expr *exprBuilder, stop func(pqToken) bool) (string, error) {
+	var out []string
+	depth := 0
+	operandExpected := true
+	between := false
+	lastPath := -1 // Index in out of the last path, for IS MISSING
+	for {
+		t := p.peek()
+		if t.kind == pqEOF {
+			break
+		}
+		if depth == 0 && stop != nil && !(between && isKeyword(t, "AND")) && stop(t) {
+			break
+		}
+		switch {
+		case startsValue(t, operandExpected):
+			v, err := p.value()
+			if err != nil {
+				return "", err
+			}
+			out = append(out, expr.value(v))
+			operandExpected = false
+		case t.kind == pqPunct:
+			p.next()
+			switch t.text {
+			case "(":
+				depth++
+			case ")":
+				depth--
+				if depth < 0 {
+					return "", fmt.Errorf("syntax error: unbalanced %q", ")")
+				}
+			case ",", "=", "<>", "<", "<=", ">", ">=", "+", "-":
+			default:
+				return "", fmt.Errorf("syntax error: unexpected %q", t.text)
+			}
+			out = append(out, t.text)
+			operandExpected = t.text != ")"
+		case isKeyword(t, "AND"), isKeyword(t, "OR"), isKeyword(t, "NOT"), isKeyword(t, "BETWEEN"):
+			p.next()
+			out = append(out, strings.ToUpper(t.text))
+			operandExpected = true
+			if isKeyword(t, "BETWEEN") {
+				between = true
+			} else if isKeyword(t, "AND") {
+				between = false
+			}
+		case isKeyword(t, "IN"):
+			p.next()
+			closing := "]"
+			if !p.punct("[") {
+				if err := p.expect("("); err != nil {
+					return "", err
+				}
+				closing = ")"
+			}
+			var values []string
+			for !p.punct(closing) {
+				if len(values) > 0 {
+					if err := p.expect(","); err != nil {
+						return "", err
+					}
+				}
+				v, err := p.value()
+				if err != nil {
+					return "", err
+				}
+				values = append(values, expr.value(v))
+			}
+			out = append(out, "IN", "("+strings.Join(values, ", ")+")")
+			operandExpected = false
+		case isKeyword(t, "IS"):
+			p.next()
+			if lastPath < 0 || lastPath != len(out)-1 {
+				return "", errors.New("syntax error: IS must follow an attribute")
+			}
+			function := "attribute_exists"
+			if !p.keyword("NOT") {
+				function = "attribute_not_exists"
+			}
+			if err := p.expectKeyword("MISSING"); err != nil {
+				return "", err
+			}
+			out[lastPath] = function + "(" + out[lastPath] + ")"
+			operandExpected = false
+		case t.kind == pqIdent && p.peekAt(1).kind == pqPunct && p.peekAt(1).text == "(":
+			// Functions are case-insensitive in PartiQL
+			p.next()
+			out = append(out, strings.ToLower(t.text))
+			operandExpected = true
+		default:
+			path, _, err := p.path(expr)
+			if err != nil {
+				return "", err
+			}
+			out = append(out, path)
+			lastPath = len(out) - 1
+			operandExpected = false
+		}
+	}
+	if depth != 0 {
+		return "", fmt.Errorf("syntax error: unbalanced %q", "(")
+	}
+	if len(out) == 0 {
+		return "", p.unexpected("an expression")
+	}
+	return strings.Join(out, " "), nil
+}
+
+// keyWhere parses the WHERE clause of an UPDATE or DELETE. It must compare
+// every key attribute with = and may add further conditions with AND.
+func (p *pqParser) keyWhere(expr *exprBuilder, keyAttrs []string) (Item, []string, error) {
+	if err := p.expectKeyword("WHERE"); err != nil {
+		return nil, nil, err
+	}
+	key := Item{}
+	var conditions []string
+	isAnd := func(t pqToken) bool { return isKeyword(t, "AND") }
+	for {
+		// A key equality is a name, = and a value followed by AND or the end
+		start := p.pos
+		if name, err := p.name(); err == nil && p.punct("=") && startsValue(p.peek(), true) && isKeyAttr(name, keyAttrs) {
+			params := p.params.next
+			if v, err := p.value(); err == nil && (p.peek().kind == pqEOF || isAnd(p.peek())) {
+				key[name] = v
+			} else {
+				p.pos, p.params.next = start, params
+			}
+		} else {
+			p.pos = start
+		}
+
+		if p.pos == start {
+			condition, err := p.expression(expr, func(t pqToken) bool { return isAnd(t) || isKeyword(t, "OR") })
+			if err != nil {
+				return nil, nil, err
+			}
+			if isKeyword(p.peek(), "OR") {
+				return nil, nil, errors.New("the WHERE clause must combine its conditions with AND")
+			}
+			conditions = append(conditions, condition)
+		}
+		if !p.keyword("AND") {
+			break
+		}
+	}
+	if err := p.end(); err != nil {
+		return nil, nil, err
+	}
+	for _, attr := range keyAttrs {
+		if _, ok := key[attr]; !ok {
+			return nil, nil, errors.New("the WHERE clause must compare every key attribute with =")
+		}
+	}
+	return key, conditions, nil
+}
+
+func isKeyAttr(name string, keyAttrs []string) bool {
+	for _, attr := range keyAttrs {
+		if attr == name {
+			return true
+		}
+	}
+	return false
+}
+
+// ExecuteStatement runs a PartiQL statement
+func (m *MemoryBackend) ExecuteStatement(ctx context.Context, params *dynamodb.ExecuteStatementInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ExecuteStatementOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	tokens, err := lexPartiQL(aws.ToString(params.Statement))
+	if err != nil {
+		return nil, validationError("Statement wasn't well formed: %v", err)
+	}
+	p := &pqParser{tokens: tokens, params: &pqParams{values: params.Parameters}}
+
+	var out *dynamodb.ExecuteStatementOutput
+	switch {
+	case p.keyword("SELECT"):
+		out, err = m.selectStatement(ctx, p, params)
+	case p.keyword("INSERT"):
+		err = m.insertStatement(ctx, p)
+	case p.keyword("UPDATE"):
+		err = m.updateStatement(ctx, p)
+	case p.keyword("DELETE"):
+		err = m.deleteStatement(ctx, p)
+	default:
+		err = p.unexpected("SELECT, INSERT, UPDATE or DELETE")
+	}
+	if err != nil {
+		var apiErr smithy.APIError
+		if errors.As(err, &apiErr) {
+			return nil, err
+		}
+		return nil, validationError("Statement wasn't well formed: %v", err)
+	}
+	if p.params.next != len(p.params.values) {
+		return nil, validationError("Number of parameters in request and statement don't match.")
+	}
+	if out == nil {
+		out = &dynamodb.ExecuteStatementOutput{Items: []map[string]types.AttributeValue{}}
+	}
+	return out, nil
+}
+
+// selectStatement runs SELECT * | path, ... FROM table[.index] [WHERE ...]
+// as a Scan
+func (m *MemoryBackend) selectStatement(ctx context.Context, p *pqParser, params *dynamodb.ExecuteStatementInput) (*dynamodb.ExecuteStatementOutput, error) {
+	expr := newExprBuilder()
+	var projection []string
+	if !p.punct("*") {
+		for {
+			path, _, err := p.path(expr)
+			if err != nil {
+				return nil, err
+			}
+			projection = append(projection, path)
+			if !p.punct(",") {
+				break
+			}
+		}
+	}
+	if err := p.expectKeyword("FROM"); err != nil {
+		return nil, err
+	}
+	table, err := p.name()
+	if err != nil {
+		return nil, err
+	}
+	input := &dynamodb.ScanInput{TableName: aws.String(table), Limit: params.Limit}
+	if p.punct(".") {
+		index, err := p.name()
+		if err != nil {
+			return nil, err
+		}
+		input.IndexName = aws.String(index)
+	}
+	if p.keyword("WHERE") {
+		filter, err := p.expression(expr, nil)
+		if err != nil {
+			return nil, err
+		}
+		input.FilterExpression = aws.String(filter)
+	}
+	if err := p.end(); err != nil {
+		return nil, err
+	}
+	if len(projection) > 0 {
+		input.ProjectionExpression = aws.String(strings.Join(projection, ", "))
+	}
+	input.ExpressionAttributeNames = expr.attributeNames()
+	input.ExpressionAttributeValues = expr.attributeValues()
+	if params.NextToken != nil {
+		if input.ExclusiveStartKey, err = decodeNextToken(*params.NextToken); err != nil {
+			return nil, validationError("Invalid NextToken")
+		}
+	}
+
+	resp, err := m.Scan(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+	out := &dynamodb.ExecuteStatementOutput{Items: resp.Items}
+	if len(resp.LastEvaluatedKey) > 0 {
+		out.NextToken = aws.String(encodeNextToken(resp.LastEvaluatedKey))
+	}
+	return out, nil
+}
+
+// insertStatement runs INSERT INTO table VALUE {...}, which fails if an
+// item with the same key exists
+func (m *MemoryBackend) insertStatement(ctx context.Context, p *pqParser) error {
+	if err := p.expectKeyword("INTO"); err != nil {
+		return err
+	}
+	table, err := p.name()
+	if err != nil {
+		return err
+	}
+	if err := p.expectKeyword("VALUE"); err != nil {
+		return err
+	}
+	value, err := p.value()
+	if err != nil {
+		return err
+	}
+	item, ok := value.(*types.AttributeValueMemberM)
+	if !ok {
+		return errors.New("INSERT takes a single item in { }")
+	}
+	if err := p.end(); err != nil {
+		return err
+	}
+	keyAttrs, err := m.keyAttrs(table)
+	if err != nil {
+		return err
+	}
+
+	expr := newExprBuilder()
+	_, err = m.PutItem(ctx, &dynamodb.PutItemInput{
+		TableName:                aws.String(table),
+		Item:                     item.Value,
+		ConditionExpression:      aws.String(fmt.Sprintf("attribute_not_exists(%s)", expr.name(keyAttrs[0]))),
+		ExpressionAttributeNames: expr.attributeNames(),
+	})
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return &types.DuplicateItemException{Message: aws.String("Duplicate primary key exists in table")}
+	}
+	return err
+}
+
+// updateStatement runs UPDATE table SET path = value ... REMOVE path ...
+// WHERE key, which fails if the item does not exist
+func (m *MemoryBackend) updateStatement(ctx context.Context, p *pqParser) error {
+	table, err := p.name()
+	if err != nil {
+		return err
+	}
+	keyAttrs, err := m.keyAttrs(table)
+	if err != nil {
+		return err
+	}
+
+	expr := newExprBuilder()
+	var sets, removes []string
+	endOfValue := func(t pqToken) bool {
+		return t.kind == pqPunct && t.text == "," || isKeyword(t, "SET") || isKeyword(t, "REMOVE") || isKeyword(t, "WHERE")
+	}
+	for {
+		switch {
+		case p.keyword("SET"):
+			for {
+				path, _, err := p.path(expr)
+				if err != nil {
+					return err
+				}
+				if err := p.expect("="); err != nil {
+					return err
+				}
+				value, err := p.expression(expr, endOfValue)
+				if err != nil {
+					return err
+				}
+				sets = append(sets, path+" = "+value)
+				if !p.punct(",") {
+					break
+				}
+			}
+			continue
+		case p.keyword("REMOVE"):
+			for {
+				path, _, err := p.path(expr)
+				if err != nil {
+					return err
+				}
+				removes = append(removes, path)
+				if !p.punct(",") {
+					break
+				}
+			}
+			continue
+		}
+		break
+	}
+	if len(sets)+len(removes) == 0 {
+		return p.unexpected("SET or REMOVE")
+	}
+
+	key, conditions, err := p.keyWhere(expr, keyAttrs)
+	if err != nil {
+		return err
+	}
+	conditions = append([]string{fmt.Sprintf("attribute_exists(%s)", expr.name(keyAttrs[0]))}, conditions...)
+	var update []string
+	if len(sets) > 0 {
+		update = append(update, "SET "+strings.Join(sets, ", "))
+	}
+	if len(removes) > 0 {
+		update = append(update, "REMOVE "+strings.Join(removes, ", "))
+	}
+
+	_, err = m.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(table),
+		Key:                       key,
+		UpdateExpression:          aws.String(strings.Join(update, " ")),
+		ConditionExpression:       aws.String(strings.Join(conditions, " AND ")),
+		ExpressionAttributeNames:  expr.attributeNames(),
+		ExpressionAttributeValues: expr.attributeValues(),
+	})
+	return err
+}
+
+// deleteStatement runs DELETE FROM table WHERE key
+func (m *MemoryBackend) deleteStatement(ctx context.Context, p *pqParser) error {
+	if err := p.expectKeyword("FROM"); err != nil {
+		return err
+	}
+	table, err := p.name()
+	if err != nil {
+		return err
+	}
+	keyAttrs, err := m.keyAttrs(table)
+	if err != nil {
+		return err
+	}
+
+	expr := newExprBuilder()
+	key, conditions, err := p.keyWhere(expr, keyAttrs)
+	if err != nil {
+		return err
+	}
+	input := &dynamodb.DeleteItemInput{TableName: aws.String(table), Key: key}
+	if len(conditions) > 0 {
+		input.ConditionExpression = aws.String(strings.Join(conditions, " AND "))
+		input.ExpressionAttributeNames = expr.attributeNames()
+		input.ExpressionAttributeValues = expr.attributeValues()
+	}
+	_, err = m.DeleteItem(ctx, input)
+	return err
+}
+
+// BatchExecuteStatement runs up to 25 statements, all reads or all writes.
+// Each statement succeeds or fails on its own; a read returns the first
+// matching item.
+func (m *MemoryBackend) BatchExecuteStatement(ctx context.Context, params *dynamodb.BatchExecuteStatementInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchExecuteStatementOutput, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if n := len(params.Statements); n == 0 || n > maxBatchStatements {
+		return nil, validationError("1 validation error detected: Value at 'statements' failed to satisfy constraint: Member must have length between 1 and %d", maxBatchStatements)
+	}
+	reads := 0
+	for _, statement := range params.Statements {
+		if verb, _ := DescribeStatement(aws.ToString(statement.Statement)); verb == "SELECT" {
+			reads++
+		}
+	}
+	if reads > 0 && reads < len(params.Statements) {
+		return nil, validationError("Batch statements must all be reads or all be writes")
+	}
+
+	out := &dynamodb.BatchExecuteStatementOutput{}
+	for _, statement := range params.Statements {
+		_, table := DescribeStatement(aws.ToString(statement.Statement))
+		response := types.BatchStatementResponse{TableName: aws.String(table)}
+		resp, err := m.ExecuteStatement(ctx, &dynamodb.ExecuteStatementInput{
+			Statement:  statement.Statement,
+			Parameters: statement.Parameters,
+		})
+		switch {
+		case err != nil:
+			response.Error = batchStatementError(err)
+		case len(resp.Items) > 0:
+			response.Item = resp.Items[0]
+		}
+		out.Responses = append(out.Responses, response)
+	}
+	return out, nil
+}
+
+// batchStatementError reports the error of one statement of a batch the
+// way DynamoDB does, with a code such as ConditionalCheckFailed
+func batchStatementError(err error) *types.BatchStatementError {
+	code := "InternalServerError"
+	message := err.Error()
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		code = strings.TrimSuffix(apiErr.ErrorCode(), "Exception")
+		if code == "Validation" {
+			code = "ValidationError"
+		}
+		message = apiErr.ErrorMessage()
+	}
+	return &types.BatchStatementError{Code: types.BatchStatementErrorCodeEnum(code), Message: aws.String(message)}
+}
+
+// keyAttrs returns the key attributes of a table
+func (m *MemoryBackend) keyAttrs(table string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, err := m.table(&table)
+	if err != nil {
+		return nil, err
+	}
+	return t.keyAttrs, nil
+}
+
+// encodeNextToken turns the last key of a page into an opaque NextToken
+func encodeNextToken(key Item) string {
+	data, _ := json.Marshal(ItemToDynamoJSON(key))
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeNextToken(token string) (Item, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, err
+	}
+	return ItemFromDynamoJSON(data)
+}
@@ -0,0 +1,191 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestMemoryFilterAndProjection(t *testing.T) {
+	backend := NewDemoBackend()
+
+	out, err := backend.Scan(context.Background(), &dynamodb.ScanInput{
+		TableName:            aws.String("Products"),
+		FilterExpression:     aws.String("Price BETWEEN :lo AND :hi AND (contains(Tags, :tag) OR begins_with(#n, :prefix))"),
+		ProjectionExpression: aws.String("ProductID, #n, Tags[1]"),
+		ExpressionAttributeNames: map[string]string{
+			"#n": "Name",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":lo":     num("10"),
+			":hi":     num("50"),
+			":tag":    str("sale"),
+			":prefix": str("Garden"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("Error scanning: %v", err)
+	}
+
+	// Prices are 5.99, 12.99, ... so items 2 to 7 are in range; of those,
+	// items 2 and 5 are on sale and items 3 and 7 are in Garden
+	var ids []string
+	for _, item := range out.Items {
+		ids = append(ids, item["ProductID"].(*types.AttributeValueMemberS).Value)
+		if _, ok := item["Price"]; ok {
+			t.Errorf("Expected Price to be projected away, got %v", item)
+		}
+		if tags, ok := item["Tags"].(*types.AttributeValueMemberL); !ok || len(tags.Value) != 1 {
+			t.Errorf("Expected only the second tag, got %v", item["Tags"])
+		}
+	}
+	want := []string{"prod-002", "prod-003", "prod-005", "prod-007"}
+	if len(ids) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, ids)
+	}
+	for i := range want {
+		if ids[i] != want[i] {
+			t.Errorf("Expected %v, got %v", want, ids)
+			break
+		}
+	}
+	if out.ScannedCount != 12 {
+		t.Errorf("Expected 12 scanned items, got %d", out.ScannedCount)
+	}
+}
+
+func TestMemoryUpdateExpression(t *testing.T) {
+	backend := NewDemoBackend()
+	key := Item{"ProductID": str("prod-001")}
+
+	out, err := backend.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		TableName:        aws.String("Products"),
+		Key:              key,
+		UpdateExpression: aws.String("SET Stock = Stock - :one, Tags = list_append(Tags, :more), Rating = if_not_exists(Rating, :zero) REMOVE #n"),
+		ExpressionAttributeNames: map[string]string{
+			"#n": "Name",
+		},
+		ExpressionAttributeValues: map[string]types.AttributeValue{
+			":one":  num("1.5"),
+			":more": &types.AttributeValueMemberL{Value: []types.AttributeValue{str("clearance")}},
+			":zero": num("0"),
+		},
+		ReturnValues: types.ReturnValueAllNew,
+	})
+	if err != nil {
+		t.Fatalf("Error updating: %v", err)
+	}
+
+	item := out.Attributes
+	if stock := item["Stock"].(*types.AttributeValueMemberN).Value; stock != "-1.5" {
+		t.Errorf("Expected Stock -1.5, got %s", stock)
+	}
+	if tags := item["Tags"].(*types.AttributeValueMemberL).Value; len(tags) != 3 {
+		t.Errorf("Expected 3 tags, got %d", len(tags))
+	}
+	if _, ok := item["Rating"]; !ok {
+		t.Error("Expected Rating to be set")
+	}
+	if _, ok := item["Name"]; ok {
+		t.Error("Expected Name to be removed")
+	}
+
+	_, err = backend.UpdateItem(context.Background(), &dynamodb.UpdateItemInput{
+		TableName:                 aws.String("Products"),
+		Key:                       key,
+		UpdateExpression:          aws.String("SET ProductID = :id"),
+		ExpressionAttributeValues: map[string]types.AttributeValue{":id": str("prod-999")},
+	})
+	if !IsKind(newError("update item", "Products", err), KindValidation) {
+		t.Errorf("Expected a validation error when updating a key attribute, got %v", err)
+	}
+}
+
+func TestMemoryRejectsBadRequests(t *testing.T) {
+	backend := NewDemoBackend()
+	ctx := context.Background()
+
+	tests := []struct {
+		name string
+		call func() error
+		kind ErrorKind
+	}{
+		{"missing table", func() error {
+			_, err := backend.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String("Missing")})
+			return err
+		}, KindNotFound},
+		{"unused value", func() error {
+			_, err := backend.Scan(ctx, &dynamodb.ScanInput{
+				TableName:                 aws.String("Users"),
+				FilterExpression:          aws.String("Active = :yes"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{":yes": &types.AttributeValueMemberBOOL{Value: true}, ":no": &types.AttributeValueMemberBOOL{}},
+			})
+			return err
+		}, KindValidation},
+		{"syntax error", func() error {
+			_, err := backend.Scan(ctx, &dynamodb.ScanInput{TableName: aws.String("Users"), FilterExpression: aws.String("Active = ")})
+			return err
+		}, KindValidation},
+		{"query without partition key", func() error {
+			_, err := backend.Query(ctx, &dynamodb.QueryInput{
+				TableName:                 aws.String("Orders"),
+				KeyConditionExpression:    aws.String("OrderID = :id"),
+				ExpressionAttributeValues: map[string]types.AttributeValue{":id": str("order-1000")},
+			})
+			return err
+		}, KindValidation},
+		{"wrong key type", func() error {
+			_, err := backend.GetItem(ctx, &dynamodb.GetItemInput{TableName: aws.String("Products"), Key: Item{"ProductID": num("1")}})
+			return err
+		}, KindValidation},
+	}
+
+	for _, tt := range tests {
+		if err := tt.call(); !IsKind(newError(tt.name, "", err), tt.kind) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.kind, err)
+		}
+	}
+}
+
+func TestMemoryBatchDelete(t *testing.T) {
+	client := NewDemoClient(nil)
+
+	page, err := client.Scan(context.Background(), "Orders", 0, nil)
+	if err != nil {
+		t.Fatalf("Error scanning Orders: %v", err)
+	}
+	var keys []Item
+	for _, item := range page.Items {
+		keys = append(keys, KeyOf(item, []string{"CustomerID", "OrderID"}))
+	}
+
+	var last DeleteProgress
+	if err := client.DeleteItems(context.Background(), "Orders", keys, func(p DeleteProgress) { last = p }); err != nil {
+		t.Fatalf("Error deleting orders: %v", err)
+	}
+	if last.Deleted != len(keys) {
+		t.Errorf("Expected %d deleted, got %d", len(keys), last.Deleted)
+	}
+
+	page, err = client.Scan(context.Background(), "Orders", 0, nil)
+	if err != nil {
+		t.Fatalf("Error scanning Orders: %v", err)
+	}
+	if len(page.Items) != 0 {
+		t.Errorf("Expected an empty table, got %d items", len(page.Items))
+	}
+}
+
+func TestMemoryCancelledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewDemoBackend().ListTables(ctx, &dynamodb.ListTablesInput{})
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
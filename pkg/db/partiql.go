@@ -0,0 +1,163 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// maxBatchStatements is the most statements BatchExecuteStatement accepts
+const maxBatchStatements = 25
+
+// Statement is a PartiQL statement with the values of its ? parameters
+type Statement struct {
+	Text       string
+	Parameters []types.AttributeValue
+}
+
+// DescribeStatement returns the verb of a PartiQL statement in upper case,
+// such as SELECT or DELETE, and the table it reads or changes. Both are
+// empty if the statement cannot be tokenized.
+func DescribeStatement(text string) (verb, table string) {
+	tokens, err := lexPartiQL(text)
+	if err != nil || tokens[0].kind != pqIdent {
+		return "", ""
+	}
+	verb = strings.ToUpper(tokens[0].text)
+
+	// The table follows FROM in SELECT and DELETE, INTO in INSERT and the
+	// verb itself in UPDATE
+	after := map[string]string{"SELECT": "FROM", "DELETE": "FROM", "INSERT": "INTO", "UPDATE": "UPDATE"}[verb]
+	for i, t := range tokens[:len(tokens)-1] {
+		if isKeyword(t, after) && (tokens[i+1].kind == pqQuoted || tokens[i+1].kind == pqIdent) {
+			return verb, tokens[i+1].text
+		}
+	}
+	return verb, ""
+}
+
+// SplitStatements splits text at the semicolons between statements,
+// ignoring those in quoted strings and names. Empty statements are dropped.
+func SplitStatements(text string) []string {
+	var statements []string
+	var quote byte
+	start := 0
+	for i := 0; i <= len(text); i++ {
+		switch {
+		case i == len(text) || quote == 0 && text[i] == ';':
+			if statement := strings.TrimSpace(text[start:i]); statement != "" {
+				statements = append(statements, statement)
+			}
+			start = i + 1
+		case quote == 0 && (text[i] == '\'' || text[i] == '"'):
+			quote = text[i]
+		case text[i] == quote:
+			// A doubled quote is an escaped one and closes and reopens
+			quote = 0
+		}
+	}
+	return statements
+}
+
+// ExecuteStatement runs a PartiQL statement. A SELECT returns one page of
+// items; pass the NextToken of the page to read the next one. Other
+// statements return an empty page and are refused on a read-only
+// connection.
+func (d *DynamoClient) ExecuteStatement(ctx context.Context, stmt Statement, limit int32, nextToken string) (*ItemPage, error) {
+	if err := d.ready(); err != nil {
+		return nil, err
+	}
+
+	verb, tableName := DescribeStatement(stmt.Text)
+	timeout := d.timeouts().Scan
+	if verb != "SELECT" {
+		if err := d.writable("execute statement", tableName); err != nil {
+			return nil, err
+		}
+		timeout = d.timeouts().Write
+	}
+
+	input := &dynamodb.ExecuteStatementInput{
+		Statement:  aws.String(stmt.Text),
+		Parameters: stmt.Parameters,
+	}
+	if limit > 0 {
+		input.Limit = aws.Int32(limit)
+	}
+	if nextToken != "" {
+		input.NextToken = aws.String(nextToken)
+	}
+
+	ctx, cancel := d.withTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := d.client.ExecuteStatement(ctx, input)
+	if err != nil {
+		return nil, newError("execute statement", tableName, err)
+	}
+
+	return &ItemPage{
+		Items:            resp.Items,
+		LastEvaluatedKey: resp.LastEvaluatedKey,
+		NextToken:        aws.ToString(resp.NextToken),
+	}, nil
+}
+
+// StatementResult is the outcome of one statement of a batch. Item is the
+// item a SELECT found, if any.
+type StatementResult struct {
+	Item Item
+	Err  error
+}
+
+// BatchExecuteStatement runs up to 25 PartiQL statements in one request.
+// They must all be reads or all be writes; each succeeds or fails on its
+// own, so the results hold an error per statement.
+func (d *DynamoClient) BatchExecuteStatement(ctx context.Context, stmts []Statement) ([]StatementResult, error) {
+	if err := d.ready(); err != nil {
+		return nil, err
+	}
+	if len(stmts) == 0 || len(stmts) > maxBatchStatements {
+		return nil, fmt.Errorf("a batch holds 1 to %d statements, got %d", maxBatchStatements, len(stmts))
+	}
+
+	input := &dynamodb.BatchExecuteStatementInput{}
+	timeout := d.timeouts().Scan
+	for _, stmt := range stmts {
+		if verb, tableName := DescribeStatement(stmt.Text); verb != "SELECT" {
+			if err := d.writable("batch execute statement", tableName); err != nil {
+				return nil, err
+			}
+			timeout = d.timeouts().Write
+		}
+		input.Statements = append(input.Statements, types.BatchStatementRequest{
+			Statement:  aws.String(stmt.Text),
+			Parameters: stmt.Parameters,
+		})
+	}
+
+	ctx, cancel := d.withTimeout(ctx, timeout)
+	defer cancel()
+
+	resp, err := d.client.BatchExecuteStatement(ctx, input)
+	if err != nil {
+		return nil, newError("batch execute statement", "", err)
+	}
+
+	results := make([]StatementResult, len(stmts))
+	for i, response := range resp.Responses {
+		if i >= len(results) {
+			break
+		}
+		results[i].Item = response.Item
+		if response.Error != nil {
+			results[i].Err = errors.New(string(response.Error.Code) + ": " + aws.ToString(response.Error.Message))
+		}
+	}
+	return results, nil
+}
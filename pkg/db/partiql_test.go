@@ -0,0 +1,201 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+)
+
+func TestDescribeStatement(t *testing.T) {
+	tests := []struct {
+		text  string
+		verb  string
+		table string
+	}{
+		{`SELECT * FROM "Orders" WHERE CustomerID = 'user-1'`, "SELECT", "Orders"},
+		{`select Name from Products."CategoryPriceIndex"`, "SELECT", "Products"},
+		{`INSERT INTO "Users" VALUE {'UserID': 'u'}`, "INSERT", "Users"},
+		{`update "My Table" set a = 1 where id = 'x'`, "UPDATE", "My Table"},
+		{`DELETE FROM Orders WHERE CustomerID = ?;`, "DELETE", "Orders"},
+		{`EXPLAIN`, "EXPLAIN", ""},
+		{`SELECT * FROM 'unterminated`, "", ""},
+	}
+	for _, tt := range tests {
+		verb, table := DescribeStatement(tt.text)
+		if verb != tt.verb || table != tt.table {
+			t.Errorf("DescribeStatement(%q) = %q, %q, want %q, %q", tt.text, verb, table, tt.verb, tt.table)
+		}
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{`SELECT * FROM Orders`, []string{`SELECT * FROM Orders`}},
+		{`DELETE FROM T WHERE id = 'a'; DELETE FROM T WHERE id = 'b';`, []string{`DELETE FROM T WHERE id = 'a'`, `DELETE FROM T WHERE id = 'b'`}},
+		{`UPDATE T SET note = 'a;b' WHERE id = 'it''s;'; ;`, []string{`UPDATE T SET note = 'a;b' WHERE id = 'it''s;'`}},
+		{`SELECT * FROM "odd;name"`, []string{`SELECT * FROM "odd;name"`}},
+		{` ; `, nil},
+	}
+	for _, tt := range tests {
+		if got := SplitStatements(tt.text); !slices.Equal(got, tt.want) {
+			t.Errorf("SplitStatements(%q) = %q, want %q", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestExecuteSelect(t *testing.T) {
+	client := NewDemoClient(&appconfig.Config{})
+	ctx := context.Background()
+
+	// Status is a reserved word and needs no quoting in PartiQL
+	page, err := client.ExecuteStatement(ctx, Statement{
+		Text:       `SELECT OrderID, "Status", Lines[0].ProductID FROM "Orders" WHERE Status IN ['SHIPPED', 'CANCELLED'] AND Total >= ?`,
+		Parameters: []types.AttributeValue{num("40")},
+	}, 0, "")
+	if err != nil {
+		t.Fatalf("Failed to select: %v", err)
+	}
+	// Totals are 10.50, 13.50, ... so orders 10 to 19 qualify, of which
+	// every other one is shipped or cancelled
+	if len(page.Items) != 5 || page.HasMore() {
+		t.Fatalf("Expected five orders on a single page, got %d: %v", len(page.Items), page.Items)
+	}
+	for _, item := range page.Items {
+		if len(item) != 3 || item["Lines"] == nil {
+			t.Errorf("Expected OrderID, Status and the first product, got %v", item)
+		}
+	}
+
+	// NextToken pages through the results
+	var ids []string
+	token := ""
+	for pages := 0; ; pages++ {
+		page, err := client.ExecuteStatement(ctx, Statement{Text: `SELECT * FROM Products WHERE Tags[1] = 'sale' OR Price < 10`}, 2, token)
+		if err != nil {
+			t.Fatalf("Failed to read page %d: %v", pages, err)
+		}
+		for _, item := range page.Items {
+			ids = append(ids, item["ProductID"].(*types.AttributeValueMemberS).Value)
+		}
+		if !page.HasMore() {
+			break
+		}
+		if pages > 10 {
+			t.Fatal("Expected the pages to end")
+		}
+		token = page.NextToken
+	}
+	want := "prod-001,prod-002,prod-005,prod-008,prod-011"
+	if strings.Join(ids, ",") != want {
+		t.Errorf("Expected %s, got %v", want, ids)
+	}
+
+	page, err = client.ExecuteStatement(ctx, Statement{Text: `SELECT * FROM "Users"."UsernameIndex" WHERE Roles IS MISSING`}, 0, "")
+	if err != nil || len(page.Items) != 4 {
+		t.Errorf("Expected the four users without roles, got %v, %v", page, err)
+	}
+}
+
+func TestExecuteWrites(t *testing.T) {
+	client := NewDemoClient(&appconfig.Config{})
+	ctx := context.Background()
+	key := Item{"ProductID": str("prod-100")}
+	exec := func(text string, params ...types.AttributeValue) error {
+		_, err := client.ExecuteStatement(ctx, Statement{Text: text, Parameters: params}, 0, "")
+		return err
+	}
+
+	if err := exec(`INSERT INTO Products VALUE {'ProductID': ?, 'Name': 'It''s new', 'Price': 9.5, 'Tags': <<'a', 'b'>>}`, str("prod-100")); err != nil {
+		t.Fatalf("Failed to insert: %v", err)
+	}
+	var duplicate *types.DuplicateItemException
+	if err := exec(`INSERT INTO Products VALUE {'ProductID': 'prod-100'}`); !errors.As(err, &duplicate) {
+		t.Errorf("Expected a duplicate item error, got %v", err)
+	}
+
+	if err := exec(`UPDATE Products SET Price = Price + 1, Stock = ? REMOVE Tags WHERE ProductID = 'prod-100' AND Price BETWEEN 9 AND 10`, num("3")); err != nil {
+		t.Fatalf("Failed to update: %v", err)
+	}
+	item, err := client.GetItem(ctx, "Products", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ToPlain(item["Price"]) != ToPlain(num("10.5")) || ToPlain(item["Stock"]) != ToPlain(num("3")) || item["Tags"] != nil {
+		t.Errorf("Expected the price, stock and tags to change, got %v", item)
+	}
+	if ToPlain(item["Name"]) != "It's new" {
+		t.Errorf("Expected the escaped quote in the name, got %v", item["Name"])
+	}
+
+	// UPDATE and DELETE need the whole key and an existing item
+	failures := map[string]string{
+		`UPDATE Products SET Stock = 1 WHERE ProductID = 'prod-999'`:              "ConditionalCheckFailed",
+		`UPDATE Products SET Stock = 1 WHERE Price = 10.5`:                        "every key attribute",
+		`UPDATE Products SET Stock = 1 WHERE ProductID = 'prod-100' OR Stock = 1`: "with AND",
+		`DELETE FROM Products WHERE ProductID = 'prod-100' AND Stock > 5`:         "ConditionalCheckFailed",
+		`SELECT * FROM Products WHERE ProductID = ?`:                              "Number of parameters",
+		`DROP TABLE Products`: "SELECT, INSERT, UPDATE or DELETE",
+	}
+	for text, want := range failures {
+		if err := exec(text); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%s: expected an error containing %q, got %v", text, want, err)
+		}
+	}
+
+	if err := exec(`DELETE FROM "Products" WHERE "ProductID" = 'prod-100'`); err != nil {
+		t.Fatalf("Failed to delete: %v", err)
+	}
+	if item, err := client.GetItem(ctx, "Products", key); err != nil || item != nil {
+		t.Errorf("Expected the item to be deleted, got %v, %v", item, err)
+	}
+}
+
+func TestBatchExecuteStatement(t *testing.T) {
+	client := NewDemoClient(&appconfig.Config{})
+	ctx := context.Background()
+
+	results, err := client.BatchExecuteStatement(ctx, []Statement{
+		{Text: `UPDATE Products SET Stock = 0 WHERE ProductID = 'prod-001'`},
+		{Text: `UPDATE Products SET Stock = 0 WHERE ProductID = 'prod-999'`},
+		{Text: `DELETE FROM Products WHERE ProductID = 'prod-002'`},
+	})
+	if err != nil {
+		t.Fatalf("Failed to run the batch: %v", err)
+	}
+	if len(results) != 3 || results[0].Err != nil || results[2].Err != nil {
+		t.Fatalf("Expected the first and last statements to succeed, got %+v", results)
+	}
+	if results[1].Err == nil || !strings.HasPrefix(results[1].Err.Error(), "ConditionalCheckFailed: ") {
+		t.Errorf("Expected the second statement to fail its condition, got %v", results[1].Err)
+	}
+
+	results, err = client.BatchExecuteStatement(ctx, []Statement{
+		{Text: `SELECT * FROM Products WHERE ProductID = 'prod-001'`},
+		{Text: `SELECT * FROM Products WHERE ProductID = 'prod-002'`},
+	})
+	if err != nil || len(results) != 2 {
+		t.Fatalf("Failed to run the reads: %v", err)
+	}
+	if ToPlain(results[0].Item["Stock"]) != ToPlain(num("0")) || results[1].Item != nil {
+		t.Errorf("Expected the updated product and no deleted one, got %+v", results)
+	}
+
+	if _, err := client.BatchExecuteStatement(ctx, []Statement{
+		{Text: `SELECT * FROM Products WHERE ProductID = 'prod-001'`},
+		{Text: `DELETE FROM Products WHERE ProductID = 'prod-001'`},
+	}); err == nil || !strings.Contains(err.Error(), "all be reads or all be writes") {
+		t.Errorf("Expected mixed reads and writes to be rejected, got %v", err)
+	}
+	if _, err := client.BatchExecuteStatement(ctx, make([]Statement, 26)); err == nil {
+		t.Error("Expected a batch of 26 statements to be rejected")
+	}
+}
@@ -0,0 +1,154 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+)
+
+// ErrSingleRegion is returned for another region of a client that can only
+// reach its own, such as one serving demo data or using a custom endpoint
+var ErrSingleRegion = errors.New("the client only reaches its configured region")
+
+// describeConcurrency bounds the DescribeTable calls made at once, across
+// all regions, to find global tables
+const describeConcurrency = 8
+
+// RegionTables are the tables of one region, or the error listing them
+type RegionTables struct {
+	Region string
+	Tables []TableSummary
+	Err    error
+}
+
+// TableSummary names a table and the regions it is replicated to
+type TableSummary struct {
+	Name     string
+	Replicas []string // Other regions of a global table, empty otherwise
+}
+
+// Global reports whether the table is a replica of a global table
+func (t TableSummary) Global() bool {
+	return len(t.Replicas) > 0
+}
+
+// Region returns the region the client sends requests to
+func (d *DynamoClient) Region() string {
+	if d.cfg == nil {
+		return ""
+	}
+	return d.cfg.Region
+}
+
+// InRegion returns a client for region that shares the credentials of d
+func (d *DynamoClient) InRegion(region string) (*DynamoClient, error) {
+	if region == d.Region() {
+		return d, nil
+	}
+	base, ok := d.client.(*dynamodb.Client)
+	if !ok || d.cfg.Endpoint != "" {
+		return nil, fmt.Errorf("%w: cannot use %s", ErrSingleRegion, region)
+	}
+	awsConfig := d.awsConfig.Copy()
+	awsConfig.Region = region
+	return &DynamoClient{
+		client:      dynamodb.New(base.Options(), func(o *dynamodb.Options) { o.Region = region }),
+		cfg:         d.cfg.WithRegion(region),
+		credentials: d.credentials,
+		awsConfig:   awsConfig,
+	}, nil
+}
+
+// ListTablesInRegions lists the tables of each region in parallel. The
+// regions "all" stands for every region enabled for the account. The
+// results are in the order of the regions; a region that cannot be listed
+// has its Err set, so the others are still shown.
+func (d *DynamoClient) ListTablesInRegions(ctx context.Context, regions []string) ([]RegionTables, error) {
+	if slices.Equal(regions, []string{appconfig.AllRegions}) {
+		var err error
+		if regions, err = d.EnabledRegions(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	results := make([]RegionTables, len(regions))
+	limit := make(chan struct{}, describeConcurrency)
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = d.listRegion(ctx, region, limit)
+		}()
+	}
+	wg.Wait()
+	return results, nil
+}
+
+// listRegion lists the tables of region and describes each to find the
+// global tables among them. limit is shared by the regions listed together.
+func (d *DynamoClient) listRegion(ctx context.Context, region string, limit chan struct{}) RegionTables {
+	result := RegionTables{Region: region}
+	client, err := d.InRegion(region)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	names, err := client.ListTables(ctx)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Tables = make([]TableSummary, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		result.Tables[i].Name = name
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			limit <- struct{}{}
+			defer func() { <-limit }()
+			// A table that cannot be described is listed as a regional one
+			if info, err := client.DescribeTable(ctx, name); err == nil {
+				result.Tables[i].Replicas = slices.DeleteFunc(info.Replicas, func(r string) bool { return r == region })
+			}
+		}()
+	}
+	wg.Wait()
+	return result
+}
+
+// EnabledRegions asks EC2 DescribeRegions for the regions enabled for the
+// account. A client that only reaches its own region returns just that.
+func (d *DynamoClient) EnabledRegions(ctx context.Context) ([]string, error) {
+	if _, ok := d.client.(*dynamodb.Client); !ok || d.credentials == nil || d.cfg.Endpoint != "" {
+		return []string{d.Region()}, nil
+	}
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Metadata)
+	defer cancel()
+
+	client := ec2.NewFromConfig(d.awsConfig, func(o *ec2.Options) {
+		if d.cfg.EC2Endpoint != "" {
+			o.BaseEndpoint = aws.String(d.cfg.EC2Endpoint)
+		}
+	})
+	resp, err := client.DescribeRegions(ctx, &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, newError("list enabled regions", "", err)
+	}
+	regions := make([]string, 0, len(resp.Regions))
+	for _, region := range resp.Regions {
+		regions = append(regions, aws.ToString(region.RegionName))
+	}
+	slices.Sort(regions)
+	return regions, nil
+}
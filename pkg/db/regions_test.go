@@ -0,0 +1,195 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+)
+
+// signingRegion matches the region in the credential scope of a signed
+// request
+var signingRegion = regexp.MustCompile(`Credential=[^/]+/[0-9]+/([a-z0-9-]+)/`)
+
+// testCredentials are static credentials for requests to stub servers
+var testCredentials = credentials.NewStaticCredentialsProvider("AKIDTEST", "secret", "")
+
+func TestListTablesInRegions(t *testing.T) {
+	tables := map[string][]string{
+		"us-east-1": {"Orders", "Users"},
+		"eu-west-1": {"Orders"},
+	}
+	// Orders is a global table in both regions
+	replicas := `{"Table":{"TableName":"Orders","Replicas":[{"RegionName":"us-east-1"},{"RegionName":"eu-west-1"}]}}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		match := signingRegion.FindStringSubmatch(r.Header.Get("Authorization"))
+		if match == nil {
+			http.Error(w, "unsigned request", http.StatusBadRequest)
+			return
+		}
+		region := match[1]
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		names, ok := tables[region]
+		if !ok {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"__type":"com.amazonaws.dynamodb.v20120810#AccessDeniedException","message":"not allowed in `+region+`"}`)
+			return
+		}
+		switch r.Header.Get("X-Amz-Target") {
+		case "DynamoDB_20120810.ListTables":
+			json.NewEncoder(w).Encode(map[string][]string{"TableNames": names})
+		case "DynamoDB_20120810.DescribeTable":
+			var input struct{ TableName string }
+			json.NewDecoder(r.Body).Decode(&input)
+			if input.TableName == "Orders" {
+				fmt.Fprint(w, replicas)
+			} else {
+				fmt.Fprintf(w, `{"Table":{"TableName":%q}}`, input.TableName)
+			}
+		default:
+			http.Error(w, "unexpected operation", http.StatusBadRequest)
+		}
+	}))
+	defer server.Close()
+
+	// The stub stands in for the endpoint of every region
+	base := dynamodb.New(dynamodb.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		Credentials:  testCredentials,
+	})
+	client := &DynamoClient{client: base, cfg: &appconfig.Config{Region: "us-east-1"}, credentials: testCredentials}
+
+	results, err := client.ListTablesInRegions(context.Background(), []string{"eu-west-1", "us-east-1", "ap-south-1"})
+	if err != nil {
+		t.Fatalf("Failed to list tables: %v", err)
+	}
+	if len(results) != 3 || results[0].Region != "eu-west-1" || results[1].Region != "us-east-1" || results[2].Region != "ap-south-1" {
+		t.Fatalf("Expected a result per region in the order given, got %+v", results)
+	}
+
+	want := []TableSummary{{Name: "Orders", Replicas: []string{"eu-west-1"}}, {Name: "Users"}}
+	if results[1].Err != nil || len(results[1].Tables) != 2 {
+		t.Fatalf("Expected two tables in us-east-1, got %+v", results[1])
+	}
+	for i, table := range results[1].Tables {
+		if table.Name != want[i].Name || !slices.Equal(table.Replicas, want[i].Replicas) {
+			t.Errorf("Expected %+v, got %+v", want[i], table)
+		}
+	}
+	if !results[1].Tables[0].Global() || results[1].Tables[1].Global() {
+		t.Error("Expected only Orders to be a global table")
+	}
+	if tables := results[0].Tables; len(tables) != 1 || !slices.Equal(tables[0].Replicas, []string{"us-east-1"}) {
+		t.Errorf("Expected the eu-west-1 replica of Orders, got %+v", results[0])
+	}
+
+	// A failing region does not hide the others
+	if results[2].Err == nil || !strings.Contains(results[2].Err.Error(), "not allowed in ap-south-1") {
+		t.Errorf("Expected the error of ap-south-1, got %v", results[2].Err)
+	}
+}
+
+func TestListTablesInRegionsSingleRegion(t *testing.T) {
+	backend := NewMemoryBackend()
+	ctx := context.Background()
+	for _, name := range []string{"Orders", "Users"} {
+		_, err := backend.CreateTable(ctx, &dynamodb.CreateTableInput{
+			TableName:            aws.String(name),
+			KeySchema:            []types.KeySchemaElement{{AttributeName: aws.String("id"), KeyType: types.KeyTypeHash}},
+			AttributeDefinitions: []types.AttributeDefinition{{AttributeName: aws.String("id"), AttributeType: types.ScalarAttributeTypeS}},
+		})
+		if err != nil {
+			t.Fatalf("Failed to create table %s: %v", name, err)
+		}
+	}
+	backend.tables["Orders"].description.Replicas = []types.ReplicaDescription{
+		{RegionName: aws.String("us-west-2")},
+		{RegionName: aws.String("eu-west-1")},
+	}
+	client := NewDynamoClientWithBackend(&appconfig.Config{Region: "us-west-2"}, backend)
+
+	info, err := client.DescribeTable(ctx, "Orders")
+	if err != nil || !slices.Equal(info.Replicas, []string{"us-west-2", "eu-west-1"}) {
+		t.Errorf("Expected the replicas of Orders, got %v, %v", info, err)
+	}
+
+	// The memory backend only serves its own region
+	regions, err := client.EnabledRegions(ctx)
+	if err != nil || !slices.Equal(regions, []string{"us-west-2"}) {
+		t.Errorf("Expected only us-west-2 to be enabled, got %v, %v", regions, err)
+	}
+	results, err := client.ListTablesInRegions(ctx, []string{appconfig.AllRegions})
+	if err != nil || len(results) != 1 || results[0].Region != "us-west-2" || len(results[0].Tables) != 2 {
+		t.Fatalf("Expected the two tables of us-west-2, got %+v, %v", results, err)
+	}
+	if !slices.Equal(results[0].Tables[0].Replicas, []string{"eu-west-1"}) {
+		t.Errorf("Expected Orders to be replicated to eu-west-1, got %v", results[0].Tables[0].Replicas)
+	}
+
+	results, _ = client.ListTablesInRegions(ctx, []string{"us-west-2", "eu-west-1"})
+	if !errors.Is(results[1].Err, ErrSingleRegion) {
+		t.Errorf("Expected ErrSingleRegion for eu-west-1, got %v", results[1].Err)
+	}
+}
+
+func TestEnabledRegions(t *testing.T) {
+	status := http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseForm(); err != nil || r.Form.Get("Action") != "DescribeRegions" {
+			http.Error(w, "unexpected request", http.StatusBadRequest)
+			return
+		}
+		if !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/ec2/aws4_request") {
+			http.Error(w, "request not signed for EC2 in eu-west-1", http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			fmt.Fprint(w, `<Response><Errors><Error><Code>UnauthorizedOperation</Code><Message>You are not authorized to perform this operation.</Message></Error></Errors><RequestID>1</RequestID></Response>`)
+			return
+		}
+		fmt.Fprint(w, `<DescribeRegionsResponse xmlns="http://ec2.amazonaws.com/doc/2016-11-15/">
+  <requestId>1</requestId>
+  <regionInfo>
+    <item><regionName>us-east-1</regionName><regionEndpoint>ec2.us-east-1.amazonaws.com</regionEndpoint></item>
+    <item><regionName>eu-west-1</regionName><regionEndpoint>ec2.eu-west-1.amazonaws.com</regionEndpoint></item>
+  </regionInfo>
+</DescribeRegionsResponse>`)
+	}))
+	defer server.Close()
+
+	client := &DynamoClient{
+		client:      dynamodb.New(dynamodb.Options{Region: "eu-west-1", Credentials: testCredentials}),
+		cfg:         &appconfig.Config{Region: "eu-west-1", EC2Endpoint: server.URL},
+		credentials: testCredentials,
+		awsConfig:   aws.Config{Region: "eu-west-1", Credentials: testCredentials},
+	}
+	regions, err := client.EnabledRegions(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to list enabled regions: %v", err)
+	}
+	if !slices.Equal(regions, []string{"eu-west-1", "us-east-1"}) {
+		t.Errorf("Expected the enabled regions in order, got %v", regions)
+	}
+
+	status = http.StatusForbidden
+	_, err = client.EnabledRegions(context.Background())
+	if !IsKind(err, KindAccessDenied) || !strings.Contains(err.Error(), "UnauthorizedOperation") {
+		t.Errorf("Expected the EC2 access denied error, got %v", err)
+	}
+}
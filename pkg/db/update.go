@@ -0,0 +1,218 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// ErrConditionFailed is returned when a write's condition no longer holds,
+// usually because the item was changed by someone else after it was read
+var ErrConditionFailed = errors.New("the item was changed or removed since it was loaded")
+
+// ErrItemExists is returned when creating an item whose key is already taken
+var ErrItemExists = errors.New("an item with this key already exists")
+
+// ItemUpdate is the minimal change that turns one version of an item into
+// another. Original holds the previous value of every attribute that is set
+// or removed so the write can be made conditional on it.
+type ItemUpdate struct {
+	Key      Item
+	Set      Item
+	Remove   []string
+	Original Item
+}
+
+// DiffItems computes the update from original to updated. Key attributes
+// must be unchanged.
+func DiffItems(original, updated Item, keyAttrs []string) (*ItemUpdate, error) {
+	u := &ItemUpdate{
+		Key:      Item{},
+		Set:      Item{},
+		Original: Item{},
+	}
+
+	isKey := map[string]bool{}
+	for _, key := range keyAttrs {
+		isKey[key] = true
+		if !reflect.DeepEqual(original[key], updated[key]) {
+			return nil, fmt.Errorf("key attribute %s cannot be changed", key)
+		}
+		u.Key[key] = original[key]
+	}
+
+	for _, name := range AttributeNames(updated) {
+		if isKey[name] {
+			continue
+		}
+		if old, ok := original[name]; ok {
+			if reflect.DeepEqual(old, updated[name]) {
+				continue
+			}
+			u.Original[name] = old
+		}
+		u.Set[name] = updated[name]
+	}
+
+	for _, name := range AttributeNames(original) {
+		if _, ok := updated[name]; !ok && !isKey[name] {
+			u.Remove = append(u.Remove, name)
+			u.Original[name] = original[name]
+		}
+	}
+
+	return u, nil
+}
+
+// Empty reports whether the update changes nothing
+func (u *ItemUpdate) Empty() bool {
+	return len(u.Set) == 0 && len(u.Remove) == 0
+}
+
+// Summary describes each change on its own line
+func (u *ItemUpdate) Summary() []string {
+	var lines []string
+	for _, name := range AttributeNames(u.Set) {
+		if old, ok := u.Original[name]; ok {
+			lines = append(lines, fmt.Sprintf("~ %s: %s -> %s", name, summaryValue(old), summaryValue(u.Set[name])))
+		} else {
+			lines = append(lines, fmt.Sprintf("+ %s: %s", name, summaryValue(u.Set[name])))
+		}
+	}
+	for _, name := range u.Remove {
+		lines = append(lines, fmt.Sprintf("- %s: %s", name, summaryValue(u.Original[name])))
+	}
+	return lines
+}
+
+// expressions builds the UpdateExpression and a ConditionExpression that
+// only lets the update through if the item still has its original values
+func (u *ItemUpdate) expressions(expr *exprBuilder) (string, string) {
+	var conditions []string
+	keys := AttributeNames(u.Key)
+	for _, key := range keys {
+		conditions = append(conditions, fmt.Sprintf("attribute_exists(%s)", expr.name(key)))
+	}
+
+	var sets []string
+	for _, name := range AttributeNames(u.Set) {
+		sets = append(sets, fmt.Sprintf("%s = %s", expr.name(name), expr.value(u.Set[name])))
+	}
+	var removes []string
+	for _, name := range u.Remove {
+		removes = append(removes, expr.name(name))
+	}
+
+	changed := append(AttributeNames(u.Set), u.Remove...)
+	sort.Strings(changed)
+	for _, name := range changed {
+		if old, ok := u.Original[name]; ok {
+			conditions = append(conditions, fmt.Sprintf("%s = %s", expr.name(name), expr.value(old)))
+		} else {
+			conditions = append(conditions, fmt.Sprintf("attribute_not_exists(%s)", expr.name(name)))
+		}
+	}
+
+	var update []string
+	if len(sets) > 0 {
+		update = append(update, "SET "+strings.Join(sets, ", "))
+	}
+	if len(removes) > 0 {
+		update = append(update, "REMOVE "+strings.Join(removes, ", "))
+	}
+
+	return strings.Join(update, " "), strings.Join(conditions, " AND ")
+}
+
+// UpdateItem applies an update computed by DiffItems. It fails with
+// ErrConditionFailed if any changed attribute no longer has its original
+// value.
+func (d *DynamoClient) UpdateItem(ctx context.Context, tableName string, u *ItemUpdate) error {
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if err := d.writable("update item", tableName); err != nil {
+		return err
+	}
+	if u.Empty() {
+		return nil
+	}
+
+	expr := newExprBuilder()
+	update, condition := u.expressions(expr)
+
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Write)
+	defer cancel()
+
+	_, err := d.client.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                 aws.String(tableName),
+		Key:                       u.Key,
+		UpdateExpression:          aws.String(update),
+		ConditionExpression:       aws.String(condition),
+		ExpressionAttributeNames:  expr.attributeNames(),
+		ExpressionAttributeValues: expr.attributeValues(),
+	})
+	if err != nil {
+		return writeError("update item", tableName, err, ErrConditionFailed)
+	}
+	return nil
+}
+
+// PutItem writes a complete item. Unless overwrite is set, the write fails
+// with ErrItemExists if an item with the same key already exists.
+func (d *DynamoClient) PutItem(ctx context.Context, tableName string, item Item, keyAttrs []string, overwrite bool) error {
+	if err := d.ready(); err != nil {
+		return err
+	}
+	if err := d.writable("put item", tableName); err != nil {
+		return err
+	}
+
+	input := &dynamodb.PutItemInput{
+		TableName: aws.String(tableName),
+		Item:      item,
+	}
+	if !overwrite && len(keyAttrs) > 0 {
+		expr := newExprBuilder()
+		input.ConditionExpression = aws.String(fmt.Sprintf("attribute_not_exists(%s)", expr.name(keyAttrs[0])))
+		input.ExpressionAttributeNames = expr.attributeNames()
+	}
+
+	ctx, cancel := d.withTimeout(ctx, d.timeouts().Write)
+	defer cancel()
+
+	if _, err := d.client.PutItem(ctx, input); err != nil {
+		return writeError("put item", tableName, err, ErrItemExists)
+	}
+	return nil
+}
+
+// writeError wraps a failed write, translating conditional check failures
+// into conditionErr
+func writeError(op, tableName string, err, conditionErr error) error {
+	var ccf *types.ConditionalCheckFailedException
+	if errors.As(err, &ccf) {
+		return fmt.Errorf("failed to %s in %s: %w", op, tableName, conditionErr)
+	}
+	return newError(op, tableName, err)
+}
+
+// summaryValue renders a value on one line for change summaries
+func summaryValue(av types.AttributeValue) string {
+	data, err := json.Marshal(ToPlain(av))
+	if err != nil {
+		return "?"
+	}
+	if len(data) > 60 {
+		return string(data[:57]) + "..."
+	}
+	return string(data)
+}
@@ -0,0 +1,78 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+func TestDiffItems(t *testing.T) {
+	original := Item{
+		"CustomerID": &types.AttributeValueMemberS{Value: "c-1"},
+		"OrderID":    &types.AttributeValueMemberS{Value: "o-1"},
+		"Status":     &types.AttributeValueMemberS{Value: "PENDING"},
+		"Total":      &types.AttributeValueMemberN{Value: "10"},
+		"Notes":      &types.AttributeValueMemberS{Value: "call first"},
+	}
+	updated := Item{
+		"CustomerID": &types.AttributeValueMemberS{Value: "c-1"},
+		"OrderID":    &types.AttributeValueMemberS{Value: "o-1"},
+		"Status":     &types.AttributeValueMemberS{Value: "SHIPPED"},
+		"Total":      &types.AttributeValueMemberN{Value: "10"},
+		"Carrier":    &types.AttributeValueMemberS{Value: "UPS"},
+	}
+
+	u, err := DiffItems(original, updated, []string{"CustomerID", "OrderID"})
+	if err != nil {
+		t.Fatalf("Error diffing items: %v", err)
+	}
+
+	if len(u.Set) != 2 || u.Set["Status"] == nil || u.Set["Carrier"] == nil {
+		t.Errorf("Expected Status and Carrier to be set, got %v", AttributeNames(u.Set))
+	}
+	if len(u.Remove) != 1 || u.Remove[0] != "Notes" {
+		t.Errorf("Expected Notes to be removed, got %v", u.Remove)
+	}
+
+	update, condition := u.expressions(newExprBuilder())
+	if update != "SET #n2 = :v0, #n3 = :v1 REMOVE #n4" {
+		t.Errorf("Unexpected update expression: %s", update)
+	}
+	for _, want := range []string{"attribute_exists(#n0)", "attribute_exists(#n1)", "attribute_not_exists(#n2)", "#n3 = :v3", "#n4 = :v2"} {
+		if !strings.Contains(condition, want) {
+			t.Errorf("Expected condition to contain %q, got %s", want, condition)
+		}
+	}
+}
+
+func TestDiffItemsRejectsKeyChange(t *testing.T) {
+	original := Item{"UserID": &types.AttributeValueMemberS{Value: "u-1"}}
+	updated := Item{"UserID": &types.AttributeValueMemberS{Value: "u-2"}}
+
+	if _, err := DiffItems(original, updated, []string{"UserID"}); err == nil {
+		t.Error("Expected error when changing a key attribute, got nil")
+	}
+}
+
+func TestItemFromDynamoJSON(t *testing.T) {
+	item := testItem()
+	data := `{"Lines":{"L":[{"M":{"Qty":{"N":"2"},"SKU":{"S":"sku-1"}}}]},"Notes":{"NULL":true},"OrderID":{"S":"o-1"},"Paid":{"BOOL":true},"Tags":{"SS":["gift"]},"Total":{"N":"12.50"}}`
+
+	parsed, err := ItemFromDynamoJSON([]byte(data))
+	if err != nil {
+		t.Fatalf("Error parsing item: %v", err)
+	}
+
+	u, err := DiffItems(item, parsed, []string{"OrderID"})
+	if err != nil {
+		t.Fatalf("Error diffing items: %v", err)
+	}
+	if !u.Empty() {
+		t.Errorf("Expected round trip to produce no changes, got %v", u.Summary())
+	}
+
+	if _, err := ItemFromDynamoJSON([]byte(`{"Total":{"N":"abc"}}`)); err == nil {
+		t.Error("Expected error for invalid number, got nil")
+	}
+}
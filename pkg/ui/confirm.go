@@ -0,0 +1,50 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// tableConfirm asks for the name of the table before a destructive action
+// on a production connection, so a stray key press cannot confirm it
+type tableConfirm struct {
+	tableName string
+	typed     string
+	mismatch  bool
+}
+
+func newTableConfirm(tableName string) *tableConfirm {
+	return &tableConfirm{tableName: tableName}
+}
+
+// update edits the typed name. It reports whether Enter was pressed with
+// the right name.
+func (c *tableConfirm) update(msg tea.KeyMsg) (confirmed bool) {
+	switch msg.Type {
+	case tea.KeyRunes, tea.KeySpace:
+		c.typed += string(msg.Runes)
+		c.mismatch = false
+	case tea.KeyBackspace:
+		if len(c.typed) > 0 {
+			c.typed = c.typed[:len(c.typed)-1]
+		}
+		c.mismatch = false
+	case tea.KeyEnter:
+		c.mismatch = c.typed != c.tableName
+		return !c.mismatch
+	}
+	return false
+}
+
+func (c *tableConfirm) view() string {
+	var b strings.Builder
+	warnStyle := lipgloss.NewStyle().Foreground(colors.err).Bold(true)
+	b.WriteString(warnStyle.Render("This is a production connection.") + "\n")
+	b.WriteString("Type the table name to confirm: " + c.typed + "_\n")
+	if c.mismatch {
+		b.WriteString(lipgloss.NewStyle().Foreground(colors.err).Render("The name does not match "+c.tableName) + "\n")
+	}
+	return b.String()
+}
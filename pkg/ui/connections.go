@@ -0,0 +1,138 @@
+package ui
+
+import (
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// connectionPicker lists the named connections of the config file and
+// switches the client to the chosen one
+type connectionPicker struct {
+	cfg        *appconfig.Config
+	names      []string
+	selected   int
+	switching  bool
+	err        error
+	returnMode viewMode
+}
+
+func newConnectionPicker(cfg *appconfig.Config, returnMode viewMode) *connectionPicker {
+	p := &connectionPicker{cfg: cfg, names: cfg.ConnectionNames(), returnMode: returnMode}
+	for i, name := range p.names {
+		if name == cfg.Connection {
+			p.selected = i
+		}
+	}
+	return p
+}
+
+// update moves the selection
+func (p *connectionPicker) update(key string) {
+	switch key {
+	case "up", "k":
+		if p.selected > 0 {
+			p.selected--
+		}
+	case "down", "j":
+		if p.selected < len(p.names)-1 {
+			p.selected++
+		}
+	}
+}
+
+// connect creates a client for the selected connection
+func (p *connectionPicker) connect() tea.Cmd {
+	p.switching = true
+	p.err = nil
+	name := p.names[p.selected]
+	return func() tea.Msg {
+		cfg, err := p.cfg.WithConnection(name)
+		if err != nil {
+			return connectionSwitchedMsg{picker: p, err: err}
+		}
+		client, err := db.NewDynamoClientWithConfig(cfg)
+		return connectionSwitchedMsg{picker: p, cfg: cfg, client: client, err: err}
+	}
+}
+
+func (p *connectionPicker) view() string {
+	var b strings.Builder
+	mutedStyle := lipgloss.NewStyle().Foreground(colors.muted)
+	for i, name := range p.names {
+		conn := p.cfg.Connections[name]
+		line := name
+		var details []string
+		if conn.Profile != "" {
+			details = append(details, "profile "+conn.Profile)
+		}
+		if conn.Region != "" {
+			details = append(details, conn.Region)
+		}
+		if conn.Endpoint != "" {
+			details = append(details, conn.Endpoint)
+		}
+		details = append(details, conn.Tags()...)
+		line += "  " + mutedStyle.Render(strings.Join(details, " · "))
+		if name == p.cfg.Connection {
+			line += " (current)"
+		}
+		if i == p.selected {
+			b.WriteString("> " + line + "\n")
+		} else {
+			b.WriteString("  " + line + "\n")
+		}
+	}
+	if p.switching {
+		b.WriteString("\nConnecting...\n")
+	}
+	if p.err != nil {
+		b.WriteString("\n" + lipgloss.NewStyle().Foreground(colors.err).Render("Error: "+p.err.Error()) + "\n")
+	}
+	return b.String()
+}
+
+// header names the connection in use, so production is not mistaken for
+// a local database
+func header(cfg *appconfig.Config) string {
+	if cfg == nil {
+		return ""
+	}
+	var parts []string
+	if cfg.Demo {
+		parts = append(parts, "demo data")
+	} else {
+		profile := cfg.Profile
+		if profile == "" {
+			profile = "default"
+		}
+		parts = append(parts, "profile "+profile, cfg.Region)
+		if cfg.Endpoint != "" {
+			parts = append(parts, cfg.Endpoint)
+		}
+	}
+	if cfg.ReadOnly {
+		parts = append(parts, "read-only")
+	}
+	text := strings.Join(parts, " · ")
+	if cfg.Connection != "" {
+		text = cfg.Connection + " (" + text + ")"
+	}
+	text = lipgloss.NewStyle().Bold(true).Foreground(colors.key).Render("Connection: " + text)
+	if cfg.Production {
+		text += " " + lipgloss.NewStyle().Bold(true).Foreground(colors.bannerFg).Background(colors.bannerBg).Render(" PRODUCTION ")
+	}
+	return text
+}
+
+// connectionSwitchedMsg reports the client for a newly chosen connection
+type connectionSwitchedMsg struct {
+	picker *connectionPicker
+	cfg    *appconfig.Config
+	client *db.DynamoClient
+	err    error
+}
@@ -0,0 +1,222 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// maxConsoleEntries bounds how many past results the console shows
+const maxConsoleEntries = 50
+
+// consoleEntry is a statement run in the console and its outcome
+type consoleEntry struct {
+	statement string
+	result    string
+	failed    bool
+}
+
+// partiqlConsole is a prompt for PartiQL statements. Its history lasts for
+// the session.
+type partiqlConsole struct {
+	client     *db.DynamoClient
+	input      string
+	history    []string
+	recall     int // Position in history while recalling, len(history) otherwise
+	entries    []consoleEntry
+	running    bool
+	pending    []string      // Statements waiting for confirm
+	confirm    *tableConfirm // Set while the table name is asked for
+	toConfirm  []string      // Tables to ask for after the one in confirm
+	returnMode viewMode
+}
+
+// Messages
+type statementsDoneMsg struct {
+	console *partiqlConsole
+	entries []consoleEntry
+}
+
+// remember adds the input to the history, skipping repeats of the last
+// statement
+func (c *partiqlConsole) remember() {
+	if n := len(c.history); n == 0 || c.history[n-1] != c.input {
+		c.history = append(c.history, c.input)
+	}
+	c.recall = len(c.history)
+}
+
+// update edits the input and recalls earlier statements
+func (c *partiqlConsole) update(msg tea.KeyMsg) {
+	switch msg.String() {
+	case "up":
+		if c.recall > 0 {
+			c.recall--
+			c.input = c.history[c.recall]
+		}
+		return
+	case "down":
+		if c.recall < len(c.history) {
+			c.recall++
+		}
+		c.input = ""
+		if c.recall < len(c.history) {
+			c.input = c.history[c.recall]
+		}
+		return
+	case "ctrl+u":
+		c.input = ""
+		return
+	}
+
+	switch msg.Type {
+	case tea.KeyRunes:
+		c.input += string(msg.Runes)
+	case tea.KeySpace:
+		c.input += " "
+	case tea.KeyBackspace:
+		if runes := []rune(c.input); len(runes) > 0 {
+			c.input = string(runes[:len(runes)-1])
+		}
+	}
+}
+
+// changedTables returns the tables that the statements other than SELECT
+// change, each once. It fails if the table of one of them cannot be
+// determined.
+func changedTables(statements []string) ([]string, error) {
+	var tables []string
+	for _, statement := range statements {
+		verb, table := db.DescribeStatement(statement)
+		if verb == "SELECT" {
+			continue
+		}
+		if table == "" {
+			return nil, fmt.Errorf("cannot tell which table %q changes", statement)
+		}
+		if !slices.Contains(tables, table) {
+			tables = append(tables, table)
+		}
+	}
+	return tables, nil
+}
+
+// isSelect reports whether statement reads items
+func isSelect(statement string) bool {
+	verb, _ := db.DescribeStatement(statement)
+	return verb == "SELECT"
+}
+
+// browser creates an item browser that pages through the results of a
+// SELECT with NextToken
+func (c *partiqlConsole) browser(statement string) *itemBrowser {
+	client := c.client
+	b := newItemBrowser("PartiQL: "+statement, nil, func(ctx context.Context, after *db.ItemPage) (*db.ItemPage, error) {
+		token := ""
+		if after != nil {
+			token = after.NextToken
+		}
+		return client.ExecuteStatement(ctx, db.Statement{Text: statement}, pageSize(client), token)
+	})
+	b.console = true
+	return b
+}
+
+// run executes statements, one at a time or as a batch when there are
+// several
+func (c *partiqlConsole) run(ctx context.Context, statements []string) tea.Cmd {
+	c.running = true
+	client := c.client
+	return func() tea.Msg {
+		msg := statementsDoneMsg{console: c}
+		if len(statements) == 1 {
+			entry := consoleEntry{statement: statements[0], result: "OK"}
+			if _, err := client.ExecuteStatement(ctx, db.Statement{Text: statements[0]}, 0, ""); err != nil {
+				entry.result, entry.failed = err.Error(), true
+			}
+			msg.entries = append(msg.entries, entry)
+			return msg
+		}
+
+		stmts := make([]db.Statement, len(statements))
+		for i, statement := range statements {
+			stmts[i] = db.Statement{Text: statement}
+		}
+		results, err := client.BatchExecuteStatement(ctx, stmts)
+		if err != nil {
+			msg.entries = append(msg.entries, consoleEntry{statement: strings.Join(statements, "; "), result: err.Error(), failed: true})
+			return msg
+		}
+		for i, result := range results {
+			entry := consoleEntry{statement: statements[i], result: "OK"}
+			switch {
+			case result.Err != nil:
+				entry.result, entry.failed = result.Err.Error(), true
+			case result.Item != nil:
+				entry.result = formatCell(&types.AttributeValueMemberM{Value: result.Item})
+			case isSelect(statements[i]):
+				entry.result = "No item"
+			}
+			msg.entries = append(msg.entries, entry)
+		}
+		return msg
+	}
+}
+
+// finish records the outcome of statements that were run
+func (c *partiqlConsole) finish(entries []consoleEntry) {
+	c.running = false
+	c.add(entries...)
+}
+
+// add appends entries to the output, dropping the oldest beyond
+// maxConsoleEntries
+func (c *partiqlConsole) add(entries ...consoleEntry) {
+	c.entries = append(c.entries, entries...)
+	if extra := len(c.entries) - maxConsoleEntries; extra > 0 {
+		c.entries = c.entries[extra:]
+	}
+}
+
+// view renders the latest results that fit in rows lines, then the prompt
+func (c *partiqlConsole) view(width, rows int) string {
+	promptStyle := lipgloss.NewStyle().Foreground(colors.accent).Bold(true).Render
+	mutedStyle := lipgloss.NewStyle().Foreground(colors.muted).Render
+	errorStyle := lipgloss.NewStyle().Foreground(colors.err).Render
+
+	var lines []string
+	for _, entry := range c.entries {
+		result := mutedStyle(pad(entry.result, max(width-4, 10)))
+		if entry.failed {
+			result = errorStyle(pad("Error: "+entry.result, max(width-4, 10)))
+		}
+		lines = append(lines, promptStyle("> ")+pad(entry.statement, max(width-2, 10)), "  "+result)
+	}
+	if rows > 0 && len(lines) > rows {
+		lines = lines[len(lines)-rows:]
+	}
+
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line + "\n")
+	}
+	if len(lines) > 0 {
+		b.WriteString("\n")
+	}
+	switch {
+	case c.confirm != nil:
+		b.WriteString(c.confirm.view())
+	case c.running:
+		b.WriteString(mutedStyle("Running...") + "\n")
+	default:
+		b.WriteString(promptStyle("> ") + c.input + "█\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,157 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// maxListedKeys bounds how many keys the confirmation dialog lists
+const maxListedKeys = 15
+
+// deleteJob holds the state of a confirmed or pending delete
+type deleteJob struct {
+	tableName string
+	keyAttrs  []string
+	indexes   []int
+	keys      []db.Item
+	confirm   *tableConfirm // Set while the table name is asked for
+	running   bool
+	progress  db.DeleteProgress
+	updates   chan tea.Msg
+}
+
+// Messages
+type deleteProgressMsg struct {
+	job      *deleteJob
+	progress db.DeleteProgress
+}
+
+type deleteDoneMsg struct {
+	job *deleteJob
+	err error
+}
+
+// newDeleteJob prepares to delete the items at indexes in the browser
+func newDeleteJob(table *db.TableInfo, browser *itemBrowser, indexes []int) *deleteJob {
+	job := &deleteJob{
+		tableName: table.TableName,
+		keyAttrs:  keyAttributes(table.KeySchema),
+		indexes:   indexes,
+	}
+	for _, i := range indexes {
+		job.keys = append(job.keys, db.KeyOf(browser.items[i], job.keyAttrs))
+	}
+	job.progress.Total = len(job.keys)
+	return job
+}
+
+// start runs the delete in the background, streaming progress messages
+func (j *deleteJob) start(ctx context.Context, client *db.DynamoClient) tea.Cmd {
+	j.running = true
+	j.updates = make(chan tea.Msg, 16)
+
+	go func() {
+		defer close(j.updates)
+
+		var err error
+		if len(j.keys) == 1 {
+			err = client.DeleteItem(ctx, j.tableName, j.keys[0])
+			if err == nil {
+				j.updates <- deleteProgressMsg{job: j, progress: db.DeleteProgress{Deleted: 1, Total: 1}}
+			}
+		} else {
+			err = client.DeleteItems(ctx, j.tableName, j.keys, func(p db.DeleteProgress) {
+				j.updates <- deleteProgressMsg{job: j, progress: p}
+			})
+		}
+		j.updates <- deleteDoneMsg{job: j, err: err}
+	}()
+
+	return j.wait()
+}
+
+// wait returns a command that delivers the next progress message
+func (j *deleteJob) wait() tea.Cmd {
+	return func() tea.Msg {
+		msg, ok := <-j.updates
+		if !ok {
+			return nil
+		}
+		return msg
+	}
+}
+
+// deletedIndexes returns the browser indexes of the items that are gone
+// after the job finished with err
+func (j *deleteJob) deletedIndexes(err error) []int {
+	if err == nil {
+		return j.indexes
+	}
+
+	var batchErr *db.BatchDeleteError
+	if !errors.As(err, &batchErr) {
+		return nil
+	}
+	remaining := map[string]bool{}
+	for _, key := range batchErr.Remaining {
+		remaining[db.FormatKey(key, j.keyAttrs)] = true
+	}
+	var deleted []int
+	for i, key := range j.keys {
+		if !remaining[db.FormatKey(key, j.keyAttrs)] {
+			deleted = append(deleted, j.indexes[i])
+		}
+	}
+	return deleted
+}
+
+// view renders the confirmation dialog or the progress of a running delete
+func (j *deleteJob) view(width int) string {
+	warnStyle := lipgloss.NewStyle().Foreground(colors.err).Bold(true)
+
+	var sb strings.Builder
+	if j.running {
+		sb.WriteString(fmt.Sprintf("Deleting %d item(s) from %s...\n\n", j.progress.Total, j.tableName))
+		sb.WriteString(progressBar(j.progress.Deleted, j.progress.Total, width-20))
+		sb.WriteString(fmt.Sprintf(" %d/%d\n", j.progress.Deleted, j.progress.Total))
+		if j.progress.Retries > 0 {
+			sb.WriteString(fmt.Sprintf("\nRetried unprocessed items %d time(s)\n", j.progress.Retries))
+		}
+		return sb.String()
+	}
+
+	sb.WriteString(warnStyle.Render(fmt.Sprintf("Delete %d item(s) from %s?", len(j.keys), j.tableName)) + "\n\n")
+	for i, key := range j.keys {
+		if i == maxListedKeys {
+			sb.WriteString(fmt.Sprintf("  ... and %d more\n", len(j.keys)-maxListedKeys))
+			break
+		}
+		sb.WriteString("  " + db.FormatKey(key, j.keyAttrs) + "\n")
+	}
+	sb.WriteString("\nThis cannot be undone.\n")
+	if j.confirm != nil {
+		sb.WriteString("\n" + j.confirm.view())
+	}
+	return sb.String()
+}
+
+// progressBar renders a simple horizontal bar
+func progressBar(done, total, width int) string {
+	if width < 10 {
+		width = 10
+	}
+	if width > 60 {
+		width = 60
+	}
+	filled := 0
+	if total > 0 {
+		filled = done * width / total
+	}
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", width-filled) + "]"
+}
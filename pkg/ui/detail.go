@@ -0,0 +1,333 @@
+package ui
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// detailDisplay selects how the item detail pane renders an item
+type detailDisplay int
+
+const (
+	displayTree detailDisplay = iota
+	displayPlainJSON
+	displayDynamoJSON
+)
+
+func (d detailDisplay) String() string {
+	switch d {
+	case displayPlainJSON:
+		return "Plain JSON"
+	case displayDynamoJSON:
+		return "DynamoDB JSON"
+	default:
+		return "Tree"
+	}
+}
+
+// detailNode is one visible line of the attribute tree
+type detailNode struct {
+	path       string
+	label      string
+	value      types.AttributeValue
+	depth      int
+	expandable bool
+}
+
+// itemDetail holds the state of the single-item detail pane
+type itemDetail struct {
+	title    string
+	item     db.Item
+	keyAttrs []string
+	expanded map[string]bool
+	display  detailDisplay
+	cursor   int
+	offset   int
+}
+
+// newItemDetail creates a detail pane for item with every attribute collapsed
+func newItemDetail(title string, item db.Item, keyAttrs []string) *itemDetail {
+	return &itemDetail{
+		title:    title,
+		item:     item,
+		keyAttrs: keyAttrs,
+		expanded: map[string]bool{},
+	}
+}
+
+// nodes flattens the expanded parts of the item into display lines, with the
+// key attributes first
+func (d *itemDetail) nodes() []detailNode {
+	var names []string
+	seen := map[string]bool{}
+	for _, key := range d.keyAttrs {
+		if _, ok := d.item[key]; ok {
+			names = append(names, key)
+			seen[key] = true
+		}
+	}
+	for _, name := range db.AttributeNames(d.item) {
+		if !seen[name] {
+			names = append(names, name)
+		}
+	}
+
+	var nodes []detailNode
+	for _, name := range names {
+		nodes = d.appendNode(nodes, name, name, d.item[name], 0)
+	}
+	return nodes
+}
+
+func (d *itemDetail) appendNode(nodes []detailNode, path, label string, av types.AttributeValue, depth int) []detailNode {
+	children := childValues(av)
+	nodes = append(nodes, detailNode{
+		path:       path,
+		label:      label,
+		value:      av,
+		depth:      depth,
+		expandable: children != nil,
+	})
+	if children == nil || !d.expanded[path] {
+		return nodes
+	}
+
+	for _, child := range children {
+		childPath := path + "." + child.label
+		if child.index {
+			childPath = fmt.Sprintf("%s[%s]", path, child.label)
+		}
+		nodes = d.appendNode(nodes, childPath, child.label, child.value, depth+1)
+	}
+	return nodes
+}
+
+// childValue is a labelled element of a map, list or set
+type childValue struct {
+	label string
+	index bool
+	value types.AttributeValue
+}
+
+// childValues returns the elements of a container value, or nil for scalars
+func childValues(av types.AttributeValue) []childValue {
+	var children []childValue
+	switch v := av.(type) {
+	case *types.AttributeValueMemberM:
+		keys := make([]string, 0, len(v.Value))
+		for k := range v.Value {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			children = append(children, childValue{label: k, value: v.Value[k]})
+		}
+	case *types.AttributeValueMemberL:
+		for i, elem := range v.Value {
+			children = append(children, childValue{label: fmt.Sprint(i), index: true, value: elem})
+		}
+	case *types.AttributeValueMemberSS:
+		for i, s := range v.Value {
+			children = append(children, childValue{label: fmt.Sprint(i), index: true, value: &types.AttributeValueMemberS{Value: s}})
+		}
+	case *types.AttributeValueMemberNS:
+		for i, n := range v.Value {
+			children = append(children, childValue{label: fmt.Sprint(i), index: true, value: &types.AttributeValueMemberN{Value: n}})
+		}
+	case *types.AttributeValueMemberBS:
+		for i, b := range v.Value {
+			children = append(children, childValue{label: fmt.Sprint(i), index: true, value: &types.AttributeValueMemberB{Value: b}})
+		}
+	default:
+		return nil
+	}
+	if children == nil {
+		children = []childValue{}
+	}
+	return children
+}
+
+// setAll expands or collapses every container in the item
+func (d *itemDetail) setAll(expand bool) {
+	if !expand {
+		d.expanded = map[string]bool{}
+		d.cursor, d.offset = 0, 0
+		return
+	}
+	// Expanding reveals new containers, so repeat until nothing changes
+	for changed := true; changed; {
+		changed = false
+		for _, node := range d.nodes() {
+			if node.expandable && !d.expanded[node.path] {
+				d.expanded[node.path] = true
+				changed = true
+			}
+		}
+	}
+}
+
+// lines returns the rendered lines for the current display mode
+func (d *itemDetail) lines() []string {
+	switch d.display {
+	case displayPlainJSON:
+		return jsonLines(db.ItemToPlain(d.item))
+	case displayDynamoJSON:
+		return jsonLines(db.ItemToDynamoJSON(d.item))
+	}
+
+	typeStyle := lipgloss.NewStyle().Foreground(colors.muted)
+	keyStyle := lipgloss.NewStyle().Foreground(colors.key)
+
+	nodes := d.nodes()
+	lines := make([]string, len(nodes))
+	for i, node := range nodes {
+		marker := "  "
+		if node.expandable {
+			marker = "▸ "
+			if d.expanded[node.path] {
+				marker = "▾ "
+			}
+		}
+
+		label := node.label
+		if node.depth == 0 {
+			for _, key := range d.keyAttrs {
+				if key == node.label {
+					label = keyStyle.Render(label)
+				}
+			}
+		}
+
+		value := formatCell(node.value)
+		if node.expandable {
+			value = summarize(node.value)
+		}
+
+		lines[i] = strings.Repeat("  ", node.depth) + marker + label + " " +
+			typeStyle.Render("("+db.TypeOf(node.value)+")") + ": " + value
+	}
+	return lines
+}
+
+// summarize describes the size of a container value
+func summarize(av types.AttributeValue) string {
+	n := len(childValues(av))
+	switch av.(type) {
+	case *types.AttributeValueMemberM:
+		return fmt.Sprintf("{%d attributes}", n)
+	default:
+		return fmt.Sprintf("[%d elements]", n)
+	}
+}
+
+// jsonLines renders v as indented JSON split into lines
+func jsonLines(v interface{}) []string {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return []string{"Error: " + err.Error()}
+	}
+	return strings.Split(string(data), "\n")
+}
+
+// update handles a key press on the detail pane
+func (d *itemDetail) update(key string, rows int) {
+	lineCount := len(d.lines())
+
+	switch key {
+	case "up", "k":
+		d.cursor--
+	case "down", "j":
+		d.cursor++
+	case "pgup", "ctrl+u":
+		d.cursor -= rows
+	case "pgdown", "ctrl+d":
+		d.cursor += rows
+	case "home", "g":
+		d.cursor = 0
+	case "end", "G":
+		d.cursor = lineCount - 1
+	case "v":
+		d.display = (d.display + 1) % 3
+		d.cursor, d.offset = 0, 0
+	case "+":
+		if d.display == displayTree {
+			d.setAll(true)
+		}
+	case "-":
+		if d.display == displayTree {
+			d.setAll(false)
+		}
+	case "enter", " ", "right", "l", "left", "h":
+		if d.display != displayTree {
+			break
+		}
+		nodes := d.nodes()
+		if d.cursor >= len(nodes) {
+			break
+		}
+		node := nodes[d.cursor]
+		switch key {
+		case "enter", " ":
+			if node.expandable {
+				d.expanded[node.path] = !d.expanded[node.path]
+			}
+		case "right", "l":
+			if node.expandable {
+				d.expanded[node.path] = true
+			}
+		case "left", "h":
+			if node.expandable && d.expanded[node.path] {
+				delete(d.expanded, node.path)
+				break
+			}
+			// Jump to the parent node
+			for i := d.cursor - 1; i >= 0; i-- {
+				if nodes[i].depth < node.depth {
+					d.cursor = i
+					break
+				}
+			}
+		}
+	}
+
+	lineCount = len(d.lines())
+	if d.cursor >= lineCount {
+		d.cursor = lineCount - 1
+	}
+	if d.cursor < 0 {
+		d.cursor = 0
+	}
+	if d.cursor < d.offset {
+		d.offset = d.cursor
+	}
+	if d.cursor >= d.offset+rows {
+		d.offset = d.cursor - rows + 1
+	}
+}
+
+// view renders the visible lines of the detail pane
+func (d *itemDetail) view(rows int) string {
+	cursorStyle := lipgloss.NewStyle().Reverse(true)
+
+	lines := d.lines()
+	end := d.offset + rows
+	if end > len(lines) {
+		end = len(lines)
+	}
+
+	var sb strings.Builder
+	for i := d.offset; i < end; i++ {
+		if i == d.cursor {
+			sb.WriteString("> " + cursorStyle.Render(lines[i]) + "\n")
+		} else {
+			sb.WriteString("  " + lines[i] + "\n")
+		}
+	}
+	return sb.String()
+}
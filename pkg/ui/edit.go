@@ -0,0 +1,205 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// itemEditor holds the state of an item being edited in $EDITOR
+type itemEditor struct {
+	tableName  string
+	keyAttrs   []string
+	original   db.Item // nil when creating a new item
+	text       []byte
+	updated    db.Item
+	changes    *db.ItemUpdate
+	err        error
+	saving     bool
+	confirm    *tableConfirm // Set while the table name is asked for
+	returnMode viewMode
+}
+
+// Messages
+type editorFinishedMsg struct {
+	editor *itemEditor
+	text   []byte
+	err    error
+}
+
+type itemSavedMsg struct {
+	editor *itemEditor
+}
+
+// newItemEditor prepares to edit item, or to create a new item from a
+// template of the table's key attributes when item is nil
+func newItemEditor(table *db.TableInfo, item db.Item) (*itemEditor, error) {
+	e := &itemEditor{
+		tableName: table.TableName,
+		keyAttrs:  keyAttributes(table.KeySchema),
+		original:  item,
+	}
+
+	template := item
+	if template == nil {
+		template = db.Item{}
+		for _, key := range e.keyAttrs {
+			placeholder := ""
+			if table.AttributeDefinitions[key] == "N" {
+				placeholder = "0"
+			}
+			value, err := db.KeyValue(table.AttributeDefinitions[key], placeholder)
+			if err != nil {
+				return nil, err
+			}
+			template[key] = value
+		}
+	}
+
+	text, err := json.MarshalIndent(db.ItemToDynamoJSON(template), "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	e.text = append(text, '\n')
+	return e, nil
+}
+
+// editorCommand returns the user's preferred editor command line
+func editorCommand() []string {
+	for _, env := range []string{"VISUAL", "EDITOR"} {
+		if fields := strings.Fields(os.Getenv(env)); len(fields) > 0 {
+			return fields
+		}
+	}
+	return []string{"vi"}
+}
+
+// open suspends the UI and edits the current text in the external editor
+func (e *itemEditor) open() tea.Cmd {
+	f, err := os.CreateTemp("", "dynamightea-*.json")
+	if err != nil {
+		return func() tea.Msg { return editorFinishedMsg{editor: e, err: err} }
+	}
+	path := f.Name()
+	_, err = f.Write(e.text)
+	f.Close()
+	if err != nil {
+		os.Remove(path)
+		return func() tea.Msg { return editorFinishedMsg{editor: e, err: err} }
+	}
+
+	args := append(editorCommand(), path)
+	cmd := exec.Command(args[0], args[1:]...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		defer os.Remove(path)
+		if err != nil {
+			return editorFinishedMsg{editor: e, err: fmt.Errorf("editor failed: %w", err)}
+		}
+		text, err := os.ReadFile(path)
+		return editorFinishedMsg{editor: e, text: text, err: err}
+	})
+}
+
+// finish parses the edited text and works out what changed
+func (e *itemEditor) finish(text []byte) {
+	e.text = text
+	e.updated = nil
+	e.changes = nil
+	e.err = nil
+
+	item, err := db.ItemFromDynamoJSON(text)
+	if err != nil {
+		e.err = fmt.Errorf("invalid DynamoDB JSON: %w", err)
+		return
+	}
+	e.updated = item
+
+	if e.original == nil {
+		for _, key := range e.keyAttrs {
+			if _, ok := item[key]; !ok {
+				e.err = fmt.Errorf("the new item needs key attribute %s", key)
+				return
+			}
+		}
+		return
+	}
+
+	e.changes, e.err = db.DiffItems(e.original, item, e.keyAttrs)
+}
+
+// canSave reports whether there is a valid change waiting to be saved
+func (e *itemEditor) canSave() bool {
+	if e.err != nil || e.updated == nil || e.saving {
+		return false
+	}
+	return e.original == nil || !e.changes.Empty()
+}
+
+// destructive reports whether saving removes attributes
+func (e *itemEditor) destructive() bool {
+	return e.changes != nil && len(e.changes.Remove) > 0
+}
+
+// save writes the change to DynamoDB
+func (e *itemEditor) save(ctx context.Context, client *db.DynamoClient) tea.Cmd {
+	e.saving = true
+	e.confirm = nil
+	return func() tea.Msg {
+		var err error
+		if e.original == nil {
+			err = client.PutItem(ctx, e.tableName, e.updated, e.keyAttrs, false)
+		} else {
+			err = client.UpdateItem(ctx, e.tableName, e.changes)
+		}
+		if err != nil {
+			return editorFinishedMsg{editor: e, text: e.text, err: err}
+		}
+		return itemSavedMsg{editor: e}
+	}
+}
+
+// view renders the confirmation screen shown after editing
+func (e *itemEditor) view() string {
+	addStyle := lipgloss.NewStyle().Foreground(colors.added)
+	removeStyle := lipgloss.NewStyle().Foreground(colors.err)
+	changeStyle := lipgloss.NewStyle().Foreground(colors.accent)
+
+	var sb strings.Builder
+	switch {
+	case e.saving:
+		sb.WriteString("Saving...\n")
+	case e.err != nil:
+		sb.WriteString(removeStyle.Render("Error: "+e.err.Error()) + "\n")
+	case e.original == nil:
+		sb.WriteString("Create a new item:\n\n")
+		for _, line := range strings.Split(strings.TrimRight(string(e.text), "\n"), "\n") {
+			sb.WriteString("  " + addStyle.Render(line) + "\n")
+		}
+	case e.changes.Empty():
+		sb.WriteString("No changes.\n")
+	default:
+		sb.WriteString("Changes to save:\n\n")
+		for _, line := range e.changes.Summary() {
+			style := changeStyle
+			switch line[0] {
+			case '+':
+				style = addStyle
+			case '-':
+				style = removeStyle
+			}
+			sb.WriteString("  " + style.Render(line) + "\n")
+		}
+		sb.WriteString("\nThe write only succeeds if the changed attributes still have their original values.\n")
+		if e.confirm != nil {
+			sb.WriteString("\n" + e.confirm.view())
+		}
+	}
+	return sb.String()
+}
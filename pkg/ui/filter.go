@@ -0,0 +1,314 @@
+package ui
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// filterRow is one condition of the filter builder
+type filterRow struct {
+	attribute string
+	operator  int // Index into db.FilterOperators
+	value     string
+	or        bool // Joined to the row before with OR instead of AND
+}
+
+// filterPart identifies a kind of input in the filter builder
+type filterPart int
+
+const (
+	partJoin filterPart = iota
+	partAttribute
+	partOperator
+	partValue
+	partAddCondition
+	partProjection
+	partAddAttribute
+)
+
+// filterInput is an input of the filter builder. index is the row of a
+// condition or the attribute of the projection.
+type filterInput struct {
+	part  filterPart
+	index int
+}
+
+// filterForm builds the filter and projection of a Scan or Query. Rows
+// without an attribute are ignored.
+type filterForm struct {
+	rows      []filterRow
+	attrs     []string // Attributes offered for the projection
+	projected map[string]bool
+	newAttr   string
+	focus     int // Index into inputs()
+}
+
+// newFilterForm creates a filter builder with one empty condition. The
+// projection offers the attributes defined on the table besides its key,
+// which is always read.
+func newFilterForm(table *db.TableInfo) *filterForm {
+	f := &filterForm{rows: []filterRow{{}}, projected: map[string]bool{}}
+	keys := keyAttributes(table.KeySchema)
+	for name := range table.AttributeDefinitions {
+		if !slices.Contains(keys, name) {
+			f.attrs = append(f.attrs, name)
+		}
+	}
+	sort.Strings(f.attrs)
+	return f
+}
+
+// inputs lists the inputs in focus order
+func (f *filterForm) inputs() []filterInput {
+	var inputs []filterInput
+	for i, row := range f.rows {
+		if i > 0 {
+			inputs = append(inputs, filterInput{partJoin, i})
+		}
+		inputs = append(inputs, filterInput{partAttribute, i}, filterInput{partOperator, i})
+		if db.FilterOperators[row.operator].TakesValue() {
+			inputs = append(inputs, filterInput{partValue, i})
+		}
+	}
+	inputs = append(inputs, filterInput{part: partAddCondition})
+	for i := range f.attrs {
+		inputs = append(inputs, filterInput{partProjection, i})
+	}
+	return append(inputs, filterInput{part: partAddAttribute})
+}
+
+func (f *filterForm) current() filterInput {
+	inputs := f.inputs()
+	f.focus = min(max(f.focus, 0), len(inputs)-1)
+	return inputs[f.focus]
+}
+
+// moveFocus moves the focus forward or backward. It reports false, without
+// moving, when that would leave the form.
+func (f *filterForm) moveFocus(delta int) bool {
+	next := f.focus + delta
+	if next < 0 || next >= len(f.inputs()) {
+		return false
+	}
+	f.focus = next
+	return true
+}
+
+func (f *filterForm) focusFirst() {
+	f.focus = 0
+}
+
+func (f *filterForm) focusLast() {
+	f.focus = len(f.inputs()) - 1
+}
+
+// focusOn moves the focus to the given input
+func (f *filterForm) focusOn(input filterInput) {
+	for i, in := range f.inputs() {
+		if in == input {
+			f.focus = i
+		}
+	}
+}
+
+// text returns the text input that has focus, if any
+func (f *filterForm) text() *string {
+	in := f.current()
+	switch in.part {
+	case partAttribute:
+		return &f.rows[in.index].attribute
+	case partValue:
+		return &f.rows[in.index].value
+	case partAddAttribute:
+		return &f.newAttr
+	}
+	return nil
+}
+
+// update handles a key press other than focus movement and Enter
+func (f *filterForm) update(msg tea.KeyMsg) {
+	in := f.current()
+	switch msg.String() {
+	case "left", "right":
+		delta := 1
+		if msg.String() == "left" {
+			delta = -1
+		}
+		switch in.part {
+		case partJoin:
+			f.rows[in.index].or = !f.rows[in.index].or
+		case partOperator:
+			n := len(db.FilterOperators)
+			f.rows[in.index].operator = (f.rows[in.index].operator + delta + n) % n
+		}
+		return
+	case "ctrl+x":
+		// Remove the condition with focus, keeping at least one row
+		if in.part <= partValue {
+			f.rows = slices.Delete(f.rows, in.index, in.index+1)
+			if len(f.rows) == 0 {
+				f.rows = []filterRow{{}}
+			}
+			f.focusOn(filterInput{partAttribute, min(in.index, len(f.rows)-1)})
+		}
+		return
+	case " ":
+		if in.part == partProjection {
+			attr := f.attrs[in.index]
+			f.projected[attr] = !f.projected[attr]
+			return
+		}
+	}
+
+	text := f.text()
+	if text == nil {
+		return
+	}
+	switch msg.Type {
+	case tea.KeyRunes:
+		*text += string(msg.Runes)
+	case tea.KeySpace:
+		*text += " "
+	case tea.KeyBackspace:
+		if runes := []rune(*text); len(runes) > 0 {
+			*text = string(runes[:len(runes)-1])
+		}
+	}
+}
+
+// enter handles Enter on the buttons that add a condition or an attribute.
+// It reports false when Enter should run the Scan or Query instead.
+func (f *filterForm) enter() bool {
+	switch f.current().part {
+	case partAddCondition:
+		f.rows = append(f.rows, filterRow{})
+		f.focusOn(filterInput{partAttribute, len(f.rows) - 1})
+		return true
+	case partAddAttribute:
+		attr := strings.TrimSpace(f.newAttr)
+		if attr == "" {
+			return true
+		}
+		if !slices.Contains(f.attrs, attr) {
+			f.attrs = append(f.attrs, attr)
+		}
+		f.projected[attr] = true
+		f.newAttr = ""
+		f.focusLast()
+		return true
+	}
+	return false
+}
+
+// conditions returns the filter conditions of the rows with an attribute
+func (f *filterForm) conditions() ([]db.FilterCondition, error) {
+	var conditions []db.FilterCondition
+	for _, row := range f.rows {
+		attr := strings.TrimSpace(row.attribute)
+		if attr == "" {
+			continue
+		}
+		op := db.FilterOperators[row.operator]
+		values, err := db.ParseFilterValues(op, row.value)
+		if err != nil {
+			return nil, fmt.Errorf("filter on %s: %w", attr, err)
+		}
+		conditions = append(conditions, db.FilterCondition{
+			Attribute: attr,
+			Operator:  op,
+			Values:    values,
+			Or:        row.or && len(conditions) > 0,
+		})
+	}
+	return conditions, nil
+}
+
+// projection returns the attributes to read: keys followed by the picked
+// attributes, or nil for every attribute when none was picked
+func (f *filterForm) projection(keys []string) []string {
+	var picked []string
+	for _, attr := range f.attrs {
+		if f.projected[attr] && !slices.Contains(keys, attr) {
+			picked = append(picked, attr)
+		}
+	}
+	if len(picked) == 0 {
+		return nil
+	}
+	return append(append([]string{}, keys...), picked...)
+}
+
+// active reports whether the form filters or projects anything
+func (f *filterForm) active() bool {
+	for _, row := range f.rows {
+		if strings.TrimSpace(row.attribute) != "" {
+			return true
+		}
+	}
+	return f.projection(nil) != nil
+}
+
+// view renders the form. focused is false while another form has focus.
+func (f *filterForm) view(focused bool) string {
+	sectionStyle := lipgloss.NewStyle().Bold(true)
+	focusStyle := lipgloss.NewStyle().Foreground(colors.accent).Bold(true)
+	mutedStyle := lipgloss.NewStyle().Foreground(colors.muted)
+
+	current := filterInput{part: -1}
+	if focused {
+		current = f.current()
+	}
+	// field renders an input padded to width, or as is when width is 0
+	field := func(in filterInput, value string, width int) string {
+		focused := in == current
+		if focused && (in.part == partAttribute || in.part == partValue || in.part == partAddAttribute) {
+			value += "█"
+		}
+		if width > 0 {
+			value = pad(value, width)
+		}
+		if focused {
+			return focusStyle.Render(value)
+		}
+		return value
+	}
+
+	var sb strings.Builder
+	sb.WriteString(sectionStyle.Render("Filter") + "\n")
+	for i, row := range f.rows {
+		join := pad("", 9)
+		if i > 0 {
+			word := "AND"
+			if row.or {
+				word = "OR"
+			}
+			join = field(filterInput{partJoin, i}, "◀ "+word+" ▶", 9)
+		}
+		op := db.FilterOperators[row.operator]
+		line := "  " + join + " " + field(filterInput{partAttribute, i}, row.attribute, 20) + " " +
+			field(filterInput{partOperator, i}, "◀ "+string(op)+" ▶", 26)
+		if op.TakesValue() {
+			line += " " + field(filterInput{partValue, i}, row.value, 20)
+		}
+		sb.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	sb.WriteString("  " + field(filterInput{part: partAddCondition}, "[+ Add Condition]", 0) + "\n")
+
+	sb.WriteString("\n" + sectionStyle.Render("Projection") + " " + mutedStyle.Render("(all attributes when none is picked; the key is always read)") + "\n")
+	for i, attr := range f.attrs {
+		box := "[ ] "
+		if f.projected[attr] {
+			box = "[x] "
+		}
+		sb.WriteString("  " + field(filterInput{partProjection, i}, box+attr, 0) + "\n")
+	}
+	sb.WriteString("  " + field(filterInput{part: partAddAttribute}, "+ "+f.newAttr, 0) + "\n")
+	return sb.String()
+}
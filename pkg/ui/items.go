@@ -0,0 +1,447 @@
+package ui
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+const (
+	itemPageSize     int32 = 50
+	maxColumnWidth         = 30
+	loadMoreDistance       = 5
+)
+
+// pageFetcher fetches the page of items that follows after, or the first
+// page when after is nil
+type pageFetcher func(ctx context.Context, after *db.ItemPage) (*db.ItemPage, error)
+
+// itemBrowser holds the state of a paged, scrollable item grid
+type itemBrowser struct {
+	title       string
+	keyAttrs    []string
+	columns     []string
+	items       []db.Item
+	cursor      int
+	offset      int
+	colOffset   int
+	last        *db.ItemPage // The page loaded last
+	loadingMore bool
+	stopped     bool
+	fetch       pageFetcher
+	marked      map[int]bool
+	console     bool // Results of the PartiQL console, which cannot be edited
+
+	// ctx bounds every page request; cancelLoad stops the one in flight
+	ctx        context.Context
+	cancelLoad context.CancelFunc
+}
+
+// newItemBrowser creates an empty browser whose key attributes are pinned to
+// the left of the grid
+func newItemBrowser(title string, keyAttrs []string, fetch pageFetcher) *itemBrowser {
+	return &itemBrowser{
+		title:    title,
+		keyAttrs: keyAttrs,
+		columns:  append([]string{}, keyAttrs...),
+		fetch:    fetch,
+		marked:   map[int]bool{},
+		ctx:      context.Background(),
+	}
+}
+
+// keyAttributes lists the partition and sort keys of each schema in order,
+// skipping attributes that were already listed
+func keyAttributes(schemas ...[]db.KeySchemaElement) []string {
+	var attrs []string
+	seen := map[string]bool{}
+	for _, schema := range schemas {
+		for _, name := range []string{db.PartitionKey(schema), db.SortKey(schema)} {
+			if name != "" && !seen[name] {
+				seen[name] = true
+				attrs = append(attrs, name)
+			}
+		}
+	}
+	return attrs
+}
+
+// Messages
+type itemsLoadedMsg struct {
+	browser *itemBrowser
+	page    *db.ItemPage
+}
+
+// retryPageMsg asks for a page that failed to load to be fetched again
+type retryPageMsg struct {
+	browser *itemBrowser
+	after   *db.ItemPage
+}
+
+// loadPage fetches the page that follows after for the browser. A failed
+// page is retried through retryPageMsg, so that every attempt gets its own
+// request context that Esc can cancel.
+func (b *itemBrowser) loadPage(after *db.ItemPage) tea.Cmd {
+	ctx, cancel := context.WithCancel(b.ctx)
+	b.cancelLoad = cancel
+	b.stopped = false
+
+	fetch := b.fetch
+	return func() tea.Msg {
+		defer cancel()
+		page, err := fetch(ctx, after)
+		if err != nil {
+			retry := func() tea.Msg { return retryPageMsg{browser: b, after: after} }
+			return errorMsg{err: err, retry: retry}
+		}
+		return itemsLoadedMsg{browser: b, page: page}
+	}
+}
+
+// stop cancels the page request in flight. It reports whether there was one.
+func (b *itemBrowser) stop() bool {
+	if !b.loadingMore {
+		return false
+	}
+	if b.cancelLoad != nil {
+		b.cancelLoad()
+	}
+	b.loadingMore = false
+	b.stopped = true
+	return true
+}
+
+// addPage appends a page of items and keeps it to load the next one
+func (b *itemBrowser) addPage(page *db.ItemPage) {
+	b.last = page
+	b.loadingMore = false
+	b.appendItems(page.Items)
+}
+
+// appendItems adds items to the grid and refreshes the column set
+func (b *itemBrowser) appendItems(items []db.Item) {
+	b.items = append(b.items, items...)
+
+	pinned := make(map[string]bool, len(b.keyAttrs))
+	for _, key := range b.keyAttrs {
+		pinned[key] = true
+	}
+	seen := map[string]bool{}
+	var others []string
+	for _, item := range b.items {
+		for name := range item {
+			if !pinned[name] && !seen[name] {
+				seen[name] = true
+				others = append(others, name)
+			}
+		}
+	}
+	sort.Strings(others)
+	b.columns = append(append([]string{}, b.keyAttrs...), others...)
+}
+
+// replaceSelected swaps the item under the cursor for an updated version
+func (b *itemBrowser) replaceSelected(item db.Item) {
+	if b.cursor >= 0 && b.cursor < len(b.items) {
+		b.items[b.cursor] = item
+	}
+}
+
+// insert adds a newly created item to the end of the loaded items and moves
+// the cursor to it
+func (b *itemBrowser) insert(item db.Item) {
+	b.appendItems([]db.Item{item})
+	b.cursor = len(b.items) - 1
+}
+
+// toggleMark marks or unmarks the item under the cursor
+func (b *itemBrowser) toggleMark() {
+	if b.selected() == nil {
+		return
+	}
+	if b.marked[b.cursor] {
+		delete(b.marked, b.cursor)
+	} else {
+		b.marked[b.cursor] = true
+	}
+}
+
+// targets returns the indexes of the marked items, or of the item under the
+// cursor when nothing is marked
+func (b *itemBrowser) targets() []int {
+	if len(b.marked) == 0 {
+		if b.selected() == nil {
+			return nil
+		}
+		return []int{b.cursor}
+	}
+	indexes := make([]int, 0, len(b.marked))
+	for i := range b.marked {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes
+}
+
+// remove drops the items at the given indexes and clears all marks
+func (b *itemBrowser) remove(indexes []int) {
+	drop := make(map[int]bool, len(indexes))
+	for _, i := range indexes {
+		drop[i] = true
+	}
+	kept := b.items[:0]
+	for i, item := range b.items {
+		if !drop[i] {
+			kept = append(kept, item)
+		}
+	}
+	b.items = kept
+	b.marked = map[int]bool{}
+	if b.cursor >= len(b.items) {
+		b.cursor = len(b.items) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+	if b.offset > b.cursor {
+		b.offset = b.cursor
+	}
+}
+
+// maybeLoadMore requests the next page when the cursor nears the end of the
+// loaded items
+func (b *itemBrowser) maybeLoadMore() tea.Cmd {
+	if b.loadingMore || !b.hasMore() {
+		return nil
+	}
+	if b.cursor < len(b.items)-loadMoreDistance {
+		return nil
+	}
+	b.loadingMore = true
+	return b.loadPage(b.last)
+}
+
+// hasMore reports whether there is a page after the loaded items
+func (b *itemBrowser) hasMore() bool {
+	return b.last != nil && b.last.HasMore()
+}
+
+// selected returns the item under the cursor, or nil if there is none
+func (b *itemBrowser) selected() db.Item {
+	if b.cursor < 0 || b.cursor >= len(b.items) {
+		return nil
+	}
+	return b.items[b.cursor]
+}
+
+// update handles navigation keys for the grid
+func (b *itemBrowser) update(key string, rows int) tea.Cmd {
+	switch key {
+	case "up", "k":
+		b.cursor--
+	case "down", "j":
+		b.cursor++
+	case "pgup", "ctrl+u":
+		b.cursor -= rows
+	case "pgdown", "ctrl+d":
+		b.cursor += rows
+	case "home", "g":
+		b.cursor = 0
+	case "end", "G":
+		b.cursor = len(b.items) - 1
+	case "left", "h":
+		if b.colOffset > 0 {
+			b.colOffset--
+		}
+	case "right", "l":
+		if b.colOffset < len(b.columns)-len(b.keyAttrs)-1 {
+			b.colOffset++
+		}
+	}
+
+	if b.cursor >= len(b.items) {
+		b.cursor = len(b.items) - 1
+	}
+	if b.cursor < 0 {
+		b.cursor = 0
+	}
+	if b.cursor < b.offset {
+		b.offset = b.cursor
+	}
+	if b.cursor >= b.offset+rows {
+		b.offset = b.cursor - rows + 1
+	}
+
+	return b.maybeLoadMore()
+}
+
+// view renders the grid to fit within width columns and rows item rows
+func (b *itemBrowser) view(width, rows int) string {
+	headerStyle := lipgloss.NewStyle().Bold(true).Underline(true)
+	keyStyle := lipgloss.NewStyle().Foreground(colors.key)
+	cursorStyle := lipgloss.NewStyle().Reverse(true)
+	markStyle := lipgloss.NewStyle().Foreground(colors.mark).Bold(true)
+
+	if len(b.items) == 0 {
+		if b.loadingMore {
+			return "Loading items..."
+		}
+		return "No items found"
+	}
+
+	// Pinned key columns first, then as many of the scrolled columns as fit
+	visible := append([]string{}, b.keyAttrs...)
+	visible = append(visible, b.columns[len(b.keyAttrs)+b.colOffset:]...)
+
+	end := b.offset + rows
+	if end > len(b.items) {
+		end = len(b.items)
+	}
+	page := b.items[b.offset:end]
+
+	widths := make([]int, 0, len(visible))
+	used := 2
+	for i, name := range visible {
+		w := lipgloss.Width(name)
+		for _, item := range page {
+			if cw := lipgloss.Width(formatCell(item[name])); cw > w {
+				w = cw
+			}
+		}
+		if w > maxColumnWidth {
+			w = maxColumnWidth
+		}
+		if i >= len(b.keyAttrs) && used+w+2 > width {
+			break
+		}
+		widths = append(widths, w)
+		used += w + 2
+	}
+	visible = visible[:len(widths)]
+
+	var sb strings.Builder
+	sb.WriteString("  ")
+	for i, name := range visible {
+		sb.WriteString(headerStyle.Render(pad(name, widths[i])) + "  ")
+	}
+	sb.WriteString("\n")
+
+	for i, item := range page {
+		var row strings.Builder
+		for j, name := range visible {
+			cell := pad(formatCell(item[name]), widths[j])
+			if j < len(b.keyAttrs) {
+				cell = keyStyle.Render(cell)
+			}
+			row.WriteString(cell + "  ")
+		}
+		mark := " "
+		if b.marked[b.offset+i] {
+			mark = markStyle.Render("*")
+		}
+		if b.offset+i == b.cursor {
+			sb.WriteString(">" + mark + cursorStyle.Render(row.String()) + "\n")
+		} else {
+			sb.WriteString(" " + mark + row.String() + "\n")
+		}
+	}
+
+	return sb.String()
+}
+
+// status describes the position of the cursor within the loaded items
+func (b *itemBrowser) status() string {
+	s := fmt.Sprintf("Item %d of %d", b.cursor+1, len(b.items))
+	if len(b.items) == 0 {
+		s = "0 items"
+	}
+	switch {
+	case b.loadingMore:
+		s += " (loading more...)"
+	case b.stopped && len(b.items) == 0:
+		s += " (stopped)"
+	case b.stopped:
+		s += " (stopped, scroll down to load more)"
+	case b.hasMore():
+		s += " (more available)"
+	}
+	if b.colOffset > 0 {
+		s += fmt.Sprintf(" | columns +%d", b.colOffset)
+	}
+	if len(b.marked) > 0 {
+		s += fmt.Sprintf(" | %d marked", len(b.marked))
+	}
+	return s
+}
+
+// formatCell renders an attribute value compactly for a grid cell
+func formatCell(av types.AttributeValue) string {
+	switch v := av.(type) {
+	case nil:
+		return ""
+	case *types.AttributeValueMemberS:
+		return v.Value
+	case *types.AttributeValueMemberN:
+		return v.Value
+	case *types.AttributeValueMemberBOOL:
+		return fmt.Sprintf("%t", v.Value)
+	case *types.AttributeValueMemberNULL:
+		return "null"
+	case *types.AttributeValueMemberB:
+		return base64.StdEncoding.EncodeToString(v.Value)
+	default:
+		data, err := json.Marshal(db.ToPlain(av))
+		if err != nil {
+			return "?"
+		}
+		return string(data)
+	}
+}
+
+// pad truncates or right-pads s to exactly width cells
+func pad(s string, width int) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	if lipgloss.Width(s) > width {
+		runes := []rune(s)
+		for len(runes) > 0 && lipgloss.Width(string(runes)) > width-1 {
+			runes = runes[:len(runes)-1]
+		}
+		return string(runes) + "…"
+	}
+	return s + strings.Repeat(" ", width-lipgloss.Width(s))
+}
+
+// scanTable returns a fetcher that runs the scan one page at a time
+func scanTable(client *db.DynamoClient, scan db.ScanInput) pageFetcher {
+	scan.Limit = pageSize(client)
+	return func(ctx context.Context, after *db.ItemPage) (*db.ItemPage, error) {
+		page := scan
+		page.ExclusiveStartKey = startKey(after)
+		return client.ScanWith(ctx, page)
+	}
+}
+
+// startKey returns the key a Scan or Query continues from after a page
+func startKey(after *db.ItemPage) db.Item {
+	if after == nil {
+		return nil
+	}
+	return after.LastEvaluatedKey
+}
+
+// pageSize returns the configured number of items per page, or
+// itemPageSize
+func pageSize(client *db.DynamoClient) int32 {
+	if cfg := client.Config(); cfg != nil && cfg.PageSize > 0 {
+		return cfg.PageSize
+	}
+	return itemPageSize
+}
@@ -0,0 +1,127 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	tea "github.com/charmbracelet/bubbletea"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// scanBackend serves the demo tables, except that scans block until they
+// are cancelled or fail while failures is above zero
+type scanBackend struct {
+	*db.MemoryBackend
+	block    bool
+	failures atomic.Int32
+}
+
+func (b *scanBackend) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	if b.block {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	if b.failures.Add(-1) >= 0 {
+		return nil, errors.New("service unavailable")
+	}
+	return b.MemoryBackend.Scan(ctx, params, optFns...)
+}
+
+// key returns the key press for s
+func key(s string) tea.KeyMsg {
+	switch s {
+	case "enter":
+		return tea.KeyMsg{Type: tea.KeyEnter}
+	case "esc":
+		return tea.KeyMsg{Type: tea.KeyEsc}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(s)}
+}
+
+// update passes msg to m and returns the new model with its command
+func update(t *testing.T, m Model, msg tea.Msg) (Model, tea.Cmd) {
+	t.Helper()
+	next, cmd := m.Update(msg)
+	return next.(Model), cmd
+}
+
+// openScan opens the Orders table and starts a scan of it
+func openScan(t *testing.T, backend db.Backend) (Model, tea.Cmd) {
+	t.Helper()
+	client := db.NewDynamoClientWithBackend(&appconfig.Config{Region: "us-east-1"}, backend)
+	m := NewModel(context.Background(), client)
+	m, _ = update(t, m, tablesLoadedMsg{tables: []string{"Orders"}})
+	m, cmd := update(t, m, key("enter"))
+	if cmd == nil {
+		t.Fatal("Expected the table to be described")
+	}
+	m, _ = update(t, m, cmd())
+	if m.tableData == nil {
+		t.Fatal("Expected the Orders table to be described")
+	}
+	m, cmd = update(t, m, key("s"))
+	if m.viewMode != itemViewMode || cmd == nil {
+		t.Fatalf("Expected a scan in the item grid, got view %s", m.viewMode)
+	}
+	return m, cmd
+}
+
+func TestItemViewEscCancelsLoad(t *testing.T) {
+	backend := &scanBackend{MemoryBackend: db.NewDemoBackend(), block: true}
+	m, cmd := openScan(t, backend)
+
+	done := make(chan tea.Msg)
+	go func() { done <- cmd() }()
+	m, _ = update(t, m, key("esc"))
+	if m.viewMode != itemViewMode || m.status != "Stopped loading items" {
+		t.Errorf("Expected the first Esc to stop loading, got view %s and status %q", m.viewMode, m.status)
+	}
+
+	m, _ = update(t, m, <-done)
+	if m.error != nil {
+		t.Errorf("Expected no error for a cancelled scan, got %v", m.error)
+	}
+
+	// The second Esc leaves the grid
+	m, _ = update(t, m, key("esc"))
+	if m.viewMode != tableViewMode {
+		t.Errorf("Expected the table view after the second Esc, got %s", m.viewMode)
+	}
+}
+
+func TestItemViewRetry(t *testing.T) {
+	backend := &scanBackend{MemoryBackend: db.NewDemoBackend()}
+	backend.failures.Store(1)
+	m, cmd := openScan(t, backend)
+
+	m, _ = update(t, m, cmd())
+	if m.error == nil || m.retry == nil {
+		t.Fatalf("Expected a failed scan that can be retried, got %v", m.error)
+	}
+
+	m, cmd = update(t, m, key("r"))
+	if m.error != nil || cmd == nil {
+		t.Fatalf("Expected r to retry the scan, got %v", m.error)
+	}
+	// Run commands until the page arrives
+	var msg tea.Msg
+	for i := 0; i < 3 && cmd != nil; i++ {
+		msg = cmd()
+		if _, ok := msg.(itemsLoadedMsg); ok {
+			break
+		}
+		m, cmd = update(t, m, msg)
+	}
+	if _, ok := msg.(itemsLoadedMsg); !ok {
+		t.Fatalf("Expected the retry to load items, got %#v", msg)
+	}
+	m, _ = update(t, m, msg)
+	if m.error != nil || len(m.browser.items) == 0 {
+		t.Errorf("Expected the retried page in the grid, got %d items and %v", len(m.browser.items), m.error)
+	}
+}
@@ -0,0 +1,72 @@
+package ui
+
+import (
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// defaultKeys are the keys of the actions that can be rebound in the config
+// file, by action name
+var defaultKeys = map[string]string{
+	"quit":        "q",
+	"scan":        "s",
+	"query":       "/",
+	"filter":      "f",
+	"credentials": "i",
+	"connections": "c",
+	"regions":     "R",
+	"console":     "p",
+	"edit":        "e",
+	"new":         "n",
+	"delete":      "d",
+	"mark":        " ",
+	"display":     "v",
+}
+
+// keyMap holds the keybindings of the config file. The update functions
+// handle the default keys, so a key press is translated to the default key
+// of its action first.
+type keyMap struct {
+	bound map[string]string // Action to key
+}
+
+func newKeyMap(bindings map[string]string) keyMap {
+	return keyMap{bound: bindings}
+}
+
+// translate returns the key press that the update functions expect for
+// msg, considering only the actions of the current view. ok is false for
+// the default key of an action that was rebound.
+func (k keyMap) translate(msg tea.KeyMsg, actions ...string) (translated tea.KeyMsg, ok bool) {
+	pressed := msg.String()
+	for _, action := range actions {
+		if key, bound := k.bound[action]; bound && key == pressed {
+			return keyPress(defaultKeys[action]), true
+		}
+	}
+	for _, action := range actions {
+		if _, bound := k.bound[action]; bound && defaultKeys[action] == pressed {
+			return msg, false
+		}
+	}
+	return msg, true
+}
+
+// key returns the key of action as shown in help texts
+func (k keyMap) key(action string) string {
+	key, ok := k.bound[action]
+	if !ok {
+		key = defaultKeys[action]
+	}
+	if key == " " {
+		return "Space"
+	}
+	return key
+}
+
+// keyPress creates the message of pressing a single key
+func keyPress(key string) tea.KeyMsg {
+	if key == " " {
+		return tea.KeyMsg{Type: tea.KeySpace, Runes: []rune{' '}}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(key)}
+}
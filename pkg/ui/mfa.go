@@ -0,0 +1,106 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// mfaCodeLength is the number of digits in a TOTP code
+const mfaCodeLength = 6
+
+// mfaPrompt asks for the code of an MFA device before the role of a profile
+// is assumed
+type mfaPrompt struct {
+	cfg       *appconfig.Config
+	profile   string
+	serial    string
+	code      string
+	verifying bool
+	renew     bool // The session of the current client expired
+	err       error
+}
+
+// NewMFAModel creates a UI model that asks for the MFA code described by
+// required, then connects with cfg and shows the table list
+func NewMFAModel(ctx context.Context, cfg *appconfig.Config, required *appconfig.MFARequiredError) Model {
+	m := NewModel(ctx, nil)
+	m.configure(cfg)
+	m.loading = false
+	m.viewMode = mfaMode
+	m.mfa = &mfaPrompt{cfg: cfg, profile: required.Profile, serial: required.Serial}
+	return m
+}
+
+// update handles a key press on the prompt. It returns a command when the
+// code is complete and should be verified.
+func (p *mfaPrompt) update(msg tea.KeyMsg) tea.Cmd {
+	if p.verifying {
+		return nil
+	}
+	switch msg.Type {
+	case tea.KeyRunes:
+		for _, r := range msg.Runes {
+			if r >= '0' && r <= '9' && len(p.code) < mfaCodeLength {
+				p.code += string(r)
+			}
+		}
+	case tea.KeyBackspace:
+		if len(p.code) > 0 {
+			p.code = p.code[:len(p.code)-1]
+		}
+	case tea.KeyEnter:
+		if len(p.code) != mfaCodeLength {
+			p.err = errors.New("enter the 6-digit code from your MFA device")
+			return nil
+		}
+		p.err = nil
+		p.verifying = true
+		return p.verify(p.code)
+	}
+	return nil
+}
+
+// verify assumes the role with code and creates the client
+func (p *mfaPrompt) verify(code string) tea.Cmd {
+	cfg := p.cfg
+	return func() tea.Msg {
+		cfg.SetMFAToken(code)
+		client, err := db.NewDynamoClientWithConfig(cfg)
+		return mfaVerifiedMsg{prompt: p, client: client, err: err}
+	}
+}
+
+// failed shows err and clears the code so another one can be entered
+func (p *mfaPrompt) failed(err error) {
+	p.verifying = false
+	p.code = ""
+	p.err = err
+}
+
+func (p *mfaPrompt) view() string {
+	var b strings.Builder
+	b.WriteString("Profile " + p.profile + " requires multi-factor authentication to start a session.\n")
+	b.WriteString("MFA device: " + p.serial + "\n\n")
+	b.WriteString("Code: " + p.code + strings.Repeat("_", mfaCodeLength-len(p.code)) + "\n")
+	if p.verifying {
+		b.WriteString("\nVerifying...\n")
+	}
+	if p.err != nil {
+		b.WriteString("\n" + lipgloss.NewStyle().Foreground(colors.err).Render("Error: "+p.err.Error()) + "\n")
+	}
+	return b.String()
+}
+
+// mfaVerifiedMsg reports the result of verifying an MFA code
+type mfaVerifiedMsg struct {
+	prompt *mfaPrompt
+	client *db.DynamoClient
+	err    error
+}
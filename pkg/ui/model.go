@@ -1,8 +1,14 @@
 package ui
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
 	"github.com/jlgore/dynamighTea/pkg/db"
 )
 
@@ -12,55 +18,247 @@ const (
 	tableListMode viewMode = "tables"
 	tableViewMode viewMode = "table"
 	indexViewMode viewMode = "index"
+	itemViewMode  viewMode = "items"
+	queryFormMode viewMode = "query"
+	scanFormMode  viewMode = "scan"
+	detailMode    viewMode = "detail"
+	editMode      viewMode = "edit"
+	deleteMode    viewMode = "delete"
+	mfaMode       viewMode = "mfa"
+	ssoLoginMode  viewMode = "sso"
+	whoamiMode    viewMode = "whoami"
+	connectMode   viewMode = "connections"
+	consoleMode   viewMode = "console"
 )
 
+// viewActions are the actions that can be rebound in each view
+var viewActions = map[viewMode][]string{
+	tableListMode: {"quit", "credentials", "connections", "regions", "console"},
+	tableViewMode: {"quit", "scan", "filter", "query", "credentials", "connections", "console"},
+	indexViewMode: {"quit", "scan", "filter", "query", "credentials", "connections", "console"},
+	itemViewMode:  {"quit", "edit", "new", "delete", "mark"},
+	detailMode:    {"quit", "edit", "display"},
+	whoamiMode:    {"quit", "credentials"},
+	connectMode:   {"quit", "connections"},
+}
+
 // Model represents the UI state
 type Model struct {
-	tables       []string
+	tables        []tableEntry
+	regions       []db.RegionTables // Set in multi-region mode
+	multiRegion   bool
 	selectedTable int
-	viewMode     viewMode
-	tableData    *db.TableInfo
-	width        int
-	height       int
-	loading      bool
-	error        error
-	client       *db.DynamoClient
-}
-
-// NewModel creates a new UI model
-func NewModel() Model {
-	return Model{
-		tables:       []string{},
+	viewMode      viewMode
+	tableData     *db.TableInfo
+	width         int
+	height        int
+	loading       bool
+	error         error
+	client        *db.DynamoClient
+	tableClient   *db.DynamoClient // Client for the region of the open table
+	browser       *itemBrowser
+	browserReturn viewMode
+	query         *queryForm
+	scanFilter    *filterForm
+	detail        *itemDetail
+	editor        *itemEditor
+	deletion      *deleteJob
+	mfa           *mfaPrompt
+	mfaReturn     viewMode
+	sso           *ssoLogin
+	whoami        *whoamiScreen
+	picker        *connectionPicker
+	console       *partiqlConsole // Kept for the session, with its history
+	cfg           *appconfig.Config
+	status        string
+	retry         tea.Cmd
+	session       sessionStatus
+	keys          keyMap
+	readOnly      bool
+
+	// ctx bounds every request and is cancelled on quit. Reads use viewCtx,
+	// which is cancelled when the user navigates away from the data they
+	// load; writes use ctx so they are never abandoned halfway.
+	ctx        context.Context
+	cancel     context.CancelFunc
+	viewCtx    context.Context
+	viewCancel context.CancelFunc
+}
+
+// NewModel creates a new UI model backed by the given client. The client is
+// shared by every command; requests are cancelled when ctx is done or the
+// user quits.
+func NewModel(ctx context.Context, client *db.DynamoClient) Model {
+	ctx, cancel := context.WithCancel(ctx)
+	viewCtx, viewCancel := context.WithCancel(ctx)
+	m := Model{
+		tables:        []tableEntry{},
 		selectedTable: 0,
-		viewMode:     tableListMode,
-		loading:      true,
-		client:       db.NewDynamoClient(),
+		viewMode:      tableListMode,
+		loading:       true,
+		client:        client,
+		ctx:           ctx,
+		cancel:        cancel,
+		viewCtx:       viewCtx,
+		viewCancel:    viewCancel,
+	}
+	if client != nil {
+		m.configure(client.Config())
+	}
+	return m
+}
+
+// configure applies the theme, keybindings and read-only mode of cfg
+func (m *Model) configure(cfg *appconfig.Config) {
+	if cfg == nil {
+		return
 	}
+	m.cfg = cfg
+	useTheme(cfg.Theme)
+	m.keys = newKeyMap(cfg.KeyBindings)
+	m.readOnly = cfg.ReadOnly
+	m.multiRegion = len(cfg.Regions) > 0
 }
 
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
-	return loadTables
+	// Without a client the user signs in first
+	if m.sso != nil {
+		return m.sso.start(m.viewCtx)
+	}
+	if m.client == nil {
+		return nil
+	}
+	return tea.Batch(m.loadTableList(), checkSession(m.ctx, m.client))
+}
+
+// loadTableList lists the tables of the client's region, or of several
+// regions in multi-region mode
+func (m Model) loadTableList() tea.Cmd {
+	if m.multiRegion {
+		return loadRegionTables(m.viewCtx, m.client, listedRegions(m.cfg))
+	}
+	return loadTables(m.viewCtx, m.client)
+}
+
+// leaveView cancels the requests of the view being left
+func (m *Model) leaveView() {
+	m.viewCancel()
+	m.viewCtx, m.viewCancel = context.WithCancel(m.ctx)
+}
+
+// quit cancels every outstanding request and exits
+func (m Model) quit() (tea.Model, tea.Cmd) {
+	m.cancel()
+	return m, tea.Quit
 }
 
 // Update handles messages and user input
 func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
+		m.status = ""
+		// Keybindings apply where no text is typed
+		if actions, ok := viewActions[m.viewMode]; ok {
+			if msg, ok = m.keys.translate(msg, actions...); !ok {
+				return m, nil
+			}
+		}
+		if m.error != nil {
+			switch msg.String() {
+			case "r":
+				m.error = nil
+				m.status = "Retrying..."
+				return m, m.retry
+			case "esc":
+				m.error = nil
+				return m, nil
+			}
+		}
+		switch m.viewMode {
+		case mfaMode:
+			return m.updateMFAPrompt(msg)
+		case ssoLoginMode:
+			return m.updateSSOLogin(msg)
+		case itemViewMode:
+			return m.updateItemView(msg)
+		case queryFormMode:
+			return m.updateQueryForm(msg)
+		case scanFormMode:
+			return m.updateScanForm(msg)
+		case detailMode:
+			return m.updateDetailView(msg)
+		case editMode:
+			return m.updateEditView(msg)
+		case deleteMode:
+			return m.updateDeleteView(msg)
+		case whoamiMode:
+			return m.updateWhoami(msg)
+		case connectMode:
+			return m.updateConnectionPicker(msg)
+		case consoleMode:
+			return m.updateConsole(msg)
+		}
 		switch msg.String() {
 		case "ctrl+c", "q":
-			return m, tea.Quit
+			return m.quit()
+		case "s":
+			if (m.viewMode == tableViewMode || m.viewMode == indexViewMode) && m.tableData != nil {
+				return m.openItemView(newItemBrowser(
+					"Scan: "+m.tableData.TableName,
+					keyAttributes(m.tableData.KeySchema),
+					scanTable(m.tableClient, db.ScanInput{TableName: m.tableData.TableName}),
+				))
+			}
+		case "f":
+			if (m.viewMode == tableViewMode || m.viewMode == indexViewMode) && m.tableData != nil {
+				m.scanFilter = newFilterForm(m.tableData)
+				m.viewMode = scanFormMode
+			}
+		case "R":
+			if m.viewMode == tableListMode {
+				m.leaveView()
+				m.multiRegion = !m.multiRegion
+				m.selectedTable = 0
+				m.loading = true
+				return m, m.loadTableList()
+			}
+		case "c":
+			if m.cfg == nil || len(m.cfg.Connections) == 0 {
+				m.status = "No connections are defined in the config file"
+				return m, nil
+			}
+			m.picker = newConnectionPicker(m.cfg, m.viewMode)
+			m.viewMode = connectMode
+			return m, nil
+		case "p":
+			return m.openConsole()
+		case "i":
+			m.whoami = &whoamiScreen{returnMode: m.viewMode}
+			m.viewMode = whoamiMode
+			return m, m.whoami.load(m.ctx, m.client)
+		case "/":
+			if (m.viewMode == tableViewMode || m.viewMode == indexViewMode) && m.tableData != nil {
+				m.query = newQueryForm(m.tableData)
+				m.viewMode = queryFormMode
+			}
+		case "esc":
+			// Stop loading the table and go back to the list
+			if m.viewMode == tableViewMode || m.viewMode == indexViewMode {
+				m.leaveView()
+				m.viewMode = tableListMode
+			}
 		case "tab":
 			// Cycle through view modes
 			switch m.viewMode {
 			case tableListMode:
 				if len(m.tables) > 0 {
-					m.viewMode = tableViewMode
-					return m, loadTableInfo(m.tables[m.selectedTable])
+					return m.openTable()
 				}
 			case tableViewMode:
 				m.viewMode = indexViewMode
 			case indexViewMode:
+				m.leaveView()
 				m.viewMode = tableListMode
 			}
 		case "up", "k":
@@ -73,56 +271,709 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		case "enter":
 			if m.viewMode == tableListMode && len(m.tables) > 0 {
-				m.viewMode = tableViewMode
-				return m, loadTableInfo(m.tables[m.selectedTable])
+				return m.openTable()
 			}
 		}
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
 	case tablesLoadedMsg:
-		m.tables = msg.tables
+		m.tables = make([]tableEntry, len(msg.tables))
+		for i, name := range msg.tables {
+			m.tables[i] = tableEntry{name: name, region: m.client.Region()}
+		}
+		m.regions = nil
+		m.loading = false
+	case regionTablesLoadedMsg:
+		m.tables = nil
+		for _, region := range msg.regions {
+			for _, table := range region.Tables {
+				m.tables = append(m.tables, tableEntry{name: table.Name, region: region.Region, replicas: table.Replicas})
+			}
+		}
+		m.regions = msg.regions
+		m.selectedTable = min(m.selectedTable, max(len(m.tables)-1, 0))
 		m.loading = false
 	case tableInfoLoadedMsg:
 		m.tableData = msg.tableInfo
 		m.loading = false
+	case itemsLoadedMsg:
+		// Ignore pages for a browser that has since been closed
+		if msg.browser == m.browser {
+			m.browser.addPage(msg.page)
+			return m, m.browser.maybeLoadMore()
+		}
+	case retryPageMsg:
+		if msg.browser == m.browser {
+			m.browser.loadingMore = true
+			return m, m.browser.loadPage(msg.after)
+		}
+	case editorFinishedMsg:
+		if msg.editor == m.editor {
+			m.editor.saving = false
+			if msg.err != nil {
+				m.editor.err = msg.err
+			} else {
+				m.editor.finish(msg.text)
+			}
+		}
+	case itemSavedMsg:
+		if msg.editor == m.editor {
+			if m.editor.original == nil {
+				m.browser.insert(m.editor.updated)
+				m.status = "Item created"
+			} else {
+				m.browser.replaceSelected(m.editor.updated)
+				if m.detail != nil {
+					m.detail.item = m.editor.updated
+				}
+				m.status = "Item saved"
+			}
+			m.viewMode = m.editor.returnMode
+			m.editor = nil
+		}
+	case statementsDoneMsg:
+		if msg.console == m.console {
+			m.console.finish(msg.entries)
+		}
+	case deleteProgressMsg:
+		if msg.job == m.deletion {
+			m.deletion.progress = msg.progress
+			return m, m.deletion.wait()
+		}
+	case deleteDoneMsg:
+		if msg.job == m.deletion {
+			deleted := m.deletion.deletedIndexes(msg.err)
+			m.browser.remove(deleted)
+			if msg.err != nil {
+				m.status = fmt.Sprintf("Deleted %d of %d item(s). Error: %v", len(deleted), len(m.deletion.keys), msg.err)
+			} else {
+				m.status = fmt.Sprintf("Deleted %d item(s)", len(deleted))
+			}
+			m.deletion = nil
+			m.viewMode = itemViewMode
+		}
+	case mfaVerifiedMsg:
+		if msg.prompt == m.mfa {
+			if msg.err != nil {
+				m.mfa.failed(msg.err)
+				return m, nil
+			}
+			renewed := m.mfa.renew
+			m.mfa = nil
+			if renewed {
+				// The code renewed the credentials of the running session
+				m.viewMode = m.mfaReturn
+				return m, checkSession(m.ctx, m.client)
+			}
+			return m.connected(msg.client)
+		}
+	case ssoLoginStartedMsg:
+		if msg.sso == m.sso {
+			if msg.err != nil {
+				m.sso.err = msg.err
+				return m, nil
+			}
+			m.sso.login = msg.login
+			return m, m.sso.wait(m.viewCtx)
+		}
+	case ssoLoginDoneMsg:
+		if msg.sso == m.sso {
+			// A role assumed with the SSO credentials may still need MFA
+			var mfa *appconfig.MFARequiredError
+			if errors.As(msg.err, &mfa) {
+				m.mfa = &mfaPrompt{cfg: m.sso.cfg, profile: mfa.Profile, serial: mfa.Serial}
+				m.sso = nil
+				m.viewMode = mfaMode
+				return m, nil
+			}
+			if msg.err != nil {
+				m.sso.err = msg.err
+				return m, nil
+			}
+			m.sso = nil
+			return m.connected(msg.client)
+		}
+	case identityLoadedMsg:
+		if msg.screen == m.whoami {
+			m.whoami.identity = &msg.identity
+		}
+	case sessionCheckedMsg:
+		if msg.client != m.client {
+			return m, nil
+		}
+		m.session = msg.status
+		// Renewing a role that requires MFA needs a new code
+		var mfa *appconfig.MFARequiredError
+		if errors.As(msg.status.err, &mfa) {
+			if m.mfa == nil {
+				m.mfa = &mfaPrompt{cfg: m.client.Config(), profile: mfa.Profile, serial: mfa.Serial, renew: true}
+				m.mfaReturn = m.viewMode
+				m.viewMode = mfaMode
+			}
+			return m, nil
+		}
+		return m, scheduleSessionCheck(m.client)
+	case sessionCheckDueMsg:
+		if msg.client != m.client {
+			return m, nil
+		}
+		return m, checkSession(m.ctx, m.client)
+	case connectionSwitchedMsg:
+		if msg.picker != m.picker {
+			return m, nil
+		}
+		m.picker.switching = false
+		// The new connection may need a sign-in first
+		var mfa *appconfig.MFARequiredError
+		var sso *appconfig.SSOLoginRequiredError
+		switch {
+		case errors.As(msg.err, &mfa):
+			m.mfa = &mfaPrompt{cfg: msg.cfg, profile: mfa.Profile, serial: mfa.Serial}
+			m.mfaReturn = m.picker.returnMode
+			m.picker = nil
+			m.viewMode = mfaMode
+			return m, nil
+		case errors.As(msg.err, &sso):
+			m.sso = &ssoLogin{cfg: msg.cfg, required: sso}
+			m.picker = nil
+			m.viewMode = ssoLoginMode
+			return m, m.sso.start(m.viewCtx)
+		case msg.err != nil:
+			m.picker.err = msg.err
+			return m, nil
+		}
+		m.picker = nil
+		return m.connected(msg.client)
 	case errorMsg:
+		// Requests cancelled by navigation are not errors
+		if errors.Is(msg.err, context.Canceled) {
+			return m, nil
+		}
 		m.error = msg.err
+		m.retry = msg.retry
 		m.loading = false
+		if m.browser != nil {
+			m.browser.loadingMore = false
+		}
+	}
+	return m, nil
+}
+
+// openItemView switches to the item grid and loads its first page. Esc
+// returns to the view that opened it.
+func (m Model) openItemView(browser *itemBrowser) (tea.Model, tea.Cmd) {
+	m.browser = browser
+	m.browser.ctx = m.viewCtx
+	m.browser.loadingMore = true
+	m.browserReturn = m.viewMode
+	m.viewMode = itemViewMode
+	return m, m.browser.loadPage(nil)
+}
+
+// openTable shows the selected table, through a client for its region
+func (m Model) openTable() (tea.Model, tea.Cmd) {
+	table := m.tables[m.selectedTable]
+	client, err := m.client.InRegion(table.region)
+	if err != nil {
+		m.status = "Error: " + err.Error()
+		return m, nil
+	}
+	m.tableClient = client
+	m.tableData = nil
+	m.viewMode = tableViewMode
+	return m, loadTableInfo(m.viewCtx, client, table.name)
+}
+
+// connected starts using client once the user has signed in or switched
+// to another connection
+func (m Model) connected(client *db.DynamoClient) (tea.Model, tea.Cmd) {
+	m.leaveView()
+	m.client = client
+	m.tableClient = nil
+	m.cfg = client.Config()
+	m.readOnly = m.cfg.ReadOnly
+	m.tables = nil
+	m.regions = nil
+	m.selectedTable = 0
+	m.tableData = nil
+	m.browser = nil
+	m.session = sessionStatus{}
+	m.viewMode = tableListMode
+	m.loading = true
+	return m, tea.Batch(m.loadTableList(), checkSession(m.ctx, m.client))
+}
+
+// updateConnectionPicker handles key presses on the connection picker
+func (m Model) updateConnectionPicker(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc", "c":
+		m.viewMode = m.picker.returnMode
+		m.picker = nil
+		return m, nil
+	case "enter":
+		if !m.picker.switching {
+			return m, m.picker.connect()
+		}
+		return m, nil
+	}
+	m.picker.update(msg.String())
+	return m, nil
+}
+
+// openConsole shows the PartiQL console. From a table it runs against the
+// region of the table and starts with a SELECT of the table.
+func (m Model) openConsole() (tea.Model, tea.Cmd) {
+	if m.console == nil {
+		m.console = &partiqlConsole{}
+	}
+	m.console.client = m.client
+	if m.viewMode != tableListMode && m.tableClient != nil && m.tableData != nil {
+		m.console.client = m.tableClient
+		if m.console.input == "" {
+			m.console.input = fmt.Sprintf("SELECT * FROM %q", m.tableData.TableName)
+		}
+	}
+	m.console.returnMode = m.viewMode
+	m.viewMode = consoleMode
+	return m, nil
+}
+
+// updateConsole handles key presses in the PartiQL console
+func (m Model) updateConsole(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	c := m.console
+	if msg.String() == "ctrl+c" {
+		return m.quit()
+	}
+	if c.running {
+		return m, nil
+	}
+	if c.confirm != nil {
+		if msg.String() == "esc" {
+			c.confirm = nil
+			c.toConfirm = nil
+			c.pending = nil
+			m.status = "Statement cancelled"
+			return m, nil
+		}
+		if c.confirm.update(msg) {
+			// Statements that change several tables need each name
+			if len(c.toConfirm) > 0 {
+				c.confirm = newTableConfirm(c.toConfirm[0])
+				c.toConfirm = c.toConfirm[1:]
+				return m, nil
+			}
+			c.confirm = nil
+			statements := c.pending
+			c.pending = nil
+			return m, c.run(m.ctx, statements)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "esc":
+		m.viewMode = c.returnMode
+		return m, nil
+	case "enter":
+		statements := db.SplitStatements(c.input)
+		if len(statements) == 0 {
+			return m, nil
+		}
+		c.remember()
+		c.input = ""
+		// A single SELECT opens its results in the item grid
+		if len(statements) == 1 && isSelect(statements[0]) {
+			c.add(consoleEntry{statement: statements[0], result: "Results opened in the item grid"})
+			return m.openItemView(c.browser(statements[0]))
+		}
+		// On production every change needs the name of its table, so a
+		// statement whose table is unclear is not run at all
+		if m.production() {
+			tables, err := changedTables(statements)
+			if err != nil {
+				c.add(consoleEntry{statement: strings.Join(statements, "; "), result: err.Error(), failed: true})
+				return m, nil
+			}
+			if len(tables) > 0 {
+				c.pending = statements
+				c.confirm = newTableConfirm(tables[0])
+				c.toConfirm = tables[1:]
+				return m, nil
+			}
+		}
+		return m, c.run(m.ctx, statements)
+	}
+	c.update(msg)
+	return m, nil
+}
+
+// updateWhoami handles key presses on the credentials screen
+func (m Model) updateWhoami(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc", "i":
+		m.viewMode = m.whoami.returnMode
+		m.whoami = nil
+	case "r":
+		if m.whoami.identity != nil {
+			return m, m.whoami.load(m.ctx, m.client)
+		}
+	}
+	return m, nil
+}
+
+// updateSSOLogin handles key presses during an SSO login
+func (m Model) updateSSOLogin(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc":
+		if m.client == nil {
+			return m.quit()
+		}
+		// Abandon the switch to another connection
+		m.leaveView()
+		m.sso = nil
+		m.viewMode = tableListMode
+		return m, nil
+	case "r":
+		if m.sso.err != nil {
+			return m, m.sso.start(m.viewCtx)
+		}
+	}
+	return m, nil
+}
+
+// updateMFAPrompt handles key presses while the MFA code is requested
+func (m Model) updateMFAPrompt(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m.quit()
+	case "esc":
+		if m.client == nil {
+			return m.quit()
+		}
+		// Carry on with the current client. An expired session fails
+		// until the UI is restarted; a switch of connection is abandoned.
+		m.mfa = nil
+		m.viewMode = m.mfaReturn
+		return m, nil
+	}
+	return m, m.mfa.update(msg)
+}
+
+// updateQueryForm handles key presses while the query builder is shown
+func (m Model) updateQueryForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c":
+		return m.quit()
+	case "esc":
+		m.query = nil
+		m.viewMode = tableViewMode
+		return m, nil
+	case "enter":
+		if m.query.focus == fieldFilter && m.query.filter.enter() {
+			return m, nil
+		}
+		browser, err := m.query.browser(m.tableClient)
+		if err != nil {
+			m.query.err = err
+			return m, nil
+		}
+		return m.openItemView(browser)
+	}
+	m.query.update(msg)
+	return m, nil
+}
+
+// updateScanForm handles key presses on the filter builder of a scan
+func (m Model) updateScanForm(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	f := m.scanFilter
+	switch msg.String() {
+	case "ctrl+c":
+		return m.quit()
+	case "esc":
+		m.scanFilter = nil
+		m.viewMode = tableViewMode
+		return m, nil
+	case "tab", "down":
+		if !f.moveFocus(1) {
+			f.focusFirst()
+		}
+		return m, nil
+	case "shift+tab", "up":
+		if !f.moveFocus(-1) {
+			f.focusLast()
+		}
+		return m, nil
+	case "enter":
+		if f.enter() {
+			return m, nil
+		}
+		conditions, err := f.conditions()
+		if err != nil {
+			m.status = "Error: " + err.Error()
+			return m, nil
+		}
+		title := "Scan: " + m.tableData.TableName
+		if f.active() {
+			title += " (filtered)"
+		}
+		keys := keyAttributes(m.tableData.KeySchema)
+		return m.openItemView(newItemBrowser(title, keys, scanTable(m.tableClient, db.ScanInput{
+			TableName:  m.tableData.TableName,
+			Filter:     conditions,
+			Projection: f.projection(keys),
+		})))
+	}
+	f.update(msg)
+	return m, nil
+}
+
+// updateItemView handles key presses while the item grid is shown
+func (m Model) updateItemView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc":
+		// The first Esc stops a page that is still loading
+		if m.browser.stop() {
+			m.status = "Stopped loading items"
+			return m, nil
+		}
+		m.leaveView()
+		m.browser = nil
+		m.viewMode = m.browserReturn
+		return m, nil
+	case "enter":
+		if item := m.browser.selected(); item != nil {
+			m.detail = newItemDetail(m.browser.title, item, m.browser.keyAttrs)
+			m.viewMode = detailMode
+		}
+		return m, nil
+	case "e", "n", "d", " ":
+		if m.browser.console {
+			m.status = "PartiQL results cannot be changed here: run UPDATE or DELETE in the console"
+			return m, nil
+		}
+		if m.readOnly && msg.String() != " " {
+			m.status = "Read-only mode: items cannot be changed"
+			return m, nil
+		}
+	}
+	switch msg.String() {
+	case "e":
+		if item := m.browser.selected(); item != nil {
+			return m.openEditor(item)
+		}
+		return m, nil
+	case "n":
+		return m.openEditor(nil)
+	case " ":
+		m.browser.toggleMark()
+		return m, m.browser.update("down", m.itemRows())
+	case "d":
+		if indexes := m.browser.targets(); len(indexes) > 0 {
+			m.deletion = newDeleteJob(m.tableData, m.browser, indexes)
+			m.viewMode = deleteMode
+		}
+		return m, nil
+	}
+	return m, m.browser.update(msg.String(), m.itemRows())
+}
+
+// updateDeleteView handles key presses on the delete confirmation dialog
+func (m Model) updateDeleteView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if msg.String() == "ctrl+c" {
+		return m.quit()
+	}
+	if m.deletion.running {
+		return m, nil
+	}
+	if m.deletion.confirm != nil {
+		if msg.String() == "esc" {
+			m.deletion = nil
+			m.viewMode = itemViewMode
+			m.status = "Delete cancelled"
+			return m, nil
+		}
+		if m.deletion.confirm.update(msg) {
+			return m, m.deletion.start(m.ctx, m.tableClient)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "y":
+		if m.production() {
+			m.deletion.confirm = newTableConfirm(m.deletion.tableName)
+			return m, nil
+		}
+		return m, m.deletion.start(m.ctx, m.tableClient)
+	case "n", "esc":
+		m.deletion = nil
+		m.viewMode = itemViewMode
+		m.status = "Delete cancelled"
+	}
+	return m, nil
+}
+
+// openEditor starts editing item in the external editor, or creating a new
+// item when item is nil
+func (m Model) openEditor(item db.Item) (tea.Model, tea.Cmd) {
+	editor, err := newItemEditor(m.tableData, item)
+	if err != nil {
+		m.status = "Error: " + err.Error()
+		return m, nil
+	}
+	editor.returnMode = m.viewMode
+	m.editor = editor
+	m.viewMode = editMode
+	return m, editor.open()
+}
+
+// updateEditView handles key presses on the save confirmation screen
+func (m Model) updateEditView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	if m.editor.saving {
+		if msg.String() == "ctrl+c" {
+			return m.quit()
+		}
+		return m, nil
 	}
+
+	if m.editor.confirm != nil {
+		switch msg.String() {
+		case "ctrl+c":
+			return m.quit()
+		case "esc":
+			m.editor.confirm = nil
+			return m, nil
+		}
+		if m.editor.confirm.update(msg) {
+			return m, m.editor.save(m.ctx, m.tableClient)
+		}
+		return m, nil
+	}
+
+	switch msg.String() {
+	case "ctrl+c":
+		return m.quit()
+	case "y", "enter":
+		if m.editor.canSave() {
+			if m.production() && m.editor.destructive() {
+				m.editor.confirm = newTableConfirm(m.editor.tableName)
+				return m, nil
+			}
+			return m, m.editor.save(m.ctx, m.tableClient)
+		}
+	case "e":
+		return m, m.editor.open()
+	case "esc", "n":
+		m.viewMode = m.editor.returnMode
+		m.editor = nil
+		m.status = "Edit discarded"
+	}
+	return m, nil
+}
+
+// updateDetailView handles key presses while a single item is shown
+func (m Model) updateDetailView(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
+	switch msg.String() {
+	case "ctrl+c", "q":
+		return m.quit()
+	case "esc":
+		m.detail = nil
+		m.viewMode = itemViewMode
+		return m, nil
+	case "e":
+		if m.browser.console {
+			m.status = "PartiQL results cannot be changed here: run UPDATE or DELETE in the console"
+			return m, nil
+		}
+		if m.readOnly {
+			m.status = "Read-only mode: items cannot be changed"
+			return m, nil
+		}
+		return m.openEditor(m.detail.item)
+	}
+	m.detail.update(msg.String(), m.itemRows())
 	return m, nil
 }
 
+// production reports whether the connection is tagged as production, so
+// destructive actions need the table name typed to confirm them
+func (m Model) production() bool {
+	return m.cfg != nil && m.cfg.Production
+}
+
+// itemRows returns how many item rows fit on screen
+func (m Model) itemRows() int {
+	if m.height == 0 {
+		return 20
+	}
+	rows := m.height - 9 // Header, title, status and help
+	if rows < 3 {
+		rows = 3
+	}
+	return rows
+}
+
+// viewWidth returns the usable terminal width
+func (m Model) viewWidth() int {
+	if m.width == 0 {
+		return 120
+	}
+	return m.width
+}
+
 // View renders the UI
 func (m Model) View() string {
 	if m.loading {
+		if header := header(m.cfg); header != "" {
+			return header + "\n\nLoading..."
+		}
 		return "Loading..."
 	}
 
-	if m.error != nil {
-		return "Error: " + m.error.Error()
-	}
-
 	var content string
-	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("#FFFF00")).Render
+	titleStyle := lipgloss.NewStyle().Bold(true).Foreground(colors.accent).Render
 
 	switch m.viewMode {
 	case tableListMode:
-		content = titleStyle("DynamoDB Tables") + "\n\n"
-		for i, table := range m.tables {
-			if i == m.selectedTable {
-				content += "> " + table + "\n"
-			} else {
-				content += "  " + table + "\n"
+		title := "DynamoDB Tables"
+		if m.regions != nil {
+			title += fmt.Sprintf(" (%d regions)", len(m.regions))
+		}
+		if m.client.Demo() {
+			title += " (demo data)"
+		}
+		content = titleStyle(title) + "\n\n"
+		if m.regions != nil {
+			content += tableListView(m.regions, m.tables, m.selectedTable)
+		} else {
+			for i, table := range m.tables {
+				if i == m.selectedTable {
+					content += "> " + table.name + "\n"
+				} else {
+					content += "  " + table.name + "\n"
+				}
 			}
 		}
-		content += "\n[↑/↓]: Navigate [Enter]: Select [Tab]: Switch View [q]: Quit"
-	
+		regions := "Multi-Region"
+		if m.multiRegion {
+			regions = "One Region"
+		}
+		content += fmt.Sprintf("\n[↑/↓]: Navigate [Enter]: Select [Tab]: Switch View [%s]: %s [%s]: PartiQL [%s]: Credentials [%s]: Connections [%s]: Quit", m.keys.key("regions"), regions, m.keys.key("console"), m.keys.key("credentials"), m.keys.key("connections"), m.keys.key("quit"))
+
 	case tableViewMode:
 		if m.tableData == nil {
 			content = "Loading table data..."
 		} else {
-			content = titleStyle("Table: " + m.tableData.TableName) + "\n\n"
+			content = titleStyle("Table: "+m.tableData.TableName) + "\n\n"
 			content += "Primary Key:\n"
 			for _, attr := range m.tableData.KeySchema {
 				content += "  " + attr.AttributeName + " (" + attr.KeyType + ")\n"
@@ -131,15 +982,15 @@ func (m Model) View() string {
 			for name, attrType := range m.tableData.AttributeDefinitions {
 				content += "  " + name + ": " + attrType + "\n"
 			}
-			content += "\n[Tab]: View Indexes [q]: Quit"
+			content += fmt.Sprintf("\n[%s]: Scan Items [%s]: Filtered Scan [%s]: Query [%s]: PartiQL [Tab]: View Indexes [%s]: Quit", m.keys.key("scan"), m.keys.key("filter"), m.keys.key("query"), m.keys.key("console"), m.keys.key("quit"))
 		}
-	
+
 	case indexViewMode:
 		if m.tableData == nil {
 			content = "Loading table data..."
 		} else {
-			content = titleStyle("Indexes: " + m.tableData.TableName) + "\n\n"
-			
+			content = titleStyle("Indexes: "+m.tableData.TableName) + "\n\n"
+
 			// GSIs
 			content += lipgloss.NewStyle().Bold(true).Render("Global Secondary Indexes:") + "\n"
 			if len(m.tableData.GSIs) == 0 {
@@ -153,7 +1004,7 @@ func (m Model) View() string {
 					content += "\n"
 				}
 			}
-			
+
 			// LSIs
 			content += lipgloss.NewStyle().Bold(true).Render("Local Secondary Indexes:") + "\n"
 			if len(m.tableData.LSIs) == 0 {
@@ -167,13 +1018,164 @@ func (m Model) View() string {
 					content += "\n"
 				}
 			}
-			content += "\n[Tab]: View Tables [q]: Quit"
+			content += fmt.Sprintf("\n[%s]: Scan Items [%s]: Filtered Scan [%s]: Query [%s]: PartiQL [Tab]: View Tables [%s]: Quit", m.keys.key("scan"), m.keys.key("filter"), m.keys.key("query"), m.keys.key("console"), m.keys.key("quit"))
+		}
+
+	case itemViewMode:
+		content = titleStyle(m.browser.title) + "\n\n"
+		content += m.browser.view(m.viewWidth(), m.itemRows())
+		content += "\n" + m.browser.status() + "\n"
+		back := "[Esc]: Back"
+		if m.browser.loadingMore {
+			back = "[Esc]: Stop Loading"
+		}
+		content += "[↑/↓]: Navigate [←/→]: Scroll Columns [PgUp/PgDn]: Page [Enter]: View Item "
+		if !m.readOnly && !m.browser.console {
+			content += fmt.Sprintf("[%s]: Mark [%s]: Delete [%s]: Edit [%s]: New ", m.keys.key("mark"), m.keys.key("delete"), m.keys.key("edit"), m.keys.key("new"))
+		}
+		content += back + " [" + m.keys.key("quit") + "]: Quit"
+
+	case detailMode:
+		content = titleStyle(m.detail.title+" | "+m.detail.display.String()) + "\n\n"
+		content += m.detail.view(m.itemRows())
+		if m.detail.display == displayTree {
+			content += "\n[Enter/Space]: Expand/Collapse [+/-]: Expand/Collapse All "
+		} else {
+			content += "\n[↑/↓]: Scroll "
+		}
+		content += "[" + m.keys.key("display") + "]: Switch Display "
+		if !m.readOnly && !m.browser.console {
+			content += "[" + m.keys.key("edit") + "]: Edit "
+		}
+		content += "[Esc]: Back [" + m.keys.key("quit") + "]: Quit"
+
+	case deleteMode:
+		content = titleStyle("Delete: "+m.deletion.tableName) + "\n\n"
+		content += m.deletion.view(m.viewWidth())
+		switch {
+		case m.deletion.running:
+		case m.deletion.confirm != nil:
+			content += "\n[Enter]: Delete [Esc]: Cancel"
+		default:
+			content += "\n[y]: Delete [n/Esc]: Cancel"
+		}
+
+	case editMode:
+		content = titleStyle("Edit: "+m.editor.tableName) + "\n\n"
+		content += m.editor.view()
+		if m.editor.confirm != nil {
+			content += "\n[Enter]: Save [Esc]: Back"
+		} else if m.editor.canSave() {
+			content += "\n[y]: Save [e]: Edit Again [Esc]: Discard"
+		} else {
+			content += "\n[e]: Edit Again [Esc]: Discard"
+		}
+
+	case mfaMode:
+		content = titleStyle("MFA Code") + "\n\n"
+		content += m.mfa.view()
+		if m.client == nil {
+			content += "\n[0-9]: Enter Code [Enter]: Verify [Esc]: Quit"
+		} else {
+			content += "\n[0-9]: Enter Code [Enter]: Verify [Esc]: Dismiss"
+		}
+
+	case ssoLoginMode:
+		content = titleStyle("AWS SSO Login") + "\n\n"
+		content += m.sso.view()
+		escape := "[Esc]: Quit"
+		if m.client != nil {
+			escape = "[Esc]: Cancel"
+		}
+		if m.sso.err != nil {
+			content += "\n[r]: Try Again " + escape
+		} else {
+			content += "\n" + escape
+		}
+
+	case connectMode:
+		content = titleStyle("Connections") + "\n\n"
+		content += m.picker.view()
+		content += "\n[↑/↓]: Navigate [Enter]: Connect [Esc]: Back [" + m.keys.key("quit") + "]: Quit"
+
+	case whoamiMode:
+		content = titleStyle("Credentials") + "\n\n"
+		content += m.whoami.view()
+		content += "\n[r]: Refresh [Esc]: Back [" + m.keys.key("quit") + "]: Quit"
+
+	case consoleMode:
+		content = titleStyle("PartiQL Console") + "\n\n"
+		content += m.console.view(m.viewWidth(), m.itemRows()-2)
+		if m.console.confirm != nil {
+			content += "\n[Enter]: Run [Esc]: Cancel"
+		} else {
+			content += "\n[Enter]: Run [↑/↓]: History [Ctrl+U]: Clear [Esc]: Back"
 		}
+
+	case queryFormMode:
+		content = titleStyle("Query: "+m.tableData.TableName) + "\n\n"
+		content += m.query.view()
+		content += "\n[Tab/↑/↓]: Next Field [←/→]: Change Option [Space]: Pick Attribute [Ctrl+X]: Remove Condition [Enter]: Run Query [Esc]: Back"
+
+	case scanFormMode:
+		content = titleStyle("Scan: "+m.tableData.TableName) + "\n\n"
+		content += m.scanFilter.view(true)
+		content += "\n[Tab/↑/↓]: Next Field [←/→]: Change Option [Space]: Pick Attribute [Ctrl+X]: Remove Condition [Enter]: Run Scan [Esc]: Back"
+	}
+
+	if m.status != "" {
+		content += "\n" + m.status
+	}
+
+	if session := m.session.view(); session != "" {
+		content += "\n" + session
+	}
+
+	if m.error != nil {
+		content = m.errorBanner() + "\n\n" + content
+	}
+
+	if header := header(m.headerConfig()); header != "" {
+		content = header + "\n\n" + content
 	}
 
 	return content
 }
 
+// headerConfig returns the config of the client the current view uses. A
+// table opened from another region is shown with that region.
+func (m Model) headerConfig() *appconfig.Config {
+	switch {
+	case m.console != nil && (m.viewMode == consoleMode || m.browser != nil && m.browser.console):
+		return m.console.client.Config()
+	case m.viewMode != tableListMode && m.tableClient != nil:
+		return m.tableClient.Config()
+	}
+	return m.cfg
+}
+
+// errorBanner renders the last error with a hint and the retry action
+func (m Model) errorBanner() string {
+	bannerStyle := lipgloss.NewStyle().
+		Bold(true).
+		Foreground(colors.bannerFg).
+		Background(colors.bannerBg).
+		Padding(0, 1).
+		Width(m.viewWidth())
+
+	text := "Error: " + m.error.Error()
+	var dbErr *db.Error
+	if errors.As(m.error, &dbErr) && dbErr.Hint() != "" {
+		text += "\n" + dbErr.Hint()
+	}
+	if m.retry != nil {
+		text += "\n[r]: Retry [Esc]: Dismiss"
+	} else {
+		text += "\n[Esc]: Dismiss"
+	}
+	return bannerStyle.Render(text)
+}
+
 // Messages
 type tablesLoadedMsg struct {
 	tables []string
@@ -183,27 +1185,33 @@ type tableInfoLoadedMsg struct {
 	tableInfo *db.TableInfo
 }
 
+// errorMsg reports a failed command. retry, if set, runs the command again.
 type errorMsg struct {
-	err error
+	err   error
+	retry tea.Cmd
 }
 
 // Commands
-func loadTables() tea.Msg {
-	client := db.NewDynamoClient()
-	tables, err := client.ListTables()
-	if err != nil {
-		return errorMsg{err}
+func loadTables(ctx context.Context, client *db.DynamoClient) tea.Cmd {
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		tables, err := client.ListTables(ctx)
+		if err != nil {
+			return errorMsg{err: err, retry: cmd}
+		}
+		return tablesLoadedMsg{tables}
 	}
-	return tablesLoadedMsg{tables}
+	return cmd
 }
 
-func loadTableInfo(tableName string) tea.Cmd {
-	return func() tea.Msg {
-		client := db.NewDynamoClient()
-		tableInfo, err := client.DescribeTable(tableName)
+func loadTableInfo(ctx context.Context, client *db.DynamoClient, tableName string) tea.Cmd {
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		tableInfo, err := client.DescribeTable(ctx, tableName)
 		if err != nil {
-			return errorMsg{err}
+			return errorMsg{err: err, retry: cmd}
 		}
 		return tableInfoLoadedMsg{tableInfo}
 	}
-}
\ No newline at end of file
+	return cmd
+}
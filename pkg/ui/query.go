@@ -0,0 +1,312 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// queryField identifies an input of the query form
+type queryField int
+
+const (
+	fieldTarget queryField = iota
+	fieldPartition
+	fieldOperator
+	fieldSortValue
+	fieldSortValue2
+	fieldFilter // The filter builder has focus
+)
+
+// queryTarget is the base table or one of its indexes
+type queryTarget struct {
+	label     string
+	indexName string
+	schema    []db.KeySchemaElement
+}
+
+// queryForm holds the state of the interactive query builder
+type queryForm struct {
+	table          *db.TableInfo
+	targets        []queryTarget
+	target         int
+	focus          queryField
+	partitionValue string
+	operator       int // 0 means no sort key condition
+	sortValue      string
+	sortValue2     string
+	filter         *filterForm
+	err            error
+}
+
+// newQueryForm creates a query form for the table, listing the base table
+// followed by its global and local secondary indexes
+func newQueryForm(table *db.TableInfo) *queryForm {
+	f := &queryForm{
+		table:   table,
+		targets: []queryTarget{{label: "Table: " + table.TableName, schema: table.KeySchema}},
+		focus:   fieldPartition,
+		filter:  newFilterForm(table),
+	}
+	for _, gsi := range table.GSIs {
+		f.targets = append(f.targets, queryTarget{label: "GSI: " + gsi.IndexName, indexName: gsi.IndexName, schema: gsi.KeySchema})
+	}
+	for _, lsi := range table.LSIs {
+		f.targets = append(f.targets, queryTarget{label: "LSI: " + lsi.IndexName, indexName: lsi.IndexName, schema: lsi.KeySchema})
+	}
+	return f
+}
+
+func (f *queryForm) current() queryTarget {
+	return f.targets[f.target]
+}
+
+// sortOperator returns the selected operator, or "" when there is no sort
+// key condition
+func (f *queryForm) sortOperator() db.SortOperator {
+	if f.operator == 0 || db.SortKey(f.current().schema) == "" {
+		return ""
+	}
+	return db.SortOperators[f.operator-1]
+}
+
+// fields returns the inputs that apply to the current selections
+func (f *queryForm) fields() []queryField {
+	fields := []queryField{fieldTarget, fieldPartition}
+	if db.SortKey(f.current().schema) == "" {
+		return fields
+	}
+	fields = append(fields, fieldOperator)
+	switch f.sortOperator() {
+	case "":
+	case db.SortBetween:
+		fields = append(fields, fieldSortValue, fieldSortValue2)
+	default:
+		fields = append(fields, fieldSortValue)
+	}
+	return fields
+}
+
+// moveFocus moves the focus forward or backward through the visible fields,
+// then through the filter builder
+func (f *queryForm) moveFocus(delta int) {
+	fields := f.fields()
+	if f.focus == fieldFilter {
+		if !f.filter.moveFocus(delta) {
+			f.focus = fields[0]
+			if delta < 0 {
+				f.focus = fields[len(fields)-1]
+			}
+		}
+		return
+	}
+
+	pos := 0
+	for i, field := range fields {
+		if field == f.focus {
+			pos = i
+		}
+	}
+	pos += delta
+	switch {
+	case pos >= len(fields):
+		f.focus = fieldFilter
+		f.filter.focusFirst()
+	case pos < 0:
+		f.focus = fieldFilter
+		f.filter.focusLast()
+	default:
+		f.focus = fields[pos]
+	}
+}
+
+// text returns the text input that has focus, if any
+func (f *queryForm) text() *string {
+	switch f.focus {
+	case fieldPartition:
+		return &f.partitionValue
+	case fieldSortValue:
+		return &f.sortValue
+	case fieldSortValue2:
+		return &f.sortValue2
+	}
+	return nil
+}
+
+// update handles a key press on the form
+func (f *queryForm) update(msg tea.KeyMsg) {
+	f.err = nil
+
+	switch msg.String() {
+	case "tab", "down":
+		f.moveFocus(1)
+		return
+	case "shift+tab", "up":
+		f.moveFocus(-1)
+		return
+	}
+	if f.focus == fieldFilter {
+		f.filter.update(msg)
+		return
+	}
+
+	switch msg.String() {
+	case "left", "right":
+		delta := 1
+		if msg.String() == "left" {
+			delta = -1
+		}
+		switch f.focus {
+		case fieldTarget:
+			f.target = (f.target + delta + len(f.targets)) % len(f.targets)
+		case fieldOperator:
+			n := len(db.SortOperators) + 1
+			f.operator = (f.operator + delta + n) % n
+		}
+		return
+	}
+
+	text := f.text()
+	if text == nil {
+		return
+	}
+	switch msg.Type {
+	case tea.KeyRunes:
+		*text += string(msg.Runes)
+	case tea.KeySpace:
+		*text += " "
+	case tea.KeyBackspace:
+		if runes := []rune(*text); len(runes) > 0 {
+			*text = string(runes[:len(runes)-1])
+		}
+	}
+}
+
+// input builds the query described by the form
+func (f *queryForm) input() (db.QueryInput, error) {
+	target := f.current()
+	q := db.QueryInput{
+		TableName:    f.table.TableName,
+		IndexName:    target.indexName,
+		PartitionKey: db.PartitionKey(target.schema),
+	}
+
+	if f.partitionValue == "" {
+		return q, fmt.Errorf("enter a value for partition key %s", q.PartitionKey)
+	}
+	pkValue, err := db.KeyValue(f.table.AttributeDefinitions[q.PartitionKey], f.partitionValue)
+	if err != nil {
+		return q, fmt.Errorf("partition key %s: %w", q.PartitionKey, err)
+	}
+	q.PartitionValue = pkValue
+
+	if q.Filter, err = f.filter.conditions(); err != nil {
+		return q, err
+	}
+
+	op := f.sortOperator()
+	if op == "" {
+		return q, nil
+	}
+
+	q.SortKey = db.SortKey(target.schema)
+	raw := []string{f.sortValue}
+	if op == db.SortBetween {
+		raw = append(raw, f.sortValue2)
+	}
+	var values []types.AttributeValue
+	for _, r := range raw {
+		if r == "" {
+			return q, fmt.Errorf("enter a value for sort key %s", q.SortKey)
+		}
+		value, err := db.KeyValue(f.table.AttributeDefinitions[q.SortKey], r)
+		if err != nil {
+			return q, fmt.Errorf("sort key %s: %w", q.SortKey, err)
+		}
+		values = append(values, value)
+	}
+	q.SortCondition = &db.SortKeyCondition{Operator: op, Values: values}
+
+	return q, nil
+}
+
+// browser creates an item browser for the results of the query
+func (f *queryForm) browser(client *db.DynamoClient) (*itemBrowser, error) {
+	q, err := f.input()
+	if err != nil {
+		return nil, err
+	}
+	q.Limit = pageSize(client)
+
+	title := "Query: " + f.table.TableName
+	if q.IndexName != "" {
+		title += " (" + q.IndexName + ")"
+	}
+	if f.filter.active() {
+		title += " (filtered)"
+	}
+	keys := keyAttributes(f.current().schema, f.table.KeySchema)
+	q.Projection = f.filter.projection(keys)
+
+	return newItemBrowser(title, keys, func(ctx context.Context, after *db.ItemPage) (*db.ItemPage, error) {
+		page := q
+		page.ExclusiveStartKey = startKey(after)
+		return client.Query(ctx, page)
+	}), nil
+}
+
+// view renders the form
+func (f *queryForm) view() string {
+	labelStyle := lipgloss.NewStyle().Width(18)
+	focusStyle := lipgloss.NewStyle().Foreground(colors.accent).Bold(true)
+	errorStyle := lipgloss.NewStyle().Foreground(colors.err)
+
+	target := f.current()
+	var sb strings.Builder
+
+	row := func(field queryField, label, value string) {
+		prefix := "  "
+		if f.focus == field {
+			prefix = "> "
+			value = focusStyle.Render(value)
+			if f.text() != nil {
+				value += "█"
+			}
+		}
+		sb.WriteString(prefix + labelStyle.Render(label) + value + "\n")
+	}
+
+	row(fieldTarget, "Query", "◀ "+target.label+" ▶")
+	pk := db.PartitionKey(target.schema)
+	row(fieldPartition, pk+" =", f.partitionValue)
+
+	if sk := db.SortKey(target.schema); sk != "" {
+		op := "(any)"
+		if f.operator > 0 {
+			op = string(db.SortOperators[f.operator-1])
+		}
+		row(fieldOperator, sk, "◀ "+op+" ▶")
+
+		switch f.sortOperator() {
+		case "":
+		case db.SortBetween:
+			row(fieldSortValue, "  from", f.sortValue)
+			row(fieldSortValue2, "  to", f.sortValue2)
+		default:
+			row(fieldSortValue, "  value", f.sortValue)
+		}
+	}
+
+	sb.WriteString("\n" + f.filter.view(f.focus == fieldFilter))
+
+	if f.err != nil {
+		sb.WriteString("\n" + errorStyle.Render("Error: "+f.err.Error()) + "\n")
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,83 @@
+package ui
+
+import (
+	"context"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// tableEntry is a table in the table list
+type tableEntry struct {
+	name     string
+	region   string
+	replicas []string // Other regions of a global table
+}
+
+// listedRegions returns the regions the table list covers in multi-region
+// mode: those of the config, or every enabled region
+func listedRegions(cfg *appconfig.Config) []string {
+	if cfg != nil && len(cfg.Regions) > 0 {
+		return cfg.Regions
+	}
+	return []string{appconfig.AllRegions}
+}
+
+// tableListView renders the tables grouped by region. Regions that failed
+// to list show their error in place of tables.
+func tableListView(regions []db.RegionTables, tables []tableEntry, selected int) string {
+	var b strings.Builder
+	regionStyle := lipgloss.NewStyle().Bold(true).Render
+	mutedStyle := lipgloss.NewStyle().Foreground(colors.muted).Render
+	errorStyle := lipgloss.NewStyle().Foreground(colors.err).Render
+
+	i := 0
+	for _, region := range regions {
+		b.WriteString(regionStyle(region.Region) + "\n")
+		switch {
+		case region.Err != nil:
+			b.WriteString("  " + errorStyle("Error: "+region.Err.Error()) + "\n")
+		case len(region.Tables) == 0:
+			b.WriteString("  " + mutedStyle("No tables") + "\n")
+		}
+		for range region.Tables {
+			line := tables[i].name
+			if len(tables[i].replicas) > 0 {
+				line += "  " + mutedStyle("global · also in "+strings.Join(tables[i].replicas, ", "))
+			}
+			if i == selected {
+				b.WriteString("> " + line + "\n")
+			} else {
+				b.WriteString("  " + line + "\n")
+			}
+			i++
+		}
+	}
+	return b.String()
+}
+
+// regionTablesLoadedMsg carries the tables of several regions
+type regionTablesLoadedMsg struct {
+	regions []db.RegionTables
+}
+
+// loadRegionTables lists the tables of regions in parallel
+func loadRegionTables(ctx context.Context, client *db.DynamoClient, regions []string) tea.Cmd {
+	var cmd tea.Cmd
+	cmd = func() tea.Msg {
+		results, err := client.ListTablesInRegions(ctx, regions)
+		if err == nil {
+			// Regions cancelled by navigation fail individually
+			err = ctx.Err()
+		}
+		if err != nil {
+			return errorMsg{err: err, retry: cmd}
+		}
+		return regionTablesLoadedMsg{results}
+	}
+	return cmd
+}
@@ -0,0 +1,84 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// sessionCheckInterval is how often the credentials are checked. Checking
+// also renews them shortly before they expire, so an idle UI keeps working.
+const sessionCheckInterval = 30 * time.Second
+
+// sessionStatus is the source and lifetime of the current credentials
+type sessionStatus struct {
+	info db.CredentialsInfo
+	err  error
+}
+
+// checkSession retrieves the credentials of client, renewing them if needed
+func checkSession(ctx context.Context, client *db.DynamoClient) tea.Cmd {
+	return func() tea.Msg {
+		info, err := client.CredentialsInfo(ctx)
+		return sessionCheckedMsg{client: client, status: sessionStatus{info: info, err: err}}
+	}
+}
+
+// scheduleSessionCheck checks the credentials of client again after the
+// interval
+func scheduleSessionCheck(client *db.DynamoClient) tea.Cmd {
+	return tea.Tick(sessionCheckInterval, func(time.Time) tea.Msg {
+		return sessionCheckDueMsg{client: client}
+	})
+}
+
+// view renders the status bar line, or "" for a client without credentials
+func (s sessionStatus) view() string {
+	style := lipgloss.NewStyle().Foreground(colors.muted)
+	if s.err != nil {
+		return lipgloss.NewStyle().Foreground(colors.err).Render("Session: failed to renew credentials: " + s.err.Error())
+	}
+	if s.info.Source == "" {
+		return ""
+	}
+	line := "Credentials: " + s.info.Source
+	if !s.info.CanExpire {
+		return style.Render(line)
+	}
+	remaining := time.Until(s.info.Expires)
+	switch {
+	case remaining <= 0:
+		line += ", expired"
+	case remaining < time.Minute:
+		line += ", expire in less than a minute"
+	default:
+		line += fmt.Sprintf(", expire in %s", formatLifetime(remaining))
+	}
+	return style.Render(line)
+}
+
+// formatLifetime formats d in hours and minutes, such as "1h05m" or "42m"
+func formatLifetime(d time.Duration) string {
+	d = d.Truncate(time.Minute)
+	if d >= time.Hour {
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	}
+	return fmt.Sprintf("%dm", int(d.Minutes()))
+}
+
+// sessionCheckedMsg reports the lifetime of the credentials of client
+type sessionCheckedMsg struct {
+	client *db.DynamoClient
+	status sessionStatus
+}
+
+// sessionCheckDueMsg asks for the credentials of client to be checked
+// again. Checks of a client that was switched away from stop.
+type sessionCheckDueMsg struct {
+	client *db.DynamoClient
+}
@@ -0,0 +1,94 @@
+package ui
+
+import (
+	"context"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// ssoLogin signs in to AWS IAM Identity Center with the device
+// authorization flow: the user opens a URL, confirms a code and the UI
+// connects once the login is approved
+type ssoLogin struct {
+	cfg      *appconfig.Config
+	required *appconfig.SSOLoginRequiredError
+	login    *appconfig.SSOLogin
+	err      error
+}
+
+// NewSSOLoginModel creates a UI model that signs in for the profile of
+// required, then connects with cfg and shows the table list
+func NewSSOLoginModel(ctx context.Context, cfg *appconfig.Config, required *appconfig.SSOLoginRequiredError) Model {
+	m := NewModel(ctx, nil)
+	m.configure(cfg)
+	m.loading = false
+	m.viewMode = ssoLoginMode
+	m.sso = &ssoLogin{cfg: cfg, required: required}
+	return m
+}
+
+// start begins the device authorization
+func (s *ssoLogin) start(ctx context.Context) tea.Cmd {
+	s.login = nil
+	s.err = nil
+	return func() tea.Msg {
+		login, err := s.cfg.StartSSOLogin(ctx, s.required)
+		return ssoLoginStartedMsg{sso: s, login: login, err: err}
+	}
+}
+
+// wait waits for the user to approve the login and creates the client
+func (s *ssoLogin) wait(ctx context.Context) tea.Cmd {
+	login := s.login
+	return func() tea.Msg {
+		if err := login.Wait(ctx); err != nil {
+			return ssoLoginDoneMsg{sso: s, err: err}
+		}
+		client, err := db.NewDynamoClientWithConfig(s.cfg)
+		return ssoLoginDoneMsg{sso: s, client: client, err: err}
+	}
+}
+
+func (s *ssoLogin) view() string {
+	var b strings.Builder
+	b.WriteString("Profile " + s.required.Profile + " signs in through AWS IAM Identity Center.\n")
+	b.WriteString("Start URL: " + s.required.StartURL + "\n\n")
+
+	switch {
+	case s.err != nil:
+		errorStyle := lipgloss.NewStyle().Foreground(colors.err)
+		b.WriteString(errorStyle.Render("Error: "+s.err.Error()) + "\n")
+	case s.login == nil:
+		b.WriteString("Starting login...\n")
+	default:
+		codeStyle := lipgloss.NewStyle().Bold(true).Foreground(colors.accent)
+		url := s.login.VerificationURIComplete
+		if url == "" {
+			url = s.login.VerificationURI
+		}
+		b.WriteString("Open this URL in a browser and approve the request:\n\n")
+		b.WriteString("  " + url + "\n\n")
+		b.WriteString("Confirm that it shows the code " + codeStyle.Render(s.login.UserCode) + "\n\n")
+		b.WriteString("Waiting for approval...\n")
+	}
+	return b.String()
+}
+
+// ssoLoginStartedMsg reports that the device authorization has started
+type ssoLoginStartedMsg struct {
+	sso   *ssoLogin
+	login *appconfig.SSOLogin
+	err   error
+}
+
+// ssoLoginDoneMsg reports the end of the login
+type ssoLoginDoneMsg struct {
+	sso    *ssoLogin
+	client *db.DynamoClient
+	err    error
+}
@@ -0,0 +1,53 @@
+package ui
+
+import (
+	"github.com/charmbracelet/lipgloss"
+)
+
+// palette is the set of colors used by the UI
+type palette struct {
+	accent   lipgloss.Color // Titles, focused fields and changed values
+	err      lipgloss.Color
+	muted    lipgloss.Color // Secondary text such as attribute types
+	key      lipgloss.Color // Key attributes
+	mark     lipgloss.Color // Marked items
+	added    lipgloss.Color
+	bannerFg lipgloss.Color
+	bannerBg lipgloss.Color
+}
+
+// themes are the palettes that can be chosen in the config file. The dark
+// theme suits terminals with a dark background, the light one a light
+// background.
+var themes = map[string]palette{
+	"dark": {
+		accent:   "#FFFF00",
+		err:      "#FF0000",
+		muted:    "#888888",
+		key:      "#00FFFF",
+		mark:     "#FF00FF",
+		added:    "#00FF00",
+		bannerFg: "#FFFFFF",
+		bannerBg: "#AA0000",
+	},
+	"light": {
+		accent:   "#8A5A00",
+		err:      "#C00000",
+		muted:    "#666666",
+		key:      "#006B80",
+		mark:     "#A0007A",
+		added:    "#007A00",
+		bannerFg: "#FFFFFF",
+		bannerBg: "#AA0000",
+	},
+}
+
+// colors is the palette of the current theme
+var colors = themes["dark"]
+
+// useTheme switches to the named theme. Unknown names keep the current one.
+func useTheme(name string) {
+	if p, ok := themes[name]; ok {
+		colors = p
+	}
+}
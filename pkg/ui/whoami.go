@@ -0,0 +1,95 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+
+	appconfig "github.com/jlgore/dynamighTea/pkg/config"
+	"github.com/jlgore/dynamighTea/pkg/db"
+)
+
+// whoamiScreen shows where the client connects and who its credentials
+// belong to
+type whoamiScreen struct {
+	identity   *db.Identity // nil while loading
+	returnMode viewMode
+}
+
+// load resolves the identity of client
+func (w *whoamiScreen) load(ctx context.Context, client *db.DynamoClient) tea.Cmd {
+	w.identity = nil
+	return func() tea.Msg {
+		return identityLoadedMsg{screen: w, identity: client.Identity(ctx)}
+	}
+}
+
+func (w *whoamiScreen) view() string {
+	if w.identity == nil {
+		return "Checking credentials...\n"
+	}
+	id := w.identity
+	labelStyle := lipgloss.NewStyle().Bold(true).Width(13).Render
+	errorStyle := lipgloss.NewStyle().Foreground(colors.err).Render
+
+	var b strings.Builder
+	line := func(label, value string) {
+		b.WriteString(labelStyle(label+":") + value + "\n")
+	}
+
+	profile := id.Profile
+	if profile == "" {
+		profile = "default"
+	}
+	endpoint := id.Endpoint
+	if endpoint == "" {
+		endpoint = "AWS"
+	}
+	if id.Connection != "" {
+		line("Connection", id.Connection)
+	}
+	line("Region", id.Region)
+	line("Profile", profile)
+	line("Endpoint", endpoint)
+
+	var noCreds *appconfig.NoCredentialsError
+	switch {
+	case errors.As(id.CredentialsErr, &noCreds):
+		line("Credentials", errorStyle("none found"))
+		for _, reason := range noCreds.Tried {
+			b.WriteString("  - " + reason + "\n")
+		}
+		return b.String()
+	case id.CredentialsErr != nil:
+		line("Credentials", errorStyle(id.CredentialsErr.Error()))
+		return b.String()
+	case id.Credentials.Source == "":
+		line("Credentials", "none (demo data)")
+	default:
+		line("Credentials", id.Credentials.Source)
+	}
+	if id.Credentials.CanExpire {
+		line("Expires", fmt.Sprintf("%s (in %s)", id.Credentials.Expires.Local().Format(time.Kitchen), formatLifetime(time.Until(id.Credentials.Expires))))
+	}
+
+	b.WriteString("\n")
+	if id.CallerErr != nil {
+		line("Caller", errorStyle(id.CallerErr.Error()))
+		return b.String()
+	}
+	line("Account", id.Account)
+	line("ARN", id.ARN)
+	line("User ID", id.UserID)
+	return b.String()
+}
+
+// identityLoadedMsg carries the identity shown by a whoami screen
+type identityLoadedMsg struct {
+	screen   *whoamiScreen
+	identity db.Identity
+}